@@ -0,0 +1,43 @@
+package keyManager
+
+import (
+	"testing"
+
+	"github.com/lucasgpulcinelli/mongoQLer/oracleManager"
+)
+
+func TestToMongoId(t *testing.T) {
+	c := &oracleManager.MemoryCatalog{
+		Tables: []string{"EMP", "BONUS"},
+		PrimaryKeys: map[string][]string{
+			"EMP": {"ID", "ORG"},
+		},
+	}
+
+	tests := []struct {
+		table, column string
+		want          string
+	}{
+		{"EMP", "ID", "_id.ID"},
+		{"EMP", "ORG", "_id.ORG"},
+		{"EMP", "org", "_id.org"},
+		{"EMP", "NAME", "NAME"},
+		{"BONUS", "ID", "ID"},
+		{"NOPE", "ID", "ID"},
+	}
+
+	for _, test := range tests {
+		got := ToMongoId(c, test.table, test.column)
+		if got != test.want {
+			t.Errorf("ToMongoId(%s, %s) got %s, want %s",
+				test.table, test.column, got, test.want,
+			)
+		}
+
+		if IsPk(c, test.table, test.column) != (got != test.column) {
+			t.Errorf("IsPk(%s, %s) does not agree with ToMongoId",
+				test.table, test.column,
+			)
+		}
+	}
+}
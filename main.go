@@ -1,23 +1,97 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
 	"fyne.io/fyne/v2/app"
+	"github.com/lucasgpulcinelli/mongoQLer/oracleManager"
 	"github.com/lucasgpulcinelli/mongoQLer/ui"
 )
 
+// writeSnapshot logs in to oracle with the ORACLE_URL, ORACLE_USER and
+// ORACLE_PASSWORD environment variables and saves a snapshot of the catalog of
+// the ORACLE_OWNER schema (or of the user schema, if not set) in a .json,
+// .yaml or .yml file.
+func writeSnapshot(name string) error {
+	format, err := oracleManager.SnapshotFormat(name)
+	if err != nil {
+		return err
+	}
+
+	conn, err := oracleManager.Login(os.Getenv("ORACLE_URL"),
+		os.Getenv("ORACLE_USER"), os.Getenv("ORACLE_PASSWORD"),
+	)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	catalog, err := oracleManager.NewOracleCatalog(conn,
+		os.Getenv("ORACLE_OWNER"),
+	)
+	if err != nil {
+		return err
+	}
+
+	snapshot, err := oracleManager.NewSnapshot(catalog)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+
+	err = oracleManager.EncodeSnapshot(f, snapshot, format)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
 func main() {
+	snapshot := flag.String("snapshot", "",
+		"save a snapshot of the oracle catalog in this .json, .yaml or .yml file "+
+			"and exit, logging in with the ORACLE_URL, ORACLE_USER and "+
+			"ORACLE_PASSWORD environment variables",
+	)
+	flag.Parse()
+
+	if *snapshot != "" {
+		err := writeSnapshot(*snapshot)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
 	a := app.New()
 	wLogin := ui.NewLoginWindow(a)
 
 	wLogin.Show()
 	a.Run()
 
-	// during the exit phase of the application, close the connection
-	conn := ui.GetConnections()
+	// during the exit phase of the application, close the connections
+	conn, client := ui.GetConnections()
 	if conn != nil {
 		err := conn.Close()
 		if err != nil {
 			panic(err)
 		}
 	}
+
+	if client != nil {
+		err := client.Disconnect(context.Background())
+		if err != nil {
+			panic(err)
+		}
+	}
 }
@@ -0,0 +1,193 @@
+package mongoManager
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// BatchSize is the number of documents written in each InsertMany.
+const BatchSize = 1000
+
+// LoginTimeout is the time Login waits for the server before giving up.
+const LoginTimeout = 5 * time.Second
+
+// struct BatchResult represents the result of writing one batch of documents:
+// the batch number (starting from 1), how many documents it had and how many
+// were inserted, and the error of the write, if any.
+type BatchResult struct {
+	Batch     int
+	Documents int
+	Inserted  int
+	Err       error
+}
+
+// String gets a line describing a BatchResult, for the user.
+func (r BatchResult) String() string {
+	s := fmt.Sprintf("batch %d: inserted %d of %d documents",
+		r.Batch, r.Inserted, r.Documents,
+	)
+	if r.Err != nil {
+		s += ": " + strings.ReplaceAll(r.Err.Error(), "\n", "; ")
+	}
+
+	return s
+}
+
+// struct Index represents a createIndex for a collection, with its keys and
+// options (such as the name, unique and collation), as in a createIndexes
+// command.
+type Index struct {
+	Collection string
+	Keys       bson.D
+	Options    bson.D
+}
+
+// Login creates a new connection with mongoDB, and returns the database with
+// the name provided in it. If the server cannot be reached in LoginTimeout,
+// the login fails.
+func Login(uri, database string) (*mongo.Database, error) {
+	if database == "" {
+		return nil, fmt.Errorf("no mongoDB database given")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), LoginTimeout)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		return nil, err
+	}
+
+	err = client.Ping(ctx, nil)
+	if err != nil {
+		client.Disconnect(context.Background())
+		return nil, err
+	}
+
+	return client.Database(database), nil
+}
+
+// InsertDocuments writes the documents read with next to a collection in
+// batches of BatchSize with InsertMany, calling report with the result of each
+// batch as soon as it is written, so that only one batch is in memory at a
+// time. The batches are unordered, so a document that fails (such as a
+// duplicate _id) does not stop the others in its batch, and a failed batch
+// does not stop the next ones.
+func InsertDocuments(
+	ctx context.Context, db *mongo.Database, collection string,
+	next func() (bson.D, bool), report func(BatchResult),
+) {
+	coll := db.Collection(collection)
+	batchNumber := 0
+
+	for {
+		batch := []any{}
+		for len(batch) < BatchSize {
+			doc, ok := next()
+			if !ok {
+				break
+			}
+
+			batch = append(batch, doc)
+		}
+
+		if len(batch) == 0 {
+			return
+		}
+
+		batchNumber++
+		result := BatchResult{Batch: batchNumber, Documents: len(batch)}
+
+		res, err := coll.InsertMany(ctx, batch,
+			options.InsertMany().SetOrdered(false),
+		)
+		if res != nil {
+			result.Inserted = len(res.InsertedIDs)
+		}
+
+		// in unordered inserts the documents without errors are still inserted
+		if bwe, ok := err.(mongo.BulkWriteException); ok {
+			result.Inserted = len(batch) - len(bwe.WriteErrors)
+		}
+		result.Err = err
+
+		report(result)
+
+		if len(batch) < BatchSize {
+			return
+		}
+	}
+}
+
+// InsertDocument writes a single document to a collection.
+func InsertDocument(
+	ctx context.Context, db *mongo.Database, collection string, doc bson.D,
+) error {
+	_, err := db.Collection(collection).InsertOne(ctx, doc)
+	return err
+}
+
+// indexName gets the name mongoDB gives to an index with some keys, such as
+// "A_1_B_-1".
+func indexName(keys bson.D) string {
+	parts := []string{}
+	for _, e := range keys {
+		parts = append(parts, fmt.Sprintf("%s_%v", e.Key, e.Value))
+	}
+
+	return strings.Join(parts, "_")
+}
+
+// CreateIndex creates an index with a createIndexes command.
+func CreateIndex(ctx context.Context, db *mongo.Database, index Index) error {
+	spec := bson.D{{Key: "key", Value: index.Keys}}
+
+	hasName := false
+	for _, e := range index.Options {
+		hasName = hasName || e.Key == "name"
+	}
+	if !hasName {
+		spec = append(spec, bson.E{Key: "name", Value: indexName(index.Keys)})
+	}
+
+	spec = append(spec, index.Options...)
+
+	return db.RunCommand(ctx, bson.D{
+		{Key: "createIndexes", Value: index.Collection},
+		{Key: "indexes", Value: bson.A{spec}},
+	}).Err()
+}
+
+// UploadFile writes a file to a GridFS bucket (in its .files and .chunks
+// collections), reading its content from r as it is written, with the
+// metadata provided. It returns the id of the file.
+func UploadFile(
+	db *mongo.Database, bucket, name string, r io.Reader, metadata bson.D,
+) (any, error) {
+	b, err := gridfs.NewBucket(db, options.GridFSBucket().SetName(bucket))
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := b.UploadFromStream(name, r,
+		options.GridFSUpload().SetMetadata(metadata),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return id, nil
+}
+
+// RunCommand runs a command, such as a collMod, in the database.
+func RunCommand(ctx context.Context, db *mongo.Database, cmd bson.D) error {
+	return db.RunCommand(ctx, cmd).Err()
+}
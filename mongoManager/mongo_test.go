@@ -0,0 +1,61 @@
+package mongoManager
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestLoginErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		uri      string
+		database string
+	}{
+		{"no database", "mongodb://127.0.0.1:1", ""},
+		{"invalid URI", "mysql://127.0.0.1", "test"},
+		// no server listens in port 1
+		{"unreachable server", "mongodb://127.0.0.1:1", "test"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			start := time.Now()
+
+			db, err := Login(test.uri, test.database)
+			if err == nil {
+				db.Client().Disconnect(context.Background())
+				t.Fatalf("Login did not fail")
+			}
+
+			if elapsed := time.Since(start); elapsed > LoginTimeout+time.Second {
+				t.Errorf("Login took %v to fail", elapsed)
+			}
+		})
+	}
+}
+
+func TestBatchResultString(t *testing.T) {
+	tests := []struct {
+		result BatchResult
+		want   string
+	}{
+		{
+			BatchResult{Batch: 1, Documents: 1000, Inserted: 1000},
+			"batch 1: inserted 1000 of 1000 documents",
+		},
+		{
+			BatchResult{Batch: 2, Documents: 10, Inserted: 8,
+				Err: fmt.Errorf("duplicate key\nE11000"),
+			},
+			"batch 2: inserted 8 of 10 documents: duplicate key; E11000",
+		},
+	}
+
+	for _, test := range tests {
+		if got := test.result.String(); got != test.want {
+			t.Errorf("String got %q, want %q", got, test.want)
+		}
+	}
+}
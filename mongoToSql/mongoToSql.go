@@ -0,0 +1,856 @@
+package mongoToSql
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// struct query holds the parts of an oracle SELECT while a find or pipeline is
+// being read.
+type query struct {
+	from string
+
+	join      string
+	joinAs    string
+	joinTo    string
+	joinFrom  string
+	joinReady bool // if the $unwind for the $lookup was already read
+	qualify   bool // if columns are qualified by their table, for joins
+
+	selection []string
+
+	where          []string
+	whereComposite []bool
+
+	group     bool
+	groupBy   []string
+	groupKeys map[string]string // group output field to its SQL expression
+
+	having          []string
+	havingComposite []bool
+
+	orderBy []string
+	offset  int64
+	limit   int64
+}
+
+// toDoc converts a document in any of the forms the driver uses to a bson.D,
+// ordering map keys so that the output is always the same.
+func toDoc(v any) (bson.D, bool) {
+	switch d := v.(type) {
+	case bson.D:
+		return d, true
+	case bson.M:
+		keys := []string{}
+		for k := range d {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		result := bson.D{}
+		for _, k := range keys {
+			result = append(result, bson.E{Key: k, Value: d[k]})
+		}
+
+		return result, true
+	case map[string]any:
+		return toDoc(bson.M(d))
+	}
+
+	return nil, false
+}
+
+// toArray converts an array in any of the forms the driver uses to an array of
+// any.
+func toArray(v any) ([]any, bool) {
+	switch a := v.(type) {
+	case bson.A:
+		return a, true
+	case []any:
+		return a, true
+	case []bson.D:
+		result := []any{}
+		for _, d := range a {
+			result = append(result, d)
+		}
+
+		return result, true
+	case mongo.Pipeline:
+		return toArray([]bson.D(a))
+	}
+
+	return nil, false
+}
+
+// column converts a document field to the oracle column it came from, removing
+// the joined table and _id prefixes that the sqlparser package adds. In joins,
+// columns are qualified by their table, as both tables can have columns with
+// the same name. After a $group, fields are the group output, so they are
+// converted to the SQL expression that generates them.
+func (q *query) column(field string) string {
+	if expr, ok := q.groupKeys[field]; ok && q.group {
+		return expr
+	}
+
+	table := q.from
+	if q.joinAs != "" && strings.HasPrefix(field, q.joinAs+".") {
+		field = field[len(q.joinAs)+1:]
+		table = q.join
+	}
+
+	field = strings.TrimPrefix(field, "_id.")
+
+	// names that are not valid oracle identifiers must be quoted
+	valid := field != "" && field[0] != '_' && !(field[0] >= '0' &&
+		field[0] <= '9')
+	for _, r := range field {
+		if !(r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' ||
+			r >= '0' && r <= '9' || r == '_' || r == '$' || r == '#') {
+			valid = false
+		}
+	}
+
+	if !valid {
+		field = `"` + field + `"`
+	}
+
+	if q.qualify {
+		return table + "." + field
+	}
+
+	return field
+}
+
+// expression converts an aggregation expression to SQL, supporting field
+// references and constants.
+func (q *query) expression(v any) (string, error) {
+	if s, ok := v.(string); ok && strings.HasPrefix(s, "$") {
+		return q.column(s[1:]), nil
+	}
+
+	return Value(v)
+}
+
+// Value converts a constant from a mongoDB document to an oracle literal.
+func Value(v any) (string, error) {
+	switch vv := v.(type) {
+	case nil:
+		return "NULL", nil
+	case string:
+		return "'" + strings.ReplaceAll(vv, "'", "''") + "'", nil
+	case int:
+		return strconv.FormatInt(int64(vv), 10), nil
+	case int32:
+		return strconv.FormatInt(int64(vv), 10), nil
+	case int64:
+		return strconv.FormatInt(vv, 10), nil
+	case float64:
+		return strconv.FormatFloat(vv, 'g', -1, 64), nil
+	case bool:
+		if vv {
+			return "1", nil
+		}
+		return "0", nil
+	case primitive.Decimal128:
+		return vv.String(), nil
+	case primitive.DateTime:
+		return Value(vv.Time())
+	case time.Time:
+		return "TIMESTAMP '" + vv.UTC().Format("2006-01-02 15:04:05.000") + "'",
+			nil
+	}
+
+	if d, ok := toDoc(v); ok && len(d) == 1 && d[0].Key == "$literal" {
+		return Value(d[0].Value)
+	}
+
+	return "", fmt.Errorf("unsupported value %v", v)
+}
+
+// comparision converts a single operator applied to a field to SQL. Since
+// $ne and $nin also match documents where the field is null, their conditions
+// accept NULL explicitly. On the other hand, {$exists: false} becomes IS NULL,
+// which is an approximation: it also matches the documents with an explicit
+// null, which SQL cannot tell apart from a missing field.
+func (q *query) comparision(col, op string, v any) (string, error) {
+	sqlOps := map[string]string{
+		"$eq": "=", "$ne": "<>", "$gt": ">", "$gte": ">=", "$lt": "<", "$lte": "<=",
+	}
+
+	switch op {
+	case "$eq", "$ne", "$gt", "$gte", "$lt", "$lte":
+		if v == nil && op == "$eq" {
+			return col + " IS NULL", nil
+		} else if v == nil && op == "$ne" {
+			return col + " IS NOT NULL", nil
+		}
+
+		s, err := Value(v)
+		if err != nil {
+			return "", err
+		}
+
+		if op == "$ne" {
+			return "(" + col + " <> " + s + " OR " + col + " IS NULL)", nil
+		}
+
+		return col + " " + sqlOps[op] + " " + s, nil
+
+	case "$in", "$nin":
+		vs, ok := toArray(v)
+		if !ok || len(vs) == 0 {
+			return "", fmt.Errorf("%s needs a non empty array", op)
+		}
+
+		ss := []string{}
+		for _, vi := range vs {
+			s, err := Value(vi)
+			if err != nil {
+				return "", err
+			}
+
+			ss = append(ss, s)
+		}
+
+		if op == "$nin" {
+			return "(" + col + " NOT IN (" + strings.Join(ss, ", ") + ") OR " +
+				col + " IS NULL)", nil
+		}
+
+		return col + " IN (" + strings.Join(ss, ", ") + ")", nil
+
+	case "$exists":
+		if b, ok := v.(bool); ok && !b {
+			return col + " IS NULL", nil
+		}
+
+		return col + " IS NOT NULL", nil
+	}
+
+	return "", fmt.Errorf("unsupported query operator %s", op)
+}
+
+// join joins conditions with a boolean operator, adding parenthesis to the
+// conditions that are composite themselves.
+func join(conds []string, composite []bool, op string) string {
+	ss := []string{}
+	for i, c := range conds {
+		if composite[i] && len(conds) > 1 {
+			c = "(" + c + ")"
+		}
+
+		ss = append(ss, c)
+	}
+
+	return strings.Join(ss, " "+op+" ")
+}
+
+// filter converts a query filter document to an SQL condition, returning it
+// and if it is composite (has an AND or OR at the top level). Empty filters
+// return an empty condition.
+func (q *query) filter(filter bson.D) (string, bool, error) {
+	conds := []string{}
+	composite := []bool{}
+
+	for _, e := range filter {
+		switch e.Key {
+		case "$and", "$or", "$nor":
+			subs, ok := toArray(e.Value)
+			if !ok || len(subs) == 0 {
+				return "", false, fmt.Errorf("%s needs a non empty array", e.Key)
+			}
+
+			subConds := []string{}
+			subComposite := []bool{}
+			for _, sub := range subs {
+				d, ok := toDoc(sub)
+				if !ok {
+					return "", false, fmt.Errorf("%s needs documents", e.Key)
+				}
+
+				s, c, err := q.filter(d)
+				if err != nil {
+					return "", false, err
+				}
+
+				if s == "" {
+					s, c = "1 = 1", false
+				}
+
+				subConds = append(subConds, s)
+				subComposite = append(subComposite, c)
+			}
+
+			op := "AND"
+			if e.Key != "$and" {
+				op = "OR"
+			}
+
+			s := join(subConds, subComposite, op)
+			if e.Key == "$nor" {
+				s = "NOT (" + s + ")"
+			}
+
+			conds = append(conds, s)
+			if len(subs) == 1 {
+				composite = append(composite, subComposite[0])
+			} else {
+				composite = append(composite, e.Key != "$nor")
+			}
+			continue
+		}
+
+		if strings.HasPrefix(e.Key, "$") {
+			return "", false, fmt.Errorf("unsupported query operator %s", e.Key)
+		}
+
+		col := q.column(e.Key)
+
+		// with a document with operators, each operator is a condition,
+		// otherwise the value is compared for equality
+		d, ok := toDoc(e.Value)
+		if !ok || len(d) == 0 || !strings.HasPrefix(d[0].Key, "$") {
+			d = bson.D{{Key: "$eq", Value: e.Value}}
+		}
+
+		for _, op := range d {
+			s, err := q.comparision(col, op.Key, op.Value)
+			if err != nil {
+				return "", false, err
+			}
+
+			conds = append(conds, s)
+			composite = append(composite, false)
+		}
+	}
+
+	if len(conds) == 1 {
+		return conds[0], composite[0], nil
+	}
+
+	return join(conds, composite, "AND"), len(conds) > 1, nil
+}
+
+// project reads a projection document, setting the query selection. Only
+// projections that include fields are supported, as a SELECT cannot list the
+// columns it does not want.
+func (q *query) project(projection bson.D) error {
+	q.selection = []string{}
+	excluded := false
+
+	for _, e := range projection {
+		switch v := e.Value.(type) {
+		case bool:
+			if !v {
+				excluded = true
+				continue
+			}
+		case int32, int64, int, float64:
+			if fmt.Sprint(v) == "0" {
+				excluded = true
+				continue
+			}
+		default:
+			return fmt.Errorf("unsupported projection for %s", e.Key)
+		}
+
+		if e.Key != "_id" {
+			q.selection = append(q.selection, q.column(e.Key))
+		}
+	}
+
+	if excluded && len(q.selection) == 0 {
+		return fmt.Errorf("projections that only exclude fields are not supported")
+	}
+
+	return nil
+}
+
+// accumulator converts a $group accumulator to an SQL group function.
+func (q *query) accumulator(acc bson.D) (string, error) {
+	if len(acc) != 1 {
+		return "", fmt.Errorf("invalid $group accumulator")
+	}
+
+	fns := map[string]string{
+		"$sum": "SUM", "$avg": "AVG", "$min": "MIN", "$max": "MAX",
+		"$stdDevSamp": "STDDEV",
+	}
+
+	op, v := acc[0].Key, acc[0].Value
+
+	if op == "$count" {
+		return "COUNT(*)", nil
+	}
+
+	if op == "$sum" {
+		// {$sum: 1} counts all documents
+		if s, err := Value(v); err == nil && s == "1" {
+			return "COUNT(*)", nil
+		}
+
+		// {$sum: {$cond: [{$ne: ["$A", null]}, 1, 0]}} counts the non null A
+		if d, ok := toDoc(v); ok && len(d) == 1 && d[0].Key == "$cond" {
+			cond, ok := toArray(d[0].Value)
+			if ok && len(cond) == 3 {
+				ne, ok := toDoc(cond[0])
+				if ok && len(ne) == 1 && ne[0].Key == "$ne" {
+					args, ok := toArray(ne[0].Value)
+					if ok && len(args) == 2 && args[1] == nil {
+						col, err := q.expression(args[0])
+						if err != nil {
+							return "", err
+						}
+
+						return "COUNT(" + col + ")", nil
+					}
+				}
+			}
+		}
+	}
+
+	fn, ok := fns[op]
+	if !ok {
+		return "", fmt.Errorf("unsupported $group accumulator %s", op)
+	}
+
+	col, err := q.expression(v)
+	if err != nil {
+		return "", err
+	}
+
+	return fn + "(" + col + ")", nil
+}
+
+// groupStage reads a $group stage.
+func (q *query) groupStage(group bson.D) error {
+	if q.group {
+		return fmt.Errorf("only one $group is supported")
+	}
+
+	q.groupKeys = map[string]string{}
+	q.selection = []string{}
+
+	for _, e := range group {
+		if e.Key == "_id" {
+			// the group key is either null, a field, or a document of fields
+			keys := bson.D{}
+			if d, ok := toDoc(e.Value); ok {
+				keys = d
+			} else if e.Value != nil {
+				keys = bson.D{{Key: "_id", Value: e.Value}}
+			}
+
+			for _, k := range keys {
+				col, err := q.expression(k.Value)
+				if err != nil {
+					return err
+				}
+
+				q.groupBy = append(q.groupBy, col)
+				q.selection = append(q.selection, col)
+				q.groupKeys["_id."+k.Key] = col
+			}
+
+			if len(keys) == 1 {
+				q.groupKeys["_id"] = q.groupBy[0]
+			}
+
+			continue
+		}
+
+		acc, ok := toDoc(e.Value)
+		if !ok {
+			return fmt.Errorf("invalid $group accumulator for %s", e.Key)
+		}
+
+		expr, err := q.accumulator(acc)
+		if err != nil {
+			return err
+		}
+
+		// name the output column only if it differs from the expression
+		q.groupKeys[e.Key] = expr
+		if e.Key != expr {
+			expr += ` AS "` + e.Key + `"`
+		}
+
+		q.selection = append(q.selection, expr)
+	}
+
+	q.group = true
+	return nil
+}
+
+// matchStage reads a $match stage, that is either a WHERE or, after a $group,
+// a HAVING.
+func (q *query) matchStage(match bson.D) error {
+	// each condition is added separately, so that conditions from many stages
+	// are joined by AND without extra parenthesis
+	for _, e := range match {
+		if subs, ok := toArray(e.Value); ok && e.Key == "$and" {
+			for _, sub := range subs {
+				d, ok := toDoc(sub)
+				if !ok {
+					return fmt.Errorf("$and needs documents")
+				}
+
+				err := q.matchStage(d)
+				if err != nil {
+					return err
+				}
+			}
+
+			continue
+		}
+
+		cond, composite, err := q.filter(bson.D{e})
+		if err != nil {
+			return err
+		}
+
+		if q.group {
+			q.having = append(q.having, cond)
+			q.havingComposite = append(q.havingComposite, composite)
+		} else {
+			q.where = append(q.where, cond)
+			q.whereComposite = append(q.whereComposite, composite)
+		}
+	}
+
+	return nil
+}
+
+// lookupStage reads a $lookup stage, that must be followed by an $unwind to
+// be an inner join.
+func (q *query) lookupStage(lookup bson.D) error {
+	if q.join != "" {
+		return fmt.Errorf("only one $lookup is supported")
+	}
+
+	for _, e := range lookup {
+		s, ok := e.Value.(string)
+		if !ok {
+			return fmt.Errorf("unsupported $lookup option %s", e.Key)
+		}
+
+		switch e.Key {
+		default:
+			return fmt.Errorf("unsupported $lookup option %s", e.Key)
+		case "from":
+			q.join = s
+		case "localField":
+			q.joinFrom = s
+		case "foreignField":
+			q.joinTo = s
+		case "as":
+			q.joinAs = s
+		}
+	}
+
+	if q.join == "" || q.joinFrom == "" || q.joinTo == "" || q.joinAs == "" {
+		return fmt.Errorf("incomplete $lookup")
+	}
+
+	q.joinFrom = q.column(q.joinFrom)
+	q.joinTo = q.column(q.joinAs + "." + q.joinTo)
+
+	return nil
+}
+
+// unwindStage reads the $unwind of a $lookup.
+func (q *query) unwindStage(v any) error {
+	if d, ok := toDoc(v); ok {
+		for _, e := range d {
+			if e.Key == "path" {
+				v = e.Value
+			} else if e.Key == "preserveNullAndEmptyArrays" && e.Value == true {
+				return fmt.Errorf("outer joins are not supported")
+			}
+		}
+	}
+
+	if q.join == "" || q.joinReady || v != "$"+q.joinAs {
+		return fmt.Errorf("$unwind is only supported after a $lookup")
+	}
+
+	q.joinReady = true
+	return nil
+}
+
+// sortStage reads a $sort stage.
+func (q *query) sortStage(sort bson.D) error {
+	q.orderBy = []string{}
+
+	for _, e := range sort {
+		col := q.column(e.Key)
+		if fmt.Sprint(e.Value) == "-1" {
+			col += " DESC"
+		}
+
+		q.orderBy = append(q.orderBy, col)
+	}
+
+	return nil
+}
+
+// toInt converts a numeric value from a stage to an int64.
+func toInt(v any) (int64, error) {
+	switch vv := v.(type) {
+	case int:
+		return int64(vv), nil
+	case int32:
+		return int64(vv), nil
+	case int64:
+		return vv, nil
+	case float64:
+		return int64(vv), nil
+	}
+
+	return 0, fmt.Errorf("invalid number %v", v)
+}
+
+// stage reads a single aggregation pipeline stage.
+func (q *query) stage(stage bson.D) error {
+	if len(stage) != 1 {
+		return fmt.Errorf("a pipeline stage must have exactly one operator")
+	}
+
+	op, v := stage[0].Key, stage[0].Value
+
+	// most stages have a document as a value
+	d, isDoc := toDoc(v)
+	if !isDoc && op != "$unwind" && op != "$limit" && op != "$skip" &&
+		op != "$count" {
+		return fmt.Errorf("invalid value for %s", op)
+	}
+
+	if q.join != "" && !q.joinReady && op != "$unwind" {
+		return fmt.Errorf("$lookup must be followed by its $unwind")
+	}
+
+	switch op {
+	case "$match":
+		// a SELECT filters before it limits the rows
+		if q.limit != 0 || q.offset != 0 {
+			return fmt.Errorf("$match after $limit or $skip is not supported")
+		}
+
+		return q.matchStage(d)
+	case "$lookup":
+		return q.lookupStage(d)
+	case "$unwind":
+		return q.unwindStage(v)
+	case "$group":
+		return q.groupStage(d)
+	case "$project":
+		return q.project(d)
+	case "$sort":
+		return q.sortStage(d)
+	case "$count":
+		name, ok := v.(string)
+		if !ok || q.group {
+			return fmt.Errorf("invalid $count")
+		}
+
+		return q.groupStage(bson.D{
+			{Key: "_id", Value: nil},
+			{Key: name, Value: bson.D{{Key: "$count", Value: bson.D{}}}},
+		})
+	case "$limit":
+		n, err := toInt(v)
+		if err != nil {
+			return err
+		}
+
+		if q.limit == 0 || n < q.limit {
+			q.limit = n
+		}
+
+		return nil
+	case "$skip":
+		n, err := toInt(v)
+		if err != nil {
+			return err
+		}
+
+		if q.limit != 0 {
+			return fmt.Errorf("$skip after $limit is not supported")
+		}
+
+		q.offset += n
+		return nil
+	}
+
+	return fmt.Errorf("unsupported pipeline stage %s", op)
+}
+
+// String gets the oracle SELECT for the query.
+func (q *query) String() string {
+	s := "SELECT "
+	if len(q.selection) == 0 {
+		s += "*"
+	} else {
+		s += strings.Join(q.selection, ", ")
+	}
+
+	s += " FROM " + q.from
+
+	if q.join != "" {
+		s += " JOIN " + q.join + " ON " + q.joinTo + " = " + q.joinFrom
+	}
+
+	if len(q.where) != 0 {
+		s += " WHERE " + join(q.where, q.whereComposite, "AND")
+	}
+
+	if len(q.groupBy) != 0 {
+		s += " GROUP BY " + strings.Join(q.groupBy, ", ")
+	}
+
+	if len(q.having) != 0 {
+		s += " HAVING " + join(q.having, q.havingComposite, "AND")
+	}
+
+	if len(q.orderBy) != 0 {
+		s += " ORDER BY " + strings.Join(q.orderBy, ", ")
+	}
+
+	if q.offset != 0 {
+		s += " OFFSET " + strconv.FormatInt(q.offset, 10) + " ROWS"
+	}
+
+	if q.limit != 0 {
+		s += " FETCH FIRST " + strconv.FormatInt(q.limit, 10) + " ROWS ONLY"
+	}
+
+	return s + ";"
+}
+
+// FindToSQL converts a mongoDB find over a collection, with a filter and a
+// projection, to an oracle SELECT.
+func FindToSQL(collection string, filter, projection bson.D) (string, error) {
+	q := &query{from: collection}
+
+	err := q.matchStage(filter)
+	if err != nil {
+		return "", err
+	}
+
+	err = q.project(projection)
+	if err != nil {
+		return "", err
+	}
+
+	return q.String(), nil
+}
+
+// AggregateToSQL converts a mongoDB aggregation over a collection to an oracle
+// SELECT. Only pipelines that map to a single SELECT are supported: $match,
+// one $lookup followed by its $unwind, one $group, $project, $sort, $count,
+// $skip and $limit.
+func AggregateToSQL(collection string, p mongo.Pipeline) (string, error) {
+	q := &query{from: collection}
+
+	for _, stage := range p {
+		if len(stage) != 0 && stage[0].Key == "$lookup" {
+			q.qualify = true
+		}
+	}
+
+	for _, stage := range p {
+		err := q.stage(stage)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if q.join != "" && !q.joinReady {
+		return "", fmt.Errorf("$lookup must be followed by its $unwind")
+	}
+
+	return q.String(), nil
+}
+
+// ShellToSQL converts a mongosh command in the form "db.COLLECTION.find(...)"
+// or "db.COLLECTION.aggregate([...])", with arguments in extended JSON, to an
+// oracle SELECT.
+func ShellToSQL(cmd string) (string, error) {
+	cmd = strings.TrimSuffix(strings.TrimSpace(cmd), ";")
+
+	open := strings.Index(cmd, "(")
+	if !strings.HasPrefix(cmd, "db.") || open == -1 ||
+		!strings.HasSuffix(cmd, ")") {
+		return "", fmt.Errorf("expected db.COLLECTION.find(...) or aggregate(...)")
+	}
+
+	target := cmd[len("db."):open]
+	dot := strings.LastIndex(target, ".")
+	if dot == -1 {
+		return "", fmt.Errorf("expected db.COLLECTION.find(...) or aggregate(...)")
+	}
+
+	collection, method := target[:dot], target[dot+1:]
+
+	// read the arguments as an array, so that both finds and aggregates can be
+	// read by the same call
+	var args struct {
+		Args bson.A `bson:"args"`
+	}
+	err := bson.UnmarshalExtJSON(
+		[]byte(`{"args": [`+cmd[open+1:len(cmd)-1]+`]}`), false, &args,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	switch method {
+	case "find":
+		docs := []bson.D{{}, {}}
+		if len(args.Args) > 2 {
+			return "", fmt.Errorf("find has at most two arguments")
+		}
+
+		for i, arg := range args.Args {
+			d, ok := toDoc(arg)
+			if !ok {
+				return "", fmt.Errorf("find arguments must be documents")
+			}
+
+			docs[i] = d
+		}
+
+		return FindToSQL(collection, docs[0], docs[1])
+
+	case "aggregate":
+		if len(args.Args) == 0 {
+			return "", fmt.Errorf("aggregate needs a pipeline")
+		}
+
+		stages, ok := toArray(args.Args[0])
+		if !ok {
+			return "", fmt.Errorf("aggregate needs an array pipeline")
+		}
+
+		p := mongo.Pipeline{}
+		for _, stage := range stages {
+			d, ok := toDoc(stage)
+			if !ok {
+				return "", fmt.Errorf("pipeline stages must be documents")
+			}
+
+			p = append(p, d)
+		}
+
+		return AggregateToSQL(collection, p)
+	}
+
+	return "", fmt.Errorf("unsupported method %s", method)
+}
@@ -0,0 +1,233 @@
+package mongoToSql
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// doc parses a document in extended JSON for the test cases.
+func doc(t *testing.T, s string) bson.D {
+	t.Helper()
+
+	d := bson.D{}
+	err := bson.UnmarshalExtJSON([]byte(s), false, &d)
+	if err != nil {
+		t.Fatalf("invalid test document %s: %v", s, err)
+	}
+
+	return d
+}
+
+// pipeline parses an array of stages in extended JSON for the test cases.
+func pipeline(t *testing.T, s string) mongo.Pipeline {
+	t.Helper()
+
+	var stages struct {
+		Stages []bson.D `bson:"stages"`
+	}
+	err := bson.UnmarshalExtJSON([]byte(`{"stages": `+s+`}`), false, &stages)
+	if err != nil {
+		t.Fatalf("invalid test pipeline %s: %v", s, err)
+	}
+
+	return stages.Stages
+}
+
+func TestFindToSQL(t *testing.T) {
+	tests := []struct {
+		name       string
+		filter     string
+		projection string
+		want       string
+	}{
+		{
+			name:       "equality and comparision",
+			filter:     `{"SALARY": {"$gt": 10}, "NAME": "A"}`,
+			projection: `{"NAME": 1, "_id": 0}`,
+			want:       "SELECT NAME FROM EMP WHERE SALARY > 10 AND NAME = 'A';",
+		},
+		{
+			name:   "primary key in _id",
+			filter: `{"_id.ID": {"$lte": 3}}`,
+			want:   "SELECT * FROM EMP WHERE ID <= 3;",
+		},
+		{
+			name:   "$ne also matches NULL",
+			filter: `{"NAME": {"$ne": "A"}}`,
+			want:   "SELECT * FROM EMP WHERE (NAME <> 'A' OR NAME IS NULL);",
+		},
+		{
+			name:   "$ne null",
+			filter: `{"NAME": {"$ne": null}}`,
+			want:   "SELECT * FROM EMP WHERE NAME IS NOT NULL;",
+		},
+		{
+			name:   "$eq null",
+			filter: `{"NAME": null}`,
+			want:   "SELECT * FROM EMP WHERE NAME IS NULL;",
+		},
+		{
+			name:   "$in",
+			filter: `{"ID": {"$in": [1, 2]}}`,
+			want:   "SELECT * FROM EMP WHERE ID IN (1, 2);",
+		},
+		{
+			name:   "$nin also matches NULL",
+			filter: `{"ID": {"$nin": [1, 2]}}`,
+			want:   "SELECT * FROM EMP WHERE (ID NOT IN (1, 2) OR ID IS NULL);",
+		},
+		{
+			name:   "$exists false",
+			filter: `{"NAME": {"$exists": false}}`,
+			want:   "SELECT * FROM EMP WHERE NAME IS NULL;",
+		},
+		{
+			name:   "$or",
+			filter: `{"$or": [{"_id.ID": 1}, {"NAME": {"$lte": "B"}}]}`,
+			want:   "SELECT * FROM EMP WHERE ID = 1 OR NAME <= 'B';",
+		},
+		{
+			name:   "$or inside $and",
+			filter: `{"$or": [{"ID": 1}, {"ID": 2}], "NAME": "A"}`,
+			want:   "SELECT * FROM EMP WHERE (ID = 1 OR ID = 2) AND NAME = 'A';",
+		},
+		{
+			name:   "date",
+			filter: `{"D": {"$gte": {"$date": "2020-01-02T00:00:00Z"}}}`,
+			want: "SELECT * FROM EMP WHERE " +
+				"D >= TIMESTAMP '2020-01-02 00:00:00.000';",
+		},
+		{
+			name:   "string with quote",
+			filter: `{"NAME": "O'Brien"}`,
+			want:   "SELECT * FROM EMP WHERE NAME = 'O''Brien';",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			projection := bson.D{}
+			if test.projection != "" {
+				projection = doc(t, test.projection)
+			}
+
+			got, err := FindToSQL("EMP", doc(t, test.filter), projection)
+			if err != nil {
+				t.Fatalf("FindToSQL failed: %v", err)
+			}
+
+			if got != test.want {
+				t.Errorf("FindToSQL got\n%s\nwant\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestFindToSQLErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		filter     string
+		projection string
+	}{
+		{"unknown operator", `{"NAME": {"$regex": "A.*"}}`, `{}`},
+		{"empty $in", `{"ID": {"$in": []}}`, `{}`},
+		{"top level operator", `{"$where": "true"}`, `{}`},
+		{"excluding projection", `{}`, `{"NAME": 0}`},
+		{"_id excluding projection", `{}`, `{"_id": 0}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := FindToSQL(
+				"EMP", doc(t, test.filter), doc(t, test.projection),
+			)
+			if err == nil {
+				t.Errorf("FindToSQL of %s did not fail", test.filter)
+			}
+		})
+	}
+}
+
+func TestAggregateToSQL(t *testing.T) {
+	lookup := `{"$lookup": {"from": "DEPT", "localField": "DEPT_ID", ` +
+		`"foreignField": "_id.ID", "as": "DEPT"}}, {"$unwind": "$DEPT"}`
+
+	tests := []struct {
+		name     string
+		pipeline string
+		want     string
+	}{
+		{
+			name: "join",
+			pipeline: `[{"$match": {"SALARY": {"$gt": 10}}}, ` + lookup +
+				`, {"$project": {"DEPT.NAME": 1, "SALARY": 1, "_id": 0}}]`,
+			want: "SELECT DEPT.NAME, EMP.SALARY FROM EMP " +
+				"JOIN DEPT ON DEPT.ID = EMP.DEPT_ID WHERE EMP.SALARY > 10;",
+		},
+		{
+			name: "join with group",
+			pipeline: `[` + lookup + `, {"$group": {"_id": "$DEPT.NAME", ` +
+				`"N": {"$count": {}}}}]`,
+			want: `SELECT DEPT.NAME, COUNT(*) AS "N" FROM EMP ` +
+				"JOIN DEPT ON DEPT.ID = EMP.DEPT_ID GROUP BY DEPT.NAME;",
+		},
+		{
+			name: "group with having, order and pagination",
+			pipeline: `[{"$group": {"_id": "$DEPT_ID", ` +
+				`"TOTAL": {"$sum": "$SALARY"}, "N": {"$count": {}}}}, ` +
+				`{"$match": {"TOTAL": {"$gt": 100}}}, ` +
+				`{"$sort": {"TOTAL": -1}}, {"$skip": 5}, {"$limit": 10}]`,
+			want: `SELECT DEPT_ID, SUM(SALARY) AS "TOTAL", COUNT(*) AS "N" ` +
+				"FROM EMP GROUP BY DEPT_ID HAVING SUM(SALARY) > 100 " +
+				"ORDER BY SUM(SALARY) DESC OFFSET 5 ROWS " +
+				"FETCH FIRST 10 ROWS ONLY;",
+		},
+		{
+			name:     "smallest limit",
+			pipeline: `[{"$limit": 10}, {"$limit": 3}]`,
+			want:     "SELECT * FROM EMP FETCH FIRST 3 ROWS ONLY;",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := AggregateToSQL("EMP", pipeline(t, test.pipeline))
+			if err != nil {
+				t.Fatalf("AggregateToSQL failed: %v", err)
+			}
+
+			if got != test.want {
+				t.Errorf("AggregateToSQL got\n%s\nwant\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestAggregateToSQLErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		pipeline string
+	}{
+		{
+			name: "$lookup without $unwind",
+			pipeline: `[{"$lookup": {"from": "DEPT", "localField": "DEPT_ID", ` +
+				`"foreignField": "_id.ID", "as": "DEPT"}}]`,
+		},
+		{"unsupported stage", `[{"$out": "X"}]`},
+		{"$skip after $limit", `[{"$limit": 3}, {"$skip": 1}]`},
+		{"$match after $limit", `[{"$limit": 3}, {"$match": {"ID": 1}}]`},
+		{"$match after $skip", `[{"$skip": 3}, {"$match": {"ID": 1}}]`},
+		{"excluding $project", `[{"$project": {"NAME": 0, "_id": 0}}]`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := AggregateToSQL("EMP", pipeline(t, test.pipeline))
+			if err == nil {
+				t.Errorf("AggregateToSQL of %s did not fail", test.pipeline)
+			}
+		})
+	}
+}
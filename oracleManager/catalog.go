@@ -0,0 +1,230 @@
+package oracleManager
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// A Catalog provides the metadata of a database schema: its owner (empty for
+// the schema logged in), tables, columns and constraints. All table and column
+// names are case insensitive.
+type Catalog interface {
+	GetOwner() string
+	GetTables() ([]string, error)
+	GetSources() ([]Source, error)
+	GetColumns(table string) []ColumnInfo
+	GetPrimaryKeys(table string) []string
+	GetReferences() ([]Reference, error)
+	GetUniques() ([]UniqueEntry, error)
+	GetIndexes() ([]IndexEntry, error)
+	GetChecks() ([]CheckEntry, error)
+}
+
+// TableExists indicates if a table has any columns in the catalog.
+func TableExists(c Catalog, table string) bool {
+	return len(c.GetColumns(table)) != 0
+}
+
+// GetColumn gets the ColumnInfo of a column in a table of the catalog,
+// returning false if there is no such column.
+func GetColumn(c Catalog, table, column string) (ColumnInfo, bool) {
+	for _, col := range c.GetColumns(table) {
+		if col.Name == strings.ToUpper(column) {
+			return col, true
+		}
+	}
+
+	return ColumnInfo{}, false
+}
+
+// TableContainsColumn indicates if a column is in a table of the catalog.
+func TableContainsColumn(c Catalog, table, columnToCheck string) bool {
+	_, ok := GetColumn(c, table, columnToCheck)
+	return ok
+}
+
+// struct OracleCatalog represents the Catalog of an oracle connection. The
+// sources, columns and primary keys are read once when it is created, while
+// the other constraints are read every time they are requested.
+type OracleCatalog struct {
+	db    *sql.DB
+	owner string
+
+	sources []Source
+
+	columns     map[string][]ColumnInfo
+	primaryKeys map[string][]string
+}
+
+// NewOracleCatalog creates the Catalog of a schema owner (or of the schema
+// logged in, if owner is empty) in an oracle connection.
+func NewOracleCatalog(db *sql.DB, owner string) (*OracleCatalog, error) {
+	columns, err := GetTableColumns(db, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	pks, err := GetPrimaryKeys(db, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	sources, err := GetSources(db, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	// synonyms have the columns and primary keys of their base objects, which
+	// may be in other schemas, so these are read once for each schema
+	type schema struct {
+		columns map[string][]ColumnInfo
+		pks     map[string][]string
+	}
+	schemas := map[string]schema{}
+
+	for _, src := range sources {
+		if src.Kind != SourceSynonym {
+			continue
+		}
+
+		base, ok := schemas[src.BaseOwner]
+		if !ok {
+			base.columns, err = GetTableColumns(db, src.BaseOwner)
+			if err != nil {
+				return nil, err
+			}
+
+			base.pks, err = GetPrimaryKeys(db, src.BaseOwner)
+			if err != nil {
+				return nil, err
+			}
+
+			schemas[src.BaseOwner] = base
+		}
+
+		columns[src.Name] = base.columns[src.BaseName]
+		pks[src.Name] = base.pks[src.BaseName]
+	}
+
+	return &OracleCatalog{
+		db:          db,
+		owner:       owner,
+		sources:     sources,
+		columns:     columns,
+		primaryKeys: pks,
+	}, nil
+}
+
+// GetOwner implements the Catalog interface.
+func (oc *OracleCatalog) GetOwner() string {
+	return oc.owner
+}
+
+// GetTables implements the Catalog interface.
+func (oc *OracleCatalog) GetTables() ([]string, error) {
+	return GetTables(oc.db, oc.owner)
+}
+
+// GetSources implements the Catalog interface.
+func (oc *OracleCatalog) GetSources() ([]Source, error) {
+	return oc.sources, nil
+}
+
+// GetColumns implements the Catalog interface.
+func (oc *OracleCatalog) GetColumns(table string) []ColumnInfo {
+	return oc.columns[strings.ToUpper(table)]
+}
+
+// GetPrimaryKeys implements the Catalog interface.
+func (oc *OracleCatalog) GetPrimaryKeys(table string) []string {
+	return oc.primaryKeys[strings.ToUpper(table)]
+}
+
+// GetReferences implements the Catalog interface.
+func (oc *OracleCatalog) GetReferences() ([]Reference, error) {
+	return GetReferences(oc.db, oc.owner)
+}
+
+// GetUniques implements the Catalog interface.
+func (oc *OracleCatalog) GetUniques() ([]UniqueEntry, error) {
+	return GetUniques(oc.db, oc.owner)
+}
+
+// GetIndexes implements the Catalog interface.
+func (oc *OracleCatalog) GetIndexes() ([]IndexEntry, error) {
+	return GetIndexes(oc.db, oc.owner)
+}
+
+// GetChecks implements the Catalog interface.
+func (oc *OracleCatalog) GetChecks() ([]CheckEntry, error) {
+	return GetChecks(oc.db, oc.owner)
+}
+
+// struct MemoryCatalog represents a Catalog kept entirely in memory, such as
+// one read from a DDL file. All names in it must be in upper case, like in the
+// oracle data dictionary. If Sources is nil, the sources are the tables.
+type MemoryCatalog struct {
+	Owner       string
+	Tables      []string
+	Sources     []Source
+	Columns     map[string][]ColumnInfo
+	PrimaryKeys map[string][]string
+	References  []Reference
+	Uniques     []UniqueEntry
+	Indexes     []IndexEntry
+	Checks      []CheckEntry
+}
+
+// GetOwner implements the Catalog interface.
+func (mc *MemoryCatalog) GetOwner() string {
+	return mc.Owner
+}
+
+// GetTables implements the Catalog interface.
+func (mc *MemoryCatalog) GetTables() ([]string, error) {
+	return mc.Tables, nil
+}
+
+// GetSources implements the Catalog interface.
+func (mc *MemoryCatalog) GetSources() ([]Source, error) {
+	if mc.Sources != nil {
+		return mc.Sources, nil
+	}
+
+	result := []Source{}
+	for _, table := range mc.Tables {
+		result = append(result, Source{Name: table, Kind: SourceTable})
+	}
+
+	return result, nil
+}
+
+// GetColumns implements the Catalog interface.
+func (mc *MemoryCatalog) GetColumns(table string) []ColumnInfo {
+	return mc.Columns[strings.ToUpper(table)]
+}
+
+// GetPrimaryKeys implements the Catalog interface.
+func (mc *MemoryCatalog) GetPrimaryKeys(table string) []string {
+	return mc.PrimaryKeys[strings.ToUpper(table)]
+}
+
+// GetReferences implements the Catalog interface.
+func (mc *MemoryCatalog) GetReferences() ([]Reference, error) {
+	return mc.References, nil
+}
+
+// GetUniques implements the Catalog interface.
+func (mc *MemoryCatalog) GetUniques() ([]UniqueEntry, error) {
+	return mc.Uniques, nil
+}
+
+// GetIndexes implements the Catalog interface.
+func (mc *MemoryCatalog) GetIndexes() ([]IndexEntry, error) {
+	return mc.Indexes, nil
+}
+
+// GetChecks implements the Catalog interface.
+func (mc *MemoryCatalog) GetChecks() ([]CheckEntry, error) {
+	return mc.Checks, nil
+}
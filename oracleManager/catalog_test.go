@@ -0,0 +1,24 @@
+package oracleManager
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMemoryCatalogSources(t *testing.T) {
+	tables := &MemoryCatalog{Tables: []string{"EMP", "DEPT"}}
+	want := []Source{
+		{Name: "EMP", Kind: SourceTable},
+		{Name: "DEPT", Kind: SourceTable},
+	}
+	if got, err := tables.GetSources(); err != nil ||
+		!reflect.DeepEqual(got, want) {
+		t.Errorf("sources of tables got %v, %v, want %v", got, err, want)
+	}
+
+	c := testCatalog()
+	if got, err := c.GetSources(); err != nil ||
+		!reflect.DeepEqual(got, c.Sources) {
+		t.Errorf("sources got %v, %v, want %v", got, err, c.Sources)
+	}
+}
@@ -1,44 +1,54 @@
 package oracleManager
 
-import "database/sql"
+import (
+	"database/sql"
+	"fmt"
+)
 
 // struct CheckEntry represents a check constraint in an oracle database,
-// having a table that it applies to and a check condition itself.
+// having its name, the table that it applies to and the check condition itself.
+// Primary keys are also represented as the NOT NULL checks of their columns.
 type CheckEntry struct {
+	Name  string
 	Table string
 	Check string
 }
 
-// GetChecks obtains all CheckEntries from a connection. It concatenates all
-// the check conditions such that there is only one per table.
-func GetChecks(db *sql.DB) ([]CheckEntry, error) {
-	// obtain the table and search condition for all constraint of type check,
-	// and the columns for all constraints of type primary key
-	s := `
-    SELECT C.TABLE_NAME, C.CONSTRAINT_TYPE,
+// GetChecks obtains all CheckEntries from a connection, one per constraint.
+func GetChecks(db *sql.DB, owner string) ([]CheckEntry, error) {
+	d := dictionary{owner}
+
+	// obtain the name, table and search condition for all constraint of type
+	// check, and the columns for all constraints of type primary key
+	s := fmt.Sprintf(`
+    SELECT C.CONSTRAINT_NAME, C.TABLE_NAME, C.CONSTRAINT_TYPE,
       CC.COLUMN_NAME, C.SEARCH_CONDITION_VC
-    FROM USER_CONSTRAINTS C
-      LEFT JOIN USER_CONS_COLUMNS CC ON CC.CONSTRAINT_NAME = C.CONSTRAINT_NAME
-        AND C.CONSTRAINT_TYPE = 'P'
-    WHERE C.CONSTRAINT_TYPE IN ('C', 'P')
-    ORDER BY C.TABLE_NAME
-  `
+    FROM %s C
+      LEFT JOIN %s CC ON CC.CONSTRAINT_NAME = C.CONSTRAINT_NAME
+        AND C.CONSTRAINT_TYPE = 'P' %s
+    WHERE C.CONSTRAINT_TYPE IN ('C', 'P') %s
+    ORDER BY C.TABLE_NAME, C.CONSTRAINT_NAME, CC.POSITION
+  `, d.view("CONSTRAINTS"), d.view("CONS_COLUMNS"),
+		d.join("CC.OWNER", "C.OWNER"), d.and("C.OWNER"),
+	)
 
-	rows, err := db.Query(s)
+	rows, err := db.Query(s, d.args()...)
 	if err != nil {
 		return nil, err
 	}
 
 	result := []CheckEntry{}
-	tableNamePrev := ""
+	constraintPrev := ""
 
 	// for each check
 	for rows.Next() {
-		var tableName, constraintType, ckStr string
+		var constraintName, tableName, constraintType, ckStr string
 		var columnName, searchCondition sql.NullString
 
 		// scan it
-		err = rows.Scan(&tableName, &constraintType, &columnName, &searchCondition)
+		err = rows.Scan(&constraintName, &tableName, &constraintType,
+			&columnName, &searchCondition,
+		)
 		if err != nil {
 			return nil, err
 		}
@@ -50,20 +60,21 @@ func GetChecks(db *sql.DB) ([]CheckEntry, error) {
 			ckStr = searchCondition.String
 		}
 
-		// if we are still in the same table, concatenate the previous check with
-		// the new condition
-		if tableName == tableNamePrev {
-			result[len(result)-1].Check += " AND (" + ckStr + ")"
+		// if we are still in the same primary key, concatenate the previous
+		// column with the new one
+		if constraintName == constraintPrev {
+			result[len(result)-1].Check += " AND " + ckStr
 			continue
 		}
 
-		// if we changed tables, append a new CheckEntry
-		result = append(
-			result,
-			CheckEntry{Table: tableName, Check: "(" + ckStr + ")"},
-		)
+		// if we changed constraints, append a new CheckEntry
+		result = append(result, CheckEntry{
+			Name:  constraintName,
+			Table: tableName,
+			Check: ckStr,
+		})
 
-		tableNamePrev = tableName
+		constraintPrev = constraintName
 	}
 
 	err = rows.Close()
@@ -71,9 +82,5 @@ func GetChecks(db *sql.DB) ([]CheckEntry, error) {
 		return nil, err
 	}
 
-	for i := range result {
-		result[i].Check += ";"
-	}
-
 	return result, nil
 }
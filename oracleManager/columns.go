@@ -0,0 +1,80 @@
+package oracleManager
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// struct ColumnInfo represents the metadata of an oracle table column, as in
+// USER_TAB_COLUMNS. Precision and Scale are nil when oracle does not define
+// them (such as for a NUMBER without precision), and Default is the text of
+// the DEFAULT expression, or an empty string if there is none.
+type ColumnInfo struct {
+	Name       string
+	DataType   string
+	DataLength int64
+	Precision  *int64
+	Scale      *int64
+	Nullable   bool
+	Default    string
+	CharLength int64
+}
+
+// GetTableColumns gets the ColumnInfo of all columns of each table, in the
+// order they were defined.
+func GetTableColumns(
+	db *sql.DB, owner string,
+) (map[string][]ColumnInfo, error) {
+	d := dictionary{owner}
+
+	s := fmt.Sprintf(`
+  SELECT TABLE_NAME, COLUMN_NAME, DATA_TYPE, DATA_LENGTH, DATA_PRECISION,
+    DATA_SCALE, NULLABLE, DATA_DEFAULT, CHAR_LENGTH
+  FROM %s
+  %s
+  ORDER BY TABLE_NAME, COLUMN_ID
+  `, d.view("TAB_COLUMNS"), d.where("OWNER"))
+
+	rows, err := db.Query(s, d.args()...)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := map[string][]ColumnInfo{}
+
+	for rows.Next() {
+		var table, nullable string
+		var col ColumnInfo
+		var dataType, dataDefault sql.NullString
+		var precision, scale, charLength sql.NullInt64
+
+		err = rows.Scan(&table, &col.Name, &dataType, &col.DataLength,
+			&precision, &scale, &nullable, &dataDefault, &charLength,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		col.DataType = dataType.String
+		col.Nullable = nullable == "Y"
+		col.Default = strings.TrimSpace(dataDefault.String)
+		col.CharLength = charLength.Int64
+
+		if precision.Valid {
+			col.Precision = &precision.Int64
+		}
+		if scale.Valid {
+			col.Scale = &scale.Int64
+		}
+
+		columns[table] = append(columns[table], col)
+	}
+
+	err = rows.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return columns, nil
+}
@@ -1,6 +1,10 @@
 package oracleManager
 
-import "database/sql"
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
 
 // struct UniqueEntry represents an unique constraint in oracle, having a base
 // table and columns that generate the unique constraint.
@@ -10,20 +14,23 @@ type UniqueEntry struct {
 }
 
 // GetUniques obtains all the unique constraints in the database as an array.
-func GetUniques(db *sql.DB) ([]UniqueEntry, error) {
+func GetUniques(db *sql.DB, owner string) ([]UniqueEntry, error) {
+	d := dictionary{owner}
 
 	// get all constraint names, tables and columns that are of constraint type
 	// unique, ordering by constraint name to make it easier to differentiate
 	// old from new UniqueEntries.
-	s := `
+	s := fmt.Sprintf(`
     SELECT C.CONSTRAINT_NAME, C.TABLE_NAME, CC.COLUMN_NAME
-    FROM USER_CONSTRAINTS C
-      JOIN USER_CONS_COLUMNS CC ON CC.CONSTRAINT_NAME = C.CONSTRAINT_NAME
-    WHERE C.CONSTRAINT_TYPE = 'U'
+    FROM %s C
+      JOIN %s CC ON CC.CONSTRAINT_NAME = C.CONSTRAINT_NAME %s
+    WHERE C.CONSTRAINT_TYPE = 'U' %s
     ORDER BY C.CONSTRAINT_NAME
-  `
+  `, d.view("CONSTRAINTS"), d.view("CONS_COLUMNS"),
+		d.join("CC.OWNER", "C.OWNER"), d.and("C.OWNER"),
+	)
 
-	rows, err := db.Query(s)
+	rows, err := db.Query(s, d.args()...)
 	if err != nil {
 		return nil, err
 	}
@@ -66,3 +73,113 @@ func GetUniques(db *sql.DB) ([]UniqueEntry, error) {
 
 	return result, nil
 }
+
+// struct IndexColumn represents a column of an index, which is either a table
+// column (Column) or, for function-based indexes, an expression over the
+// columns (Expression, as in USER_IND_EXPRESSIONS.COLUMN_EXPRESSION).
+type IndexColumn struct {
+	Column     string
+	Expression string
+	Descending bool
+}
+
+// struct IndexEntry represents an oracle B-tree index, with the index name,
+// its table, if it is unique and its columns, in order.
+type IndexEntry struct {
+	Name    string
+	Table   string
+	Unique  bool
+	Columns []IndexColumn
+}
+
+// GetIndexes obtains all the ordinary and function-based B-tree indexes in the
+// database, except the ones created for primary key and unique constraints
+// (which are already in the _id and in GetUniques).
+func GetIndexes(db *sql.DB, owner string) ([]IndexEntry, error) {
+	d := dictionary{owner}
+
+	// get all index columns, with their expressions for function-based ones,
+	// ordered by index and column position so that the columns of an index are
+	// in order and together
+	s := fmt.Sprintf(`
+    SELECT I.INDEX_NAME, I.TABLE_NAME, I.UNIQUENESS, IC.COLUMN_NAME,
+      IC.DESCEND, IE.COLUMN_EXPRESSION
+    FROM %[1]s I
+      JOIN %[2]s IC ON IC.INDEX_NAME = I.INDEX_NAME %[4]s
+      LEFT JOIN %[3]s IE ON IE.INDEX_NAME = IC.INDEX_NAME
+        AND IE.COLUMN_POSITION = IC.COLUMN_POSITION %[5]s
+    WHERE I.INDEX_TYPE IN ('NORMAL', 'FUNCTION-BASED NORMAL')
+      AND NOT EXISTS (
+        SELECT 1 FROM %[6]s C
+        WHERE C.INDEX_NAME = I.INDEX_NAME AND C.CONSTRAINT_TYPE IN ('P', 'U')
+          %[7]s
+      ) %[8]s
+    ORDER BY I.INDEX_NAME, IC.COLUMN_POSITION
+  `, d.view("INDEXES"), d.view("IND_COLUMNS"), d.view("IND_EXPRESSIONS"),
+		d.join("IC.INDEX_OWNER", "I.OWNER"), d.join("IE.INDEX_OWNER", "I.OWNER"),
+		d.view("CONSTRAINTS"), d.join("C.INDEX_OWNER", "I.OWNER"),
+		d.and("I.OWNER"),
+	)
+
+	rows, err := db.Query(s, d.args()...)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []IndexEntry{}
+	indexNamePrev := ""
+
+	for rows.Next() {
+		var indexName, tableName, uniqueness, columnName, descend string
+		var expression sql.NullString
+
+		err = rows.Scan(&indexName, &tableName, &uniqueness, &columnName,
+			&descend, &expression,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		col := IndexColumn{
+			Column:     columnName,
+			Descending: descend == "DESC",
+		}
+
+		// oracle creates descending columns as expressions with only the quoted
+		// column name, so these are converted back to columns
+		if expression.Valid {
+			e := strings.TrimSpace(expression.String)
+			if strings.Count(e, `"`) == 2 && strings.HasPrefix(e, `"`) &&
+				strings.HasSuffix(e, `"`) {
+				col.Column = strings.Trim(e, `"`)
+			} else {
+				col.Column = ""
+				col.Expression = e
+			}
+		}
+
+		// if we are in the same index, append the column to it
+		if indexName == indexNamePrev {
+			result[len(result)-1].Columns = append(
+				result[len(result)-1].Columns, col,
+			)
+			continue
+		}
+
+		result = append(result, IndexEntry{
+			Name:    indexName,
+			Table:   tableName,
+			Unique:  uniqueness == "UNIQUE",
+			Columns: []IndexColumn{col},
+		})
+
+		indexNamePrev = indexName
+	}
+
+	err = rows.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
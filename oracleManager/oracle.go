@@ -8,10 +8,6 @@ import (
 	_ "github.com/sijms/go-ora/v2"
 )
 
-var (
-	tableColumns map[string][]string
-)
-
 // Login logs in to a oracle database, returning the connection.
 func Login(url, user, password string) (*sql.DB, error) {
 	conn, err := sql.Open(
@@ -28,67 +24,91 @@ func Login(url, user, password string) (*sql.DB, error) {
 		return nil, err
 	}
 
-	// initalise the table to columns map
-	err = initTableColumnsMap(conn)
-	if err != nil {
-		return nil, err
+	return conn, nil
+}
+
+// struct dictionary builds the data dictionary queries for a schema owner. For
+// an empty owner (the schema logged in) the USER_* views are used, and for
+// other owners the ALL_* views are used, filtered by the OWNER column, which
+// only show the objects the user has been granted access to.
+type dictionary struct {
+	owner string
+}
+
+// view gets the name of a data dictionary view, such as "TABLES".
+func (d dictionary) view(name string) string {
+	if d.owner == "" {
+		return "USER_" + name
 	}
 
-	return conn, nil
+	return "ALL_" + name
 }
 
-// initTableColumnsMap initalises the table to columns relation map with the
-// newly created sql connection.
-func initTableColumnsMap(db *sql.DB) error {
-	rows, err := db.Query("SELECT TABLE_NAME, COLUMN_NAME FROM USER_TAB_COLUMNS")
-	if err != nil {
-		return err
+// where gets the WHERE clause that filters a query by the owner column.
+func (d dictionary) where(column string) string {
+	if d.owner == "" {
+		return ""
 	}
 
-	tableColumns = map[string][]string{}
+	return "WHERE " + column + " = :owner"
+}
 
-	for rows.Next() {
-		var table, column string
+// and gets the condition that filters a query by the owner column, to be
+// added after another condition.
+func (d dictionary) and(column string) string {
+	if d.owner == "" {
+		return ""
+	}
 
-		err = rows.Scan(&table, &column)
-		if err != nil {
-			return err
-		}
+	return "AND " + column + " = :owner"
+}
 
-		tableColumns[table] = append(tableColumns[table], column)
+// join gets the condition that two views in a join have the same owner, to be
+// added after another condition.
+func (d dictionary) join(column1, column2 string) string {
+	if d.owner == "" {
+		return ""
 	}
 
-	return rows.Close()
+	return "AND " + column1 + " = " + column2
 }
 
-func TableContainsColumn(table, columnToCheck string) bool {
-	columns, ok := tableColumns[strings.ToUpper(table)]
-	if !ok {
-		return false
+// args gets the arguments for a query built with where or and.
+func (d dictionary) args() []any {
+	if d.owner == "" {
+		return nil
 	}
 
-	for _, col := range columns {
-		if col == strings.ToUpper(columnToCheck) {
-			return true
-		}
+	return []any{strings.ToUpper(d.owner)}
+}
+
+// QualifiedName gets the name of a table to be used in a SELECT, qualified with
+// its owner if there is one. Both parts are quoted, since oracle upper cases
+// unquoted names and rejects the ones that are reserved words.
+func QualifiedName(owner, table string) string {
+	if owner == "" {
+		return `"` + table + `"`
 	}
 
-	return false
+	return `"` + strings.ToUpper(owner) + `"."` + table + `"`
 }
 
 // GetPrimaryKeys gets all the primary keys in the databse in a map form taking
 // the table name and returning all the column names that are primary keys.
-func GetPrimaryKeys(db *sql.DB) (map[string][]string, error) {
+// The owner is the schema to read, or an empty string for the schema logged
+// in, like in all other functions that read the data dictionary.
+func GetPrimaryKeys(db *sql.DB, owner string) (map[string][]string, error) {
 	pks := map[string][]string{}
+	d := dictionary{owner}
 
-	s := `
+	s := fmt.Sprintf(`
   SELECT TABLE_NAME, COLUMN_NAME
-  FROM USER_CONS_COLUMNS
-    NATURAL JOIN USER_CONSTRAINTS
-  WHERE CONSTRAINT_TYPE = 'P'
-  `
+  FROM %s
+    NATURAL JOIN %s
+  WHERE CONSTRAINT_TYPE = 'P' %s
+  `, d.view("CONS_COLUMNS"), d.view("CONSTRAINTS"), d.and("OWNER"))
 
-	rows, err := db.Query(s)
+	rows, err := db.Query(s, d.args()...)
 	if err != nil {
 		return nil, err
 	}
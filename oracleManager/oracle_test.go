@@ -0,0 +1,23 @@
+package oracleManager
+
+import "testing"
+
+func TestQualifiedName(t *testing.T) {
+	tests := []struct {
+		owner, table string
+		want         string
+	}{
+		{"", "EMP", `"EMP"`},
+		{"hr", "EMP", `"HR"."EMP"`},
+		{"HR", "order", `"HR"."order"`},
+	}
+
+	for _, test := range tests {
+		got := QualifiedName(test.owner, test.table)
+		if got != test.want {
+			t.Errorf("QualifiedName(%q, %q) got %s, want %s",
+				test.owner, test.table, got, test.want,
+			)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package oracleManager
 
 import (
 	"database/sql"
+	"fmt"
 )
 
 // struct Reference represents an oracle foreign key constraint from a table
@@ -15,26 +16,31 @@ type Reference struct {
 }
 
 // GetReferences gets all the foreign key references as an array.
-func GetReferences(db *sql.DB) ([]Reference, error) {
+func GetReferences(db *sql.DB, owner string) ([]Reference, error) {
+	d := dictionary{owner}
+
 	// get the constraint name, table and columns that generate the reference,
 	// and table and columns that are referenced for each constraint of type
 	// reference, ordered by constraint name to keep track of our current
 	// reference easily.
-	s := `
+	s := fmt.Sprintf(`
   SELECT C.CONSTRAINT_NAME, 
     CC.TABLE_NAME AS TABLE_REFERENCER, CC.COLUMN_NAME AS COLUMN_REFERENCER,
     RCC.TABLE_NAME AS TABLE_REFERENCED, RCC.COLUMN_NAME AS COLUMN_REFERENCED
-  FROM USER_CONSTRAINTS C
-    JOIN USER_CONS_COLUMNS CC ON
-      CC.CONSTRAINT_NAME = C.CONSTRAINT_NAME
-    JOIN USER_CONS_COLUMNS RCC ON
+  FROM %[1]s C
+    JOIN %[2]s CC ON
+      CC.CONSTRAINT_NAME = C.CONSTRAINT_NAME %[3]s
+    JOIN %[2]s RCC ON
       C.R_CONSTRAINT_NAME = RCC.CONSTRAINT_NAME
-      AND RCC.POSITION = CC.POSITION
-  WHERE C.CONSTRAINT_TYPE = 'R'
+      AND RCC.POSITION = CC.POSITION %[4]s
+  WHERE C.CONSTRAINT_TYPE = 'R' %[5]s
   ORDER BY CONSTRAINT_NAME
-  `
+  `, d.view("CONSTRAINTS"), d.view("CONS_COLUMNS"),
+		d.join("CC.OWNER", "C.OWNER"), d.join("RCC.OWNER", "C.R_OWNER"),
+		d.and("C.OWNER"),
+	)
 
-	rows, err := db.Query(s)
+	rows, err := db.Query(s, d.args()...)
 	if err != nil {
 		return nil, err
 	}
@@ -80,8 +86,13 @@ func GetReferences(db *sql.DB) ([]Reference, error) {
 
 // GetTables obtains all tables from an oracle connection and returns them in
 // an array.
-func GetTables(db *sql.DB) ([]string, error) {
-	rows, err := db.Query("SELECT TABLE_NAME FROM USER_TABLES")
+func GetTables(db *sql.DB, owner string) ([]string, error) {
+	d := dictionary{owner}
+
+	rows, err := db.Query(
+		"SELECT TABLE_NAME FROM "+d.view("TABLES")+" "+d.where("OWNER"),
+		d.args()...,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,147 @@
+package oracleManager
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SnapshotVersion is the version of the Snapshot format, increased every time
+// it changes in a way that older versions cannot be read.
+const SnapshotVersion = 1
+
+// struct Snapshot represents all the metadata of a Catalog, in a form that can
+// be saved in a JSON or YAML file and read back later as a MemoryCatalog, for
+// working without an oracle connection. The fields are encoded with the same
+// names as the structs (in lower case for YAML).
+type Snapshot struct {
+	Version       int
+	MemoryCatalog `yaml:",inline"`
+}
+
+// NewSnapshot reads all the metadata of a Catalog.
+func NewSnapshot(c Catalog) (*Snapshot, error) {
+	s := &Snapshot{
+		Version: SnapshotVersion,
+		MemoryCatalog: MemoryCatalog{
+			Owner:       c.GetOwner(),
+			Columns:     map[string][]ColumnInfo{},
+			PrimaryKeys: map[string][]string{},
+		},
+	}
+
+	var err error
+	s.Tables, err = c.GetTables()
+	if err != nil {
+		return nil, err
+	}
+
+	s.Sources, err = c.GetSources()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, src := range s.Sources {
+		s.Columns[src.Name] = c.GetColumns(src.Name)
+
+		if pks := c.GetPrimaryKeys(src.Name); len(pks) != 0 {
+			s.PrimaryKeys[src.Name] = pks
+		}
+	}
+
+	s.References, err = c.GetReferences()
+	if err != nil {
+		return nil, err
+	}
+
+	s.Uniques, err = c.GetUniques()
+	if err != nil {
+		return nil, err
+	}
+
+	s.Indexes, err = c.GetIndexes()
+	if err != nil {
+		return nil, err
+	}
+
+	s.Checks, err = c.GetChecks()
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Catalog gets the Catalog with the metadata in the Snapshot.
+func (s *Snapshot) Catalog() *MemoryCatalog {
+	return &s.MemoryCatalog
+}
+
+// SnapshotFormat gets the format of a snapshot file from its name, being
+// either "json" (for .json files) or "yaml" (for .yaml and .yml files).
+func SnapshotFormat(name string) (string, error) {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".json":
+		return "json", nil
+	case ".yaml", ".yml":
+		return "yaml", nil
+	}
+
+	return "", fmt.Errorf("snapshot %s is not a .json, .yaml or .yml file", name)
+}
+
+// EncodeSnapshot writes a Snapshot in a format (as in SnapshotFormat).
+func EncodeSnapshot(w io.Writer, s *Snapshot, format string) error {
+	switch format {
+	case "json":
+		e := json.NewEncoder(w)
+		e.SetIndent("", "  ")
+		return e.Encode(s)
+
+	case "yaml":
+		e := yaml.NewEncoder(w)
+		e.SetIndent(2)
+
+		err := e.Encode(s)
+		if err != nil {
+			return err
+		}
+
+		return e.Close()
+	}
+
+	return fmt.Errorf("invalid snapshot format %s", format)
+}
+
+// DecodeSnapshot reads a Snapshot in a format (as in SnapshotFormat), checking
+// its version.
+func DecodeSnapshot(r io.Reader, format string) (*Snapshot, error) {
+	s := &Snapshot{}
+
+	var err error
+	switch format {
+	default:
+		return nil, fmt.Errorf("invalid snapshot format %s", format)
+	case "json":
+		err = json.NewDecoder(r).Decode(s)
+	case "yaml":
+		err = yaml.NewDecoder(r).Decode(s)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if s.Version != SnapshotVersion {
+		return nil, fmt.Errorf(
+			"snapshot version %d is not supported, expected version %d",
+			s.Version, SnapshotVersion,
+		)
+	}
+
+	return s, nil
+}
@@ -0,0 +1,153 @@
+package oracleManager
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testCatalog gets a MemoryCatalog with every kind of metadata.
+func testCatalog() *MemoryCatalog {
+	five, two, zero := int64(5), int64(2), int64(0)
+
+	return &MemoryCatalog{
+		Owner:  "HR",
+		Tables: []string{"DEPT", "EMP"},
+		Sources: []Source{
+			{Name: "DEPT", Kind: SourceTable},
+			{Name: "EMP", Kind: SourceTable},
+			{Name: "EMP_V", Kind: SourceView, Text: "SELECT ID FROM EMP"},
+			{
+				Name: "E", Kind: SourceSynonym,
+				BaseOwner: "HR", BaseName: "EMP",
+			},
+		},
+		Columns: map[string][]ColumnInfo{
+			"DEPT": {
+				{Name: "ID", DataType: "NUMBER", DataLength: 22,
+					Precision: &five, Scale: &zero},
+				{Name: "NAME", DataType: "VARCHAR2", DataLength: 30,
+					Nullable: true, CharLength: 30},
+			},
+			"EMP": {
+				{Name: "ID", DataType: "NUMBER", DataLength: 22,
+					Precision: &five, Scale: &zero},
+				{Name: "SALARY", DataType: "NUMBER", DataLength: 22,
+					Precision: &five, Scale: &two, Nullable: true,
+					Default: "0"},
+				{Name: "DEPT_ID", DataType: "NUMBER", DataLength: 22,
+					Nullable: true},
+			},
+			"EMP_V": {
+				{Name: "ID", DataType: "NUMBER", DataLength: 22},
+			},
+			"E": {
+				{Name: "ID", DataType: "NUMBER", DataLength: 22},
+			},
+		},
+		PrimaryKeys: map[string][]string{
+			"DEPT": {"ID"},
+			"EMP":  {"ID"},
+		},
+		References: []Reference{{
+			ConstraintName:   "EMP_DEPT",
+			TableReferencer:  "EMP",
+			ColumnReferencer: []string{"DEPT_ID"},
+			TableReferenced:  "DEPT",
+			ColumnReferenced: []string{"ID"},
+		}},
+		Uniques: []UniqueEntry{{Table: "DEPT", Columns: []string{"NAME"}}},
+		Indexes: []IndexEntry{{
+			Name: "EMP_NAME", Table: "EMP",
+			Columns: []IndexColumn{
+				{Column: "SALARY", Descending: true},
+				{Expression: `UPPER("NAME")`},
+			},
+		}},
+		Checks: []CheckEntry{
+			{Name: "SALARY_POSITIVE", Table: "EMP", Check: "SALARY > 0"},
+		},
+	}
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	for _, format := range []string{"json", "yaml"} {
+		t.Run(format, func(t *testing.T) {
+			s, err := NewSnapshot(testCatalog())
+			if err != nil {
+				t.Fatalf("NewSnapshot failed: %v", err)
+			}
+
+			var buffer bytes.Buffer
+			err = EncodeSnapshot(&buffer, s, format)
+			if err != nil {
+				t.Fatalf("EncodeSnapshot failed: %v", err)
+			}
+
+			got, err := DecodeSnapshot(&buffer, format)
+			if err != nil {
+				t.Fatalf("DecodeSnapshot failed: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, s) {
+				t.Errorf("DecodeSnapshot got\n%+v\nwant\n%+v", got, s)
+			}
+
+			// the catalog read back answers like the original one
+			c := got.Catalog()
+			if pks := c.GetPrimaryKeys("emp"); !reflect.DeepEqual(
+				pks, []string{"ID"},
+			) {
+				t.Errorf("GetPrimaryKeys got %v", pks)
+			}
+			if col, ok := GetColumn(c, "EMP", "salary"); !ok ||
+				*col.Scale != 2 || col.Default != "0" {
+				t.Errorf("GetColumn got %+v, %v", col, ok)
+			}
+		})
+	}
+}
+
+func TestDecodeSnapshotErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		data   string
+	}{
+		{"other version", "json", `{"Version": 2}`},
+		{"missing version", "yaml", "owner: HR\n"},
+		{"invalid JSON", "json", `{"Version": `},
+		{"unknown format", "xml", `<snapshot/>`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := DecodeSnapshot(strings.NewReader(test.data), test.format)
+			if err == nil {
+				t.Errorf("DecodeSnapshot of %q did not fail", test.data)
+			}
+		})
+	}
+}
+
+func TestSnapshotFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"schema.json", "json"},
+		{"schema.YAML", "yaml"},
+		{"dir.v1/schema.yml", "yaml"},
+		{"schema.sql", ""},
+	}
+
+	for _, test := range tests {
+		got, err := SnapshotFormat(test.name)
+		if got != test.want || (err != nil) != (test.want == "") {
+			t.Errorf("SnapshotFormat(%q) got %q, %v, want %q",
+				test.name, got, err, test.want,
+			)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package oracleManager
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// the kinds of Source
+const (
+	SourceTable            = "TABLE"
+	SourceView             = "VIEW"
+	SourceMaterializedView = "MATERIALIZED VIEW"
+	SourceSynonym          = "SYNONYM"
+)
+
+// maxSynonymChain is the maximum number of synonyms followed when resolving a
+// synonym, so that synonym loops do not go on forever.
+const maxSynonymChain = 16
+
+// struct Source represents an object that can be read as a table and
+// converted to a collection: a table, a view, a materialized view or a
+// synonym. Synonyms are resolved to the object they refer to, in BaseOwner
+// and BaseName, and views have the text of their query in Text (as in
+// USER_VIEWS.TEXT_VC, so it may be truncated for long views).
+type Source struct {
+	Name string
+	Kind string
+
+	BaseOwner string
+	BaseName  string
+
+	Text string
+}
+
+// GetSources gets all tables, views, materialized views and synonyms of a
+// schema owner, in this order.
+func GetSources(db *sql.DB, owner string) ([]Source, error) {
+	d := dictionary{owner}
+
+	// the tables that hold the data of materialized views are also in
+	// USER_TABLES, so they are not read as tables
+	queries := []struct {
+		kind  string
+		query string
+	}{
+		{SourceTable, fmt.Sprintf(`
+    SELECT TABLE_NAME, NULL, NULL, NULL FROM %s T
+    WHERE NOT EXISTS (
+      SELECT 1 FROM %s M WHERE M.MVIEW_NAME = T.TABLE_NAME %s
+    ) %s
+    ORDER BY TABLE_NAME
+    `, d.view("TABLES"), d.view("MVIEWS"),
+			d.join("M.OWNER", "T.OWNER"), d.and("T.OWNER"),
+		)},
+		{SourceView, fmt.Sprintf(`
+    SELECT VIEW_NAME, NULL, NULL, TEXT_VC FROM %s %s ORDER BY VIEW_NAME
+    `, d.view("VIEWS"), d.where("OWNER"))},
+		{SourceMaterializedView, fmt.Sprintf(`
+    SELECT MVIEW_NAME, NULL, NULL, NULL FROM %s %s ORDER BY MVIEW_NAME
+    `, d.view("MVIEWS"), d.where("OWNER"))},
+		{SourceSynonym, fmt.Sprintf(`
+    SELECT SYNONYM_NAME, TABLE_OWNER, TABLE_NAME, NULL FROM %s
+    WHERE DB_LINK IS NULL %s
+    ORDER BY SYNONYM_NAME
+    `, d.view("SYNONYMS"), d.and("OWNER"))},
+	}
+
+	result := []Source{}
+
+	for _, q := range queries {
+		rows, err := db.Query(q.query, d.args()...)
+		if err != nil {
+			return nil, err
+		}
+
+		for rows.Next() {
+			var name string
+			var baseOwner, baseName, text sql.NullString
+
+			err = rows.Scan(&name, &baseOwner, &baseName, &text)
+			if err != nil {
+				return nil, err
+			}
+
+			result = append(result, Source{
+				Name:      name,
+				Kind:      q.kind,
+				BaseOwner: baseOwner.String,
+				BaseName:  baseName.String,
+				Text:      text.String,
+			})
+		}
+
+		err = rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// synonyms may refer to other synonyms, so follow them until the object
+	var err error
+	for i, src := range result {
+		if src.Kind != SourceSynonym {
+			continue
+		}
+
+		result[i].BaseOwner, result[i].BaseName, err =
+			resolveSynonym(db, src.BaseOwner, src.BaseName)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// resolveSynonym follows a chain of synonyms starting in an object until an
+// object that is not a synonym, returning its owner and name.
+func resolveSynonym(db *sql.DB, owner, name string) (string, string, error) {
+	for i := 0; i < maxSynonymChain; i++ {
+		var nextOwner, nextName string
+
+		err := db.QueryRow(`
+    SELECT TABLE_OWNER, TABLE_NAME FROM ALL_SYNONYMS
+    WHERE OWNER = :owner AND SYNONYM_NAME = :name AND DB_LINK IS NULL
+    `, owner, name).Scan(&nextOwner, &nextName)
+
+		if err == sql.ErrNoRows {
+			return owner, name, nil
+		}
+		if err != nil {
+			return "", "", err
+		}
+
+		owner, name = nextOwner, nextName
+	}
+
+	return "", "", fmt.Errorf("synonym %s.%s is part of a loop", owner, name)
+}
@@ -12,7 +12,7 @@ import (
 
 // GetGroup gets the document paired with the $group operator for a mongoDB
 // aggregation for a Statement.
-func (stmt *Statement) GetGroup() (bson.D, error) {
+func (stmt *Statement) GetGroup(catalog oracleManager.Catalog) (bson.D, error) {
 	hasGroup := false
 	for _, col := range stmt.SelectColumn {
 		if col.GroupFunction != "" {
@@ -32,11 +32,11 @@ func (stmt *Statement) GetGroup() (bson.D, error) {
 
 		// if the column is in the joined table, use table.column, because the
 		// lookup + unwind will make the attribute referenced as that.
-		if oracleManager.TableContainsColumn(stmt.JoinTable, col.Name) {
+		if oracleManager.TableContainsColumn(catalog, stmt.JoinTable, col.Name) {
 			v = "$" + stmt.JoinTable + "." +
-				keyManager.ToMongoId(stmt.JoinTable, col.Name)
+				keyManager.ToMongoId(catalog, stmt.JoinTable, col.Name)
 		} else {
-			v = "$" + keyManager.ToMongoId(stmt.FromTable, col.Name)
+			v = "$" + keyManager.ToMongoId(catalog, stmt.FromTable, col.Name)
 		}
 
 		switch strings.ToUpper(col.GroupFunction) {
@@ -95,7 +95,9 @@ func (stmt *Statement) GetGroup() (bson.D, error) {
 
 // GetLookup gets the document paired with the $lookup operator for a mongoDB
 // aggregation for a Statement.
-func (stmt *Statement) GetLookup() (bson.D, error) {
+func (stmt *Statement) GetLookup(
+	catalog oracleManager.Catalog,
+) (bson.D, error) {
 	if stmt.JoinTable == "" {
 		return bson.D{}, nil
 	}
@@ -104,26 +106,41 @@ func (stmt *Statement) GetLookup() (bson.D, error) {
 		{Key: "from", Value: stmt.JoinTable},
 		{
 			Key:   "localField",
-			Value: keyManager.ToMongoId(stmt.FromTable, stmt.JoinFromAttr),
+			Value: keyManager.ToMongoId(catalog, stmt.FromTable, stmt.JoinFromAttr),
 		},
 		{
 			Key:   "foreignField",
-			Value: keyManager.ToMongoId(stmt.JoinTable, stmt.JoinToAttr),
+			Value: keyManager.ToMongoId(catalog, stmt.JoinTable, stmt.JoinToAttr),
 		},
 		{Key: "as", Value: stmt.JoinTable},
 	}, nil
 }
 
 // ToMongoAggregate gets the Pipeline representing an aggregation for a
-// Statement.
-func (stmt *Statement) ToMongoAggregate() (mongo.Pipeline, error) {
+// Statement, already passed through OptimizePipeline.
+func (stmt *Statement) ToMongoAggregate(
+	catalog oracleManager.Catalog,
+) (mongo.Pipeline, error) {
 	if !stmt.IsAggregate() {
 		return mongo.Pipeline{}, fmt.Errorf("invalid statement for aggregation")
 	}
 
+	result, err := stmt.pipeline(catalog)
+	if err != nil {
+		return mongo.Pipeline{}, err
+	}
+
+	return OptimizePipeline(result), nil
+}
+
+// pipeline gets the Pipeline for a Statement without checking if it needs an
+// aggregation, so that finds can be used as sources for other pipelines.
+func (stmt *Statement) pipeline(
+	catalog oracleManager.Catalog,
+) (mongo.Pipeline, error) {
 	result := mongo.Pipeline{}
 
-	join, err := stmt.GetLookup()
+	join, err := stmt.GetLookup(catalog)
 	if err != nil {
 		return mongo.Pipeline{}, err
 	}
@@ -134,14 +151,14 @@ func (stmt *Statement) ToMongoAggregate() (mongo.Pipeline, error) {
 		)
 	}
 
-	where, err := stmt.Where.GetBson(stmt.FromTable, stmt.JoinTable)
+	where, err := stmt.Where.GetBson(catalog, stmt.FromTable, stmt.JoinTable)
 	if err != nil {
 		return mongo.Pipeline{}, err
 	}
 
 	result = append(result, bson.D{{Key: "$match", Value: where}})
 
-	group, err := stmt.GetGroup()
+	group, err := stmt.GetGroup(catalog)
 	if err != nil {
 		return mongo.Pipeline{}, err
 	}
@@ -150,7 +167,7 @@ func (stmt *Statement) ToMongoAggregate() (mongo.Pipeline, error) {
 		result = append(result, bson.D{{Key: "$group", Value: group}})
 	}
 
-	selection, err := stmt.GetSelect()
+	selection, err := stmt.GetSelect(catalog)
 	if err != nil {
 		return mongo.Pipeline{}, err
 	}
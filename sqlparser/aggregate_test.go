@@ -0,0 +1,152 @@
+package sqlparser
+
+import (
+	"testing"
+)
+
+func TestToMongoFind(t *testing.T) {
+	tests := []struct {
+		sql        string
+		filter     string
+		projection string
+	}{
+		{
+			sql: "SELECT NAME, SALARY FROM EMP " +
+				"WHERE SALARY > '1000' AND NAME = 'A';",
+			filter: `{"$and":[{"SALARY":{"$gt":1000}},` +
+				`{"NAME":{"$eq":"A"}}]}`,
+			projection: `{"NAME":1,"SALARY":1,"_id":0}`,
+		},
+		{
+			sql: "SELECT * FROM EMP WHERE ID IN (1, 2) OR NAME IS NULL;",
+			filter: `{"$or":[{"_id.ID":{"$in":[1,2]}},` +
+				`{"NAME":{"$eq":null}}]}`,
+			projection: `{}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.sql, func(t *testing.T) {
+			stmt := analyzed(t, test.sql)
+			if stmt.IsAggregate() {
+				t.Fatalf("statement is an aggregation")
+			}
+
+			filter, projection, err := stmt.ToMongoFind(testCatalog(t))
+			if err != nil {
+				t.Fatalf("ToMongoFind failed: %v", err)
+			}
+
+			if got := docJSON(t, filter); got != test.filter {
+				t.Errorf("filter got\n%s\nwant\n%s", got, test.filter)
+			}
+			if got := docJSON(t, projection); got != test.projection {
+				t.Errorf("projection got\n%s\nwant\n%s", got, test.projection)
+			}
+		})
+	}
+}
+
+func TestToMongoAggregate(t *testing.T) {
+	tests := []struct {
+		sql      string
+		pipeline string
+		warnings int
+	}{
+		{
+			sql: "SELECT NAME, AVG(SALARY) FROM EMP JOIN DEPT " +
+				"ON ID = DEPT_ID WHERE CODE = 'X';",
+			pipeline: `[{"$project":{"DEPT_ID":1,"SALARY":1,"_id":0}},` +
+				`{"$lookup":{"from":"DEPT","localField":"DEPT_ID",` +
+				`"foreignField":"_id.ID","as":"DEPT"}},` +
+				`{"$unwind":"$DEPT"},` +
+				`{"$match":{"DEPT.CODE":{"$eq":"X"}}},` +
+				`{"$group":{"_id":null,"AVG(SALARY)":{"$avg":"$SALARY"}}},` +
+				`{"$project":{"DEPT.NAME":1,"AVG(SALARY)":1,"_id":0}}]`,
+			warnings: 1,
+		},
+		{
+			sql: "SELECT CODE FROM EMP JOIN DEPT ON ID = DEPT_ID " +
+				"WHERE SALARY > 10 AND CODE = 'X';",
+			pipeline: `[{"$match":{"SALARY":{"$gt":10}}},` +
+				`{"$project":{"DEPT_ID":1,"_id":0}},` +
+				`{"$lookup":{"from":"DEPT","localField":"DEPT_ID",` +
+				`"foreignField":"_id.ID","as":"DEPT"}},` +
+				`{"$unwind":"$DEPT"},` +
+				`{"$match":{"DEPT.CODE":{"$eq":"X"}}},` +
+				`{"$project":{"DEPT.CODE":1,"_id":0}}]`,
+		},
+		{
+			sql: "SELECT NAME FROM EMP JOIN DEPT ON ID = DEPT_ID;",
+			pipeline: `[{"$project":{"DEPT_ID":1,"_id":0}},` +
+				`{"$lookup":{"from":"DEPT","localField":"DEPT_ID",` +
+				`"foreignField":"_id.ID","as":"DEPT"}},` +
+				`{"$unwind":"$DEPT"},` +
+				`{"$project":{"DEPT.NAME":1,"_id":0}}]`,
+			warnings: 1,
+		},
+		{
+			sql: "SELECT COUNT(ID) FROM EMP WHERE SALARY < '10.5';",
+			pipeline: `[{"$match":{"SALARY":{"$lt":10.5}}},` +
+				`{"$group":{"_id":null,"COUNT(ID)":{"$sum":` +
+				`{"$cond":[{"$ne":["$_id.ID",null]},1,0]}}}},` +
+				`{"$project":{"COUNT(ID)":1,"_id":0}}]`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.sql, func(t *testing.T) {
+			stmt := analyzed(t, test.sql)
+
+			p, err := stmt.ToMongoAggregate(testCatalog(t))
+			if err != nil {
+				t.Fatalf("ToMongoAggregate failed: %v", err)
+			}
+
+			if got := pipelineJSON(t, p); got != test.pipeline {
+				t.Errorf("pipeline got\n%s\nwant\n%s", got, test.pipeline)
+			}
+			if len(stmt.Warnings) != test.warnings {
+				t.Errorf("warnings got %q", stmt.Warnings)
+			}
+		})
+	}
+}
+
+func TestAnalyzeErrors(t *testing.T) {
+	tests := []string{
+		"SELECT X FROM EMP;",
+		"SELECT * FROM NOPE;",
+		"SELECT * FROM EMP WHERE SALARY = 'abc';",
+		"SELECT * FROM EMP JOIN DEPT ON ID = NOPE;",
+	}
+
+	for _, sql := range tests {
+		t.Run(sql, func(t *testing.T) {
+			stmt, err := Parse(sql)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+
+			if stmt.Analyze(testCatalog(t)) == nil {
+				t.Errorf("Analyze did not fail")
+			}
+		})
+	}
+}
+
+func TestToMongoAggregateErrors(t *testing.T) {
+	tests := []string{
+		"SELECT CODE, COUNT(ID) FROM DEPT;",
+		"SELECT NAME FROM EMP;",
+	}
+
+	for _, sql := range tests {
+		t.Run(sql, func(t *testing.T) {
+			_, err := analyzed(t, sql).ToMongoAggregate(testCatalog(t))
+			if err == nil {
+				t.Errorf("ToMongoAggregate did not fail")
+			}
+		})
+	}
+}
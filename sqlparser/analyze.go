@@ -0,0 +1,276 @@
+package sqlparser
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lucasgpulcinelli/mongoQLer/oracleManager"
+)
+
+// The semantic analysis resolves every identifier of a parsed statement
+// against the tables of a catalog, and converts literals
+// to the type of the column they are compared or assigned to, as oracle would
+// do implicitly (so "CHAR_COL = 1" is the same as "CHAR_COL = '1'"). It should
+// be done after parsing and before converting the statement to mongoDB.
+
+// charTypes are the oracle data types whose values are strings.
+var charTypes = map[string]bool{
+	"CHAR": true, "NCHAR": true, "VARCHAR": true, "VARCHAR2": true,
+	"NVARCHAR2": true, "CLOB": true, "NCLOB": true, "LONG": true,
+}
+
+// numberTypes are the oracle data types whose values are numbers.
+var numberTypes = map[string]bool{
+	"NUMBER": true, "FLOAT": true, "BINARY_FLOAT": true, "BINARY_DOUBLE": true,
+	"INTEGER": true, "INT": true, "SMALLINT": true, "DECIMAL": true,
+}
+
+// numericGroupFunctions are the group functions that only accept numbers.
+var numericGroupFunctions = map[string]bool{
+	"SUM": true, "AVG": true, "STDDEV": true,
+}
+
+// struct analyzer holds the errors and warnings found during the semantic
+// analysis.
+type analyzer struct {
+	catalog  oracleManager.Catalog
+	errs     []error
+	warnings []string
+}
+
+// errorf records an error found during the analysis.
+func (a *analyzer) errorf(format string, args ...any) {
+	a.errs = append(a.errs, fmt.Errorf(format, args...))
+}
+
+// err gets all errors found during the analysis as a single one, or nil.
+func (a *analyzer) err() error {
+	return errors.Join(a.errs...)
+}
+
+// table checks if a table is known.
+func (a *analyzer) table(table string) {
+	if !oracleManager.TableExists(a.catalog, table) {
+		a.errorf("unknown table %s", table)
+	}
+}
+
+// resolve gets the table (among the ones provided) that contains a column,
+// recording an error and returning an empty string if there is no such table.
+// As the grammar has no qualified columns, a column in more than one table is
+// taken from the last one (the joined table, as in the conversions), with a
+// warning.
+func (a *analyzer) resolve(column string, tables ...string) string {
+	found := []string{}
+
+	for _, table := range tables {
+		if table != "" &&
+			oracleManager.TableContainsColumn(a.catalog, table, column) {
+			found = append(found, table)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		a.errorf("unknown column %s", column)
+		return ""
+	case 1:
+		return found[0]
+	}
+
+	table := found[len(found)-1]
+	a.warnings = append(a.warnings, fmt.Sprintf(
+		"column %s is in tables %s, using the one in %s",
+		column, strings.Join(found, " and "), table,
+	))
+	return table
+}
+
+// coerce converts a literal to the type of a column, recording an error if the
+// conversion is not possible. Literals for columns of other types (such as
+// dates) are kept as is.
+func (a *analyzer) coerce(table, column string, v any) any {
+	info, _ := oracleManager.GetColumn(a.catalog, table, column)
+	dataType := info.DataType
+
+	switch vv := v.(type) {
+	case nil:
+		return v
+
+	case int64:
+		if charTypes[dataType] {
+			return strconv.FormatInt(vv, 10)
+		}
+
+	case float64:
+		if charTypes[dataType] {
+			return strconv.FormatFloat(vv, 'f', -1, 64)
+		}
+
+	case string:
+		if !numberTypes[dataType] {
+			return v
+		}
+
+		if i, err := strconv.ParseInt(strings.TrimSpace(vv), 10, 64); err == nil {
+			return i
+		}
+		if f, err := strconv.ParseFloat(strings.TrimSpace(vv), 64); err == nil {
+			return f
+		}
+
+		a.errorf("column %s.%s is a %s, but %q is not a number",
+			table, column, dataType, vv,
+		)
+	}
+
+	return v
+}
+
+// boolExpr analyzes a boolean expression whose columns are in the tables
+// provided, coercing its literals.
+func (a *analyzer) boolExpr(be BooleanExpression, tables ...string) {
+	switch e := be.(type) {
+	case *Comparision:
+		table := a.resolve(e.Id, tables...)
+		if table != "" {
+			e.Value = a.coerce(table, e.Id, e.Value)
+		}
+
+	case *InComparision:
+		table := a.resolve(e.Id, tables...)
+		if table != "" {
+			for i := range e.Values {
+				e.Values[i] = a.coerce(table, e.Id, e.Values[i])
+			}
+		}
+
+	case *BooleanComposite:
+		for _, se := range e.SubExpr {
+			a.boolExpr(se, tables...)
+		}
+	}
+}
+
+// statement analyzes a Statement.
+func (a *analyzer) statement(stmt *Statement) {
+	a.table(stmt.FromTable)
+
+	if stmt.JoinTable != "" {
+		a.table(stmt.JoinTable)
+
+		if !oracleManager.TableContainsColumn(
+			a.catalog, stmt.FromTable, stmt.JoinFromAttr,
+		) {
+			a.errorf("unknown column %s in table %s",
+				stmt.JoinFromAttr, stmt.FromTable,
+			)
+		}
+
+		if !oracleManager.TableContainsColumn(
+			a.catalog, stmt.JoinTable, stmt.JoinToAttr,
+		) {
+			a.errorf("unknown column %s in table %s",
+				stmt.JoinToAttr, stmt.JoinTable,
+			)
+		}
+	}
+
+	for _, col := range stmt.SelectColumn {
+		if col.Name == "*" {
+			continue
+		}
+
+		table := a.resolve(col.Name, stmt.FromTable, stmt.JoinTable)
+		if table == "" || !numericGroupFunctions[col.GroupFunction] {
+			continue
+		}
+
+		info, _ := oracleManager.GetColumn(a.catalog, table, col.Name)
+		if info.DataType != "" && !numberTypes[info.DataType] {
+			a.errorf("%s requires a number, but %s.%s is a %s",
+				col.GroupFunction, table, col.Name, info.DataType,
+			)
+		}
+	}
+
+	if stmt.Where != nil {
+		a.boolExpr(stmt.Where, stmt.FromTable, stmt.JoinTable)
+	}
+}
+
+// Analyze resolves all identifiers of the Statement in a catalog, returning an
+// error with every unknown column found, and converts the literals in the
+// WHERE clause to the types of the columns they are compared to. Columns in
+// both tables of a join are reported in Warnings.
+func (stmt *Statement) Analyze(catalog oracleManager.Catalog) error {
+	a := &analyzer{catalog: catalog}
+	a.statement(stmt)
+	stmt.Warnings = a.warnings
+	return a.err()
+}
+
+// AnalyzeBoolExpr analyzes a boolean expression over the columns of a single
+// table (such as a CHECK constraint), like Statement.Analyze.
+func AnalyzeBoolExpr(
+	catalog oracleManager.Catalog, be BooleanExpression, table string,
+) error {
+	a := &analyzer{catalog: catalog}
+	a.table(table)
+	a.boolExpr(be, table)
+	return a.err()
+}
+
+// Analyze resolves the target columns and the source query of the
+// InsertStatement, like Statement.Analyze.
+func (ins *InsertStatement) Analyze(catalog oracleManager.Catalog) error {
+	a := &analyzer{catalog: catalog}
+	a.table(ins.IntoTable)
+
+	for _, col := range ins.IntoColumns {
+		a.resolve(col, ins.IntoTable)
+	}
+
+	a.statement(ins.Select)
+	ins.Warnings = a.warnings
+	return a.err()
+}
+
+// assignments analyzes the assignments of a MergeStatement, converting the
+// literals to the types of the target columns.
+func (a *analyzer) assignments(
+	merge *MergeStatement, assignments []Assignment, sources []string,
+) {
+	for i, as := range assignments {
+		table := a.resolve(as.Column, merge.IntoTable)
+
+		if as.SourceColumn != "" {
+			a.resolve(as.SourceColumn, sources...)
+		} else if table != "" {
+			assignments[i].Value = a.coerce(table, as.Column, as.Value)
+		}
+	}
+}
+
+// Analyze resolves the target and source columns of the MergeStatement, like
+// Statement.Analyze.
+func (merge *MergeStatement) Analyze(catalog oracleManager.Catalog) error {
+	a := &analyzer{catalog: catalog}
+	a.table(merge.IntoTable)
+
+	sources := []string{merge.UsingTable}
+	if merge.UsingSelect != nil {
+		a.statement(merge.UsingSelect)
+		sources = []string{merge.UsingSelect.FromTable, merge.UsingSelect.JoinTable}
+	} else {
+		a.table(merge.UsingTable)
+	}
+
+	a.assignments(merge, merge.On, sources)
+	a.assignments(merge, merge.MatchedUpdate, sources)
+	a.assignments(merge, merge.NotMatchedInsert, sources)
+	merge.Warnings = a.warnings
+	return a.err()
+}
@@ -0,0 +1,1031 @@
+package sqlparser
+
+import (
+	"fmt"
+	"strings"
+	"text/scanner"
+
+	"github.com/lucasgpulcinelli/mongoQLer/oracleManager"
+)
+
+// The DDL parser follows the same recursive descent structure as the query
+// parser, with the rules described in this file in the same form.
+
+// struct ColumnDefinition represents a column in a CREATE TABLE statement,
+// with its data type as written in the statement (such as "VARCHAR2(20)"),
+// its nullability and its default value text (empty if there is none).
+type ColumnDefinition struct {
+	Name     string
+	DataType string
+	NotNull  bool
+	Default  string
+}
+
+// struct Schema represents all the metadata described in a DDL file, in the
+// same form that the oracleManager package reads it from a connection.
+type Schema struct {
+	Tables      []string
+	Columns     map[string][]ColumnDefinition
+	PrimaryKeys map[string][]string
+	References  []oracleManager.Reference
+	Uniques     []oracleManager.UniqueEntry
+	Indexes     []oracleManager.IndexEntry
+	Checks      []oracleManager.CheckEntry
+}
+
+// dataTypeAliases are the names of data types that oracle stores as other
+// types.
+var dataTypeAliases = map[string]string{
+	"VARCHAR": "VARCHAR2", "DECIMAL": "NUMBER", "NUMERIC": "NUMBER",
+	"DOUBLE PRECISION": "FLOAT", "REAL": "FLOAT",
+}
+
+// integerTypes are the names of data types that oracle stores as a NUMBER with
+// scale 0.
+var integerTypes = map[string]bool{
+	"INTEGER": true, "INT": true, "SMALLINT": true,
+}
+
+// ColumnInfo gets the metadata of a column in the same form as
+// oracleManager.GetTableColumns, given if it is part of the primary key. As
+// the database character set is not known, character lengths are considered
+// to be the same as byte lengths.
+func (col ColumnDefinition) ColumnInfo(isPk bool) oracleManager.ColumnInfo {
+	info := oracleManager.ColumnInfo{
+		Name:     col.Name,
+		Nullable: !col.NotNull && !isPk,
+		Default:  col.Default,
+	}
+
+	// split the type in its name, arguments and what comes after them, such as
+	// in "TIMESTAMP(3) WITH TIME ZONE"
+	dataType := strings.Join(strings.Fields(strings.ToUpper(col.DataType)), " ")
+	name, rest, _ := strings.Cut(dataType, "(")
+	args, suffix, _ := strings.Cut(rest, ")")
+	name = strings.TrimSpace(name)
+	suffix = strings.TrimSpace(suffix)
+
+	nums := []*int64{}
+	for _, arg := range strings.Split(args, ",") {
+		var n int64
+		if _, err := fmt.Sscan(arg, &n); err == nil {
+			nums = append(nums, &n)
+		} else {
+			nums = append(nums, nil)
+		}
+	}
+
+	arg := func(i int) *int64 {
+		if i < len(nums) {
+			return nums[i]
+		}
+
+		return nil
+	}
+
+	zero := int64(0)
+	if integerTypes[name] {
+		name = "NUMBER"
+		nums = []*int64{nil, &zero}
+	} else if alias, ok := dataTypeAliases[name]; ok {
+		name = alias
+	}
+
+	info.DataType = name
+
+	switch {
+	case name == "NUMBER":
+		info.DataLength = 22
+		info.Precision = arg(0)
+		info.Scale = arg(1)
+		if info.Precision != nil && info.Scale == nil {
+			info.Scale = &zero
+		}
+
+	case name == "FLOAT":
+		info.DataLength = 22
+		info.Precision = arg(0)
+		if info.Precision == nil {
+			p := int64(126)
+			info.Precision = &p
+		}
+
+	case name == "CHAR" || name == "NCHAR" || name == "VARCHAR2" ||
+		name == "NVARCHAR2" || name == "RAW":
+		info.DataLength = 1
+		if n := arg(0); n != nil {
+			info.DataLength = *n
+		}
+
+		if name != "RAW" {
+			info.CharLength = info.DataLength
+		}
+		if name == "NCHAR" || name == "NVARCHAR2" {
+			info.DataLength *= 2
+		}
+
+	case name == "DATE":
+		info.DataLength = 7
+
+	case name == "TIMESTAMP":
+		p := int64(6)
+		if n := arg(0); n != nil {
+			p = *n
+		}
+
+		info.Scale = &p
+		info.DataType = fmt.Sprintf("TIMESTAMP(%d)", p)
+		info.DataLength = 11
+		if suffix != "" {
+			info.DataType += " " + suffix
+			info.DataLength = 13
+		}
+
+	case strings.HasPrefix(name, "INTERVAL") || strings.HasPrefix(name, "LONG"):
+		// oracle keeps the whole text for these types
+		info.DataType = dataType
+
+	case name == "BINARY_FLOAT":
+		info.DataLength = 4
+
+	case name == "BINARY_DOUBLE":
+		info.DataLength = 8
+	}
+
+	return info
+}
+
+// Catalog gets the in memory Catalog with all the metadata in the schema.
+func (schema *Schema) Catalog() *oracleManager.MemoryCatalog {
+	columns := map[string][]oracleManager.ColumnInfo{}
+
+	for table, cols := range schema.Columns {
+		for _, col := range cols {
+			isPk := false
+			for _, pk := range schema.PrimaryKeys[table] {
+				isPk = isPk || pk == col.Name
+			}
+
+			columns[table] = append(columns[table], col.ColumnInfo(isPk))
+		}
+	}
+
+	return &oracleManager.MemoryCatalog{
+		Tables:      schema.Tables,
+		Columns:     columns,
+		PrimaryKeys: schema.PrimaryKeys,
+		References:  schema.References,
+		Uniques:     schema.Uniques,
+		Indexes:     schema.Indexes,
+		Checks:      schema.Checks,
+	}
+}
+
+// ddlKeywords are the keywords that end a data type or default value in a
+// column definition, or the index properties of a constraint.
+var ddlKeywords = map[string]bool{
+	"CONSTRAINT": true, "NOT": true, "NULL": true, "DEFAULT": true,
+	"PRIMARY": true, "UNIQUE": true, "CHECK": true, "REFERENCES": true,
+	"ENABLE": true, "DISABLE": true, "VALIDATE": true, "NOVALIDATE": true,
+	"RELY": true, "NORELY": true, "DEFERRABLE": true, "INITIALLY": true,
+	"USING": true,
+}
+
+// stripComments replaces all SQL comments ("-- ..." and "/* ... */") outside
+// of strings with spaces, keeping all offsets in the text the same.
+func stripComments(sql string) string {
+	bs := []byte(sql)
+	inString := false
+
+	for i := 0; i < len(bs); i++ {
+		switch {
+		case bs[i] == '\'':
+			inString = !inString
+		case inString:
+		case bs[i] == '-' && i+1 < len(bs) && bs[i+1] == '-':
+			for ; i < len(bs) && bs[i] != '\n'; i++ {
+				bs[i] = ' '
+			}
+		case bs[i] == '/' && i+1 < len(bs) && bs[i+1] == '*':
+			for ; i < len(bs) && !(bs[i] == '*' && i+1 < len(bs) &&
+				bs[i+1] == '/'); i++ {
+				if bs[i] != '\n' {
+					bs[i] = ' '
+				}
+			}
+
+			if i+1 < len(bs) {
+				bs[i], bs[i+1] = ' ', ' '
+				i++
+			}
+		}
+	}
+
+	return string(bs)
+}
+
+// ParseDDL parses a series of DDL statements, such as an oracle schema file,
+// returning the Schema they describe. Only CREATE TABLE, ALTER TABLE ... ADD
+// and CREATE INDEX statements are considered, all others are ignored.
+//
+// ParseDDL -> { CreateTable | AlterTable | CreateIndex | OtherStmt }
+// OtherStmt -> { <ANY> } <;>
+func ParseDDL(sql string) (*Schema, error) {
+	sql = stripComments(sql)
+	l := NewLexer(strings.NewReader(sql))
+
+	schema := &Schema{
+		Columns:     map[string][]ColumnDefinition{},
+		PrimaryKeys: map[string][]string{},
+	}
+
+	l.Lex()
+	for l.Token != scanner.EOF {
+		start := l.Offset
+		kw := strings.ToUpper(l.Value)
+
+		if kw != "CREATE" && kw != "ALTER" {
+			skipStatement(l)
+			continue
+		}
+
+		if !l.Lex() {
+			break
+		}
+
+		object := strings.ToUpper(l.Value)
+
+		var ok bool
+		switch {
+		case kw == "CREATE" && object == "TABLE":
+			ok = CreateTable(l, sql, schema)
+		case kw == "ALTER" && object == "TABLE":
+			ok = AlterTable(l, sql, schema)
+		case kw == "CREATE" && (object == "INDEX" || object == "UNIQUE"):
+			object = "INDEX"
+			ok = CreateIndex(l, sql, schema)
+		default:
+			skipStatement(l)
+			continue
+		}
+
+		if !ok {
+			return nil, fmt.Errorf(
+				"failed parsing SQL %s %s at line %d", kw, object,
+				strings.Count(sql[:start], "\n")+1,
+			)
+		}
+	}
+
+	err := schema.resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	return schema, nil
+}
+
+// skipStatement advances the lexer until after the next <;>.
+func skipStatement(l *Lexer) {
+	for l.Token != scanner.EOF && l.Value != ";" {
+		l.Lex()
+	}
+
+	l.Lex()
+}
+
+// resolve completes the schema after all statements are parsed: references
+// without columns are pointed to the primary key of the referenced table.
+func (schema *Schema) resolve() error {
+	for i, ref := range schema.References {
+		if len(ref.ColumnReferenced) != 0 {
+			continue
+		}
+
+		pks, ok := schema.PrimaryKeys[ref.TableReferenced]
+		if !ok {
+			return fmt.Errorf(
+				"reference %s to table %s without primary key",
+				ref.ConstraintName, ref.TableReferenced,
+			)
+		}
+
+		schema.References[i].ColumnReferenced = pks
+	}
+
+	// columns that reference another without a data type take its data type
+	for _, ref := range schema.References {
+		for i, name := range ref.ColumnReferencer {
+			col := schema.column(ref.TableReferencer, name)
+			if col == nil || col.DataType != "" ||
+				i >= len(ref.ColumnReferenced) {
+				continue
+			}
+
+			referenced := schema.column(
+				ref.TableReferenced, ref.ColumnReferenced[i],
+			)
+			if referenced != nil {
+				col.DataType = referenced.DataType
+			}
+		}
+	}
+
+	for _, table := range schema.Tables {
+		for _, col := range schema.Columns[table] {
+			if col.DataType == "" {
+				return fmt.Errorf(
+					"column %s of table %s has no data type", col.Name, table,
+				)
+			}
+		}
+	}
+
+	for _, ref := range schema.References {
+		if len(ref.ColumnReferenced) != len(ref.ColumnReferencer) {
+			return fmt.Errorf(
+				"reference %s has a different number of columns than %s",
+				ref.ConstraintName, ref.TableReferenced,
+			)
+		}
+	}
+
+	return nil
+}
+
+// column gets a column of a table, or nil if there is no such column.
+func (schema *Schema) column(table, name string) *ColumnDefinition {
+	for i, col := range schema.Columns[table] {
+		if col.Name == name {
+			return &schema.Columns[table][i]
+		}
+	}
+
+	return nil
+}
+
+// rawText reads tokens until the end of a column definition part (a <,>, <)>,
+// <;> or keyword from ddlKeywords outside of parenthesis), returning the text
+// read.
+func rawText(l *Lexer, sql string) string {
+	start := l.Offset
+	depth := 0
+
+	for l.Token != scanner.EOF {
+		if depth == 0 && (l.Value == "," || l.Value == ")" || l.Value == ";" ||
+			ddlKeywords[strings.ToUpper(l.Value)]) {
+			break
+		}
+
+		if l.Value == "(" {
+			depth++
+		} else if l.Value == ")" {
+			depth--
+		}
+
+		l.Lex()
+	}
+
+	end := l.Offset
+	if l.Token == scanner.EOF {
+		end = len(sql)
+	}
+
+	return strings.TrimSpace(sql[start:end])
+}
+
+// CondText -> <(> { <ANY> } <)>
+//
+// CondText reads a parenthesised condition, such as the one in a CHECK,
+// returning its text without the outer parenthesis.
+func CondText(l *Lexer, sql string, cond *string) bool {
+	if l.Value != "(" || !l.Lex() {
+		return false
+	}
+
+	start := l.Offset
+	depth := 1
+
+	for {
+		if l.Value == "(" {
+			depth++
+		} else if l.Value == ")" {
+			depth--
+		}
+
+		if depth == 0 {
+			break
+		}
+
+		if !l.Lex() {
+			return false
+		}
+	}
+
+	*cond = strings.TrimSpace(sql[start:l.Offset])
+
+	// the condition can be the last thing in the statement
+	l.Lex()
+	return true
+}
+
+// upperIdList reads an IdList in parenthesis, converting all identifiers to
+// upper case as oracle does.
+func upperIdList(l *Lexer, ids *[]string) bool {
+	if l.Value != "(" || !l.Lex() || !IdList(l, ids) || l.Value != ")" {
+		return false
+	}
+
+	for i := range *ids {
+		(*ids)[i] = strings.ToUpper((*ids)[i])
+	}
+
+	l.Lex()
+	return true
+}
+
+// CreateTable -> <CREATE> <TABLE> QualifiedId <(> TableElement
+//
+//	{ <,> TableElement } <)> { <ANY> } <;>
+//
+// The lexer should be at the <TABLE> token.
+func CreateTable(l *Lexer, sql string, schema *Schema) bool {
+	if !l.Lex() {
+		return false
+	}
+
+	_, table, ok := QualifiedId(l)
+	if !ok {
+		return false
+	}
+
+	// tables created from queries have no column definitions to read
+	if strings.ToUpper(l.Value) == "AS" {
+		skipStatement(l)
+		return true
+	}
+
+	if l.Value != "(" || !l.Lex() {
+		return false
+	}
+
+	table = strings.ToUpper(table)
+	if _, ok := schema.Columns[table]; ok {
+		return false
+	}
+
+	schema.Tables = append(schema.Tables, table)
+	schema.Columns[table] = []ColumnDefinition{}
+
+	for {
+		if !TableElement(l, sql, schema, table) {
+			return false
+		}
+
+		if l.Value == ")" {
+			break
+		}
+
+		if l.Value != "," || !l.Lex() {
+			return false
+		}
+	}
+
+	// ignore storage clauses and other options after the table definition
+	skipStatement(l)
+	return true
+}
+
+// AlterTable -> <ALTER> <TABLE> QualifiedId <ADD> TableElement <;>
+//
+//	| <ALTER> <TABLE> QualifiedId <ADD> <(> TableElement
+//	  { <,> TableElement } <)> <;>
+//	| <ALTER> <TABLE> QualifiedId { <ANY> } <;>
+//
+// The lexer should be at the <TABLE> token. Any ALTER TABLE other than ADD is
+// ignored.
+func AlterTable(l *Lexer, sql string, schema *Schema) bool {
+	if !l.Lex() {
+		return false
+	}
+
+	_, table, ok := QualifiedId(l)
+	if !ok {
+		return false
+	}
+	table = strings.ToUpper(table)
+
+	if strings.ToUpper(l.Value) != "ADD" {
+		skipStatement(l)
+		return true
+	}
+
+	if !l.Lex() {
+		return false
+	}
+
+	if l.Value != "(" {
+		if !TableElement(l, sql, schema, table) {
+			return false
+		}
+
+		skipStatement(l)
+		return true
+	}
+
+	if !l.Lex() {
+		return false
+	}
+
+	for {
+		if !TableElement(l, sql, schema, table) {
+			return false
+		}
+
+		if l.Value == ")" {
+			break
+		}
+
+		if l.Value != "," || !l.Lex() {
+			return false
+		}
+	}
+
+	skipStatement(l)
+	return true
+}
+
+// CreateIndex -> <CREATE> OptUnique <INDEX> QualifiedId <ON> QualifiedId
+//
+//	<(> IndexElement { <,> IndexElement } <)> { <ANY> } <;>
+//
+// OptUnique -> <UNIQUE> | eps
+// IndexElement -> { <ANY> } OptOrder
+// OptOrder -> <ASC> | <DESC> | eps
+//
+// The lexer should be at the <UNIQUE> or <INDEX> token. Index elements that
+// are not a single column are kept as expressions, like in
+// USER_IND_EXPRESSIONS.
+func CreateIndex(l *Lexer, sql string, schema *Schema) bool {
+	index := oracleManager.IndexEntry{}
+
+	if strings.ToUpper(l.Value) == "UNIQUE" {
+		index.Unique = true
+		if !l.Lex() {
+			return false
+		}
+	}
+
+	if strings.ToUpper(l.Value) != "INDEX" || !l.Lex() {
+		return false
+	}
+
+	_, name, ok := QualifiedId(l)
+	if !ok || strings.ToUpper(l.Value) != "ON" || !l.Lex() {
+		return false
+	}
+
+	_, table, ok := QualifiedId(l)
+	if !ok || l.Value != "(" || !l.Lex() {
+		return false
+	}
+
+	index.Name = strings.ToUpper(name)
+	index.Table = strings.ToUpper(table)
+
+	for {
+		// an element made of a single identifier is a column
+		first := l.Value
+		isColumn := l.Token == scanner.Ident && !strings.Contains(first, "'")
+
+		text := rawText(l, sql)
+		if text == "" {
+			return false
+		}
+
+		col := oracleManager.IndexColumn{}
+
+		fields := strings.Fields(text)
+		switch strings.ToUpper(fields[len(fields)-1]) {
+		case "DESC":
+			col.Descending = true
+			fallthrough
+		case "ASC":
+			text = strings.TrimSpace(text[:strings.LastIndexAny(text, " \t\n")])
+		}
+
+		if isColumn && strings.Trim(text, `"`) == first {
+			col.Column = strings.ToUpper(first)
+		} else {
+			col.Expression = text
+		}
+
+		index.Columns = append(index.Columns, col)
+
+		if l.Value == ")" {
+			break
+		}
+
+		if l.Value != "," || !l.Lex() {
+			return false
+		}
+	}
+
+	schema.Indexes = append(schema.Indexes, index)
+
+	// ignore storage clauses and other options after the index definition
+	skipStatement(l)
+	return true
+}
+
+// TableElement -> ColumnDef | TableConstraint
+// ColumnDef -> <ID> DataType { ColumnConstraint }
+// DataType -> { <ANY> }
+//
+// The data type can be missing in columns that reference another, which take
+// the data type of the referenced column.
+func TableElement(l *Lexer, sql string, schema *Schema, table string) bool {
+	switch strings.ToUpper(l.Value) {
+	case "CONSTRAINT", "PRIMARY", "UNIQUE", "CHECK", "FOREIGN":
+		return TableConstraint(l, sql, schema, table)
+	}
+
+	if l.Token != scanner.Ident {
+		return false
+	}
+
+	col := ColumnDefinition{Name: strings.ToUpper(l.Value)}
+
+	if !l.Lex() {
+		return false
+	}
+
+	col.DataType = rawText(l, sql)
+	if col.DataType == "" && strings.ToUpper(l.Value) != "REFERENCES" &&
+		strings.ToUpper(l.Value) != "CONSTRAINT" {
+		return false
+	}
+
+	for l.Value != "," && l.Value != ")" && l.Value != ";" &&
+		l.Token != scanner.EOF {
+		if !ColumnConstraint(l, sql, schema, table, &col) {
+			return false
+		}
+	}
+
+	schema.Columns[table] = append(schema.Columns[table], col)
+	return true
+}
+
+// constraintName gets the name of a constraint, generating one if the
+// statement did not name it.
+func (schema *Schema) constraintName(name, table, kind string) string {
+	if name != "" {
+		return strings.ToUpper(name)
+	}
+
+	return fmt.Sprintf("SYS_%s_%s%d", table, kind,
+		len(schema.References)+len(schema.Uniques)+1,
+	)
+}
+
+// addCheck adds a check constraint to a table, naming it like oracle does if
+// the statement did not name it.
+func (schema *Schema) addCheck(name, table, cond string) {
+	if name != "" {
+		name = strings.ToUpper(name)
+	} else {
+		name = fmt.Sprintf("SYS_%s_C%d", table, len(schema.Checks)+1)
+	}
+
+	schema.Checks = append(schema.Checks, oracleManager.CheckEntry{
+		Name: name, Table: table, Check: cond,
+	})
+}
+
+// hasNotNull indicates if a column of a table already has a NOT NULL check,
+// alone or as part of the one of a primary key.
+func (schema *Schema) hasNotNull(table, col string) bool {
+	for _, check := range schema.Checks {
+		if check.Table != table {
+			continue
+		}
+
+		for _, cond := range strings.Split(check.Check, " AND ") {
+			if cond == col+" IS NOT NULL" {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// addNotNull adds the NOT NULL check of a column, unless it already has one.
+func (schema *Schema) addNotNull(name, table, col string) {
+	if !schema.hasNotNull(table, col) {
+		schema.addCheck(name, table, col+" IS NOT NULL")
+	}
+}
+
+// addPrimaryKey adds a primary key to a table, along with the NOT NULL checks
+// its columns do not have yet.
+func (schema *Schema) addPrimaryKey(
+	name, table string, columns []string,
+) bool {
+	if _, ok := schema.PrimaryKeys[table]; ok {
+		return false
+	}
+
+	schema.PrimaryKeys[table] = columns
+
+	conds := []string{}
+	for _, col := range columns {
+		if !schema.hasNotNull(table, col) {
+			conds = append(conds, col+" IS NOT NULL")
+		}
+	}
+
+	if len(conds) != 0 {
+		schema.addCheck(name, table, strings.Join(conds, " AND "))
+	}
+	return true
+}
+
+// OptConstraintName -> <CONSTRAINT> <ID> | eps
+func OptConstraintName(l *Lexer, name *string) bool {
+	if strings.ToUpper(l.Value) != "CONSTRAINT" {
+		return true
+	}
+
+	if !l.Lex() || l.Token != scanner.Ident {
+		return false
+	}
+
+	*name = l.Value
+	return l.Lex()
+}
+
+// ReferencesClause -> <REFERENCES> QualifiedId OptIdList OptOnDelete
+// OptOnDelete -> <ON> <DELETE> ( <CASCADE> | <SET> <NULL> ) | eps
+func ReferencesClause(l *Lexer, ref *oracleManager.Reference) bool {
+	if strings.ToUpper(l.Value) != "REFERENCES" || !l.Lex() {
+		return false
+	}
+
+	_, table, ok := QualifiedId(l)
+	if !ok {
+		return false
+	}
+	ref.TableReferenced = strings.ToUpper(table)
+
+	if l.Value == "(" && !upperIdList(l, &ref.ColumnReferenced) {
+		return false
+	}
+
+	if strings.ToUpper(l.Value) != "ON" {
+		return true
+	}
+
+	if !l.Lex() || strings.ToUpper(l.Value) != "DELETE" || !l.Lex() {
+		return false
+	}
+
+	if strings.ToUpper(l.Value) == "SET" && !l.Lex() {
+		return false
+	}
+
+	// CASCADE or NULL
+	l.Lex()
+	return true
+}
+
+// ConstraintState -> { <ENABLE> | <DISABLE> | <VALIDATE> | <NOVALIDATE>
+//
+//	| <RELY> | <NORELY> | <DEFERRABLE> | <INITIALLY> <ANY>
+//	| <USING> <INDEX> { <ANY> } }
+//
+// ConstraintState reads the state clauses oracle writes after a constraint,
+// which do not change its meaning in the schema. <NOT> <DEFERRABLE> is read by
+// the constraint rules, as in a column it only differs from <NOT> <NULL> in the
+// next token.
+func ConstraintState(l *Lexer, sql string) bool {
+	for {
+		switch strings.ToUpper(l.Value) {
+		default:
+			return true
+
+		case "ENABLE", "DISABLE", "VALIDATE", "NOVALIDATE", "RELY", "NORELY",
+			"DEFERRABLE":
+			if !l.Lex() {
+				return false
+			}
+
+		case "INITIALLY":
+			// IMMEDIATE or DEFERRED
+			if !l.Lex() || !l.Lex() {
+				return false
+			}
+
+		case "USING":
+			if !l.Lex() || strings.ToUpper(l.Value) != "INDEX" || !l.Lex() {
+				return false
+			}
+
+			// ignore the index name or properties
+			rawText(l, sql)
+		}
+	}
+}
+
+// ColumnConstraint -> OptConstraintName ( <NOT> <NULL> | <NULL>
+//
+//	| <DEFAULT> { <ANY> } | <PRIMARY> <KEY> | <UNIQUE> | <CHECK> CondText
+//	| ReferencesClause ) ConstraintState
+//	| <NOT> <DEFERRABLE> ConstraintState
+func ColumnConstraint(
+	l *Lexer, sql string, schema *Schema, table string, col *ColumnDefinition,
+) bool {
+	name := ""
+	if !OptConstraintName(l, &name) {
+		return false
+	}
+
+	switch strings.ToUpper(l.Value) {
+	default:
+		return false
+
+	case "NOT":
+		if !l.Lex() {
+			return false
+		}
+
+		// the state of the previous constraint
+		if strings.ToUpper(l.Value) == "DEFERRABLE" {
+			break
+		}
+
+		if strings.ToUpper(l.Value) != "NULL" {
+			return false
+		}
+
+		col.NotNull = true
+		schema.addNotNull(name, table, col.Name)
+
+	case "NULL":
+
+	case "DEFAULT":
+		if !l.Lex() {
+			return false
+		}
+
+		// NULL also ends other default values
+		if strings.ToUpper(l.Value) == "NULL" {
+			col.Default = "NULL"
+			break
+		}
+
+		col.Default = rawText(l, sql)
+		return col.Default != ""
+
+	case "PRIMARY":
+		if !l.Lex() || strings.ToUpper(l.Value) != "KEY" {
+			return false
+		}
+
+		col.NotNull = true
+		if !schema.addPrimaryKey(name, table, []string{col.Name}) {
+			return false
+		}
+
+	case "UNIQUE":
+		schema.Uniques = append(schema.Uniques, oracleManager.UniqueEntry{
+			Table: table, Columns: []string{col.Name},
+		})
+
+	case "CHECK":
+		if !l.Lex() {
+			return false
+		}
+
+		var cond string
+		if !CondText(l, sql, &cond) {
+			return false
+		}
+
+		schema.addCheck(name, table, cond)
+		return ConstraintState(l, sql)
+
+	case "REFERENCES":
+		ref := oracleManager.Reference{
+			ConstraintName:   schema.constraintName(name, table, "R"),
+			TableReferencer:  table,
+			ColumnReferencer: []string{col.Name},
+		}
+
+		if !ReferencesClause(l, &ref) {
+			return false
+		}
+
+		schema.References = append(schema.References, ref)
+		return ConstraintState(l, sql)
+	}
+
+	// the constraint ends in its last token
+	return l.Lex() && ConstraintState(l, sql)
+}
+
+// TableConstraint -> OptConstraintName ( <PRIMARY> <KEY> <(> IdList <)>
+//
+//	| <UNIQUE> <(> IdList <)> | <CHECK> CondText
+//	| <FOREIGN> <KEY> <(> IdList <)> ReferencesClause )
+//	ConstraintState { <NOT> <DEFERRABLE> ConstraintState }
+func TableConstraint(l *Lexer, sql string, schema *Schema, table string) bool {
+	name := ""
+	if !OptConstraintName(l, &name) {
+		return false
+	}
+
+	switch strings.ToUpper(l.Value) {
+	default:
+		return false
+
+	case "PRIMARY":
+		if !l.Lex() || strings.ToUpper(l.Value) != "KEY" || !l.Lex() {
+			return false
+		}
+
+		columns := []string{}
+		if !upperIdList(l, &columns) {
+			return false
+		}
+
+		// primary key columns are implicitly NOT NULL
+		for i, col := range schema.Columns[table] {
+			for _, pk := range columns {
+				if col.Name == pk {
+					schema.Columns[table][i].NotNull = true
+				}
+			}
+		}
+
+		if !schema.addPrimaryKey(name, table, columns) {
+			return false
+		}
+
+	case "UNIQUE":
+		if !l.Lex() {
+			return false
+		}
+
+		columns := []string{}
+		if !upperIdList(l, &columns) {
+			return false
+		}
+
+		schema.Uniques = append(schema.Uniques, oracleManager.UniqueEntry{
+			Table: table, Columns: columns,
+		})
+
+	case "CHECK":
+		if !l.Lex() {
+			return false
+		}
+
+		var cond string
+		if !CondText(l, sql, &cond) {
+			return false
+		}
+
+		schema.addCheck(name, table, cond)
+
+	case "FOREIGN":
+		if !l.Lex() || strings.ToUpper(l.Value) != "KEY" || !l.Lex() {
+			return false
+		}
+
+		ref := oracleManager.Reference{
+			ConstraintName:  schema.constraintName(name, table, "R"),
+			TableReferencer: table,
+		}
+
+		if !upperIdList(l, &ref.ColumnReferencer) || !ReferencesClause(l, &ref) {
+			return false
+		}
+
+		schema.References = append(schema.References, ref)
+	}
+
+	if !ConstraintState(l, sql) {
+		return false
+	}
+
+	for strings.ToUpper(l.Value) == "NOT" {
+		if !l.Lex() || strings.ToUpper(l.Value) != "DEFERRABLE" || !l.Lex() ||
+			!ConstraintState(l, sql) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,196 @@
+package sqlparser
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lucasgpulcinelli/mongoQLer/oracleManager"
+)
+
+func TestParseDDL(t *testing.T) {
+	c := testCatalog(t)
+
+	if !reflect.DeepEqual(c.Tables, []string{"DEPT", "EMP", "BONUS"}) {
+		t.Errorf("tables got %v", c.Tables)
+	}
+
+	if pks := c.GetPrimaryKeys("EMP"); !reflect.DeepEqual(pks, []string{"ID"}) {
+		t.Errorf("primary keys of EMP got %v", pks)
+	}
+
+	if pks := c.GetPrimaryKeys("BONUS"); len(pks) != 0 {
+		t.Errorf("primary keys of BONUS got %v", pks)
+	}
+
+	want := []oracleManager.Reference{{
+		ConstraintName:   "EMP_DEPT",
+		TableReferencer:  "EMP",
+		ColumnReferencer: []string{"DEPT_ID"},
+		TableReferenced:  "DEPT",
+		ColumnReferenced: []string{"ID"},
+	}}
+	if refs, err := c.GetReferences(); err != nil ||
+		!reflect.DeepEqual(refs, want) {
+		t.Errorf("references got %v, %v", refs, err)
+	}
+
+	col, ok := oracleManager.GetColumn(c, "EMP", "SALARY")
+	if !ok || col.DataType != "NUMBER" || *col.Precision != 8 ||
+		*col.Scale != 2 || !col.Nullable {
+		t.Errorf("EMP.SALARY got %+v", col)
+	}
+}
+
+func TestParseDDLChecks(t *testing.T) {
+	tests := []struct {
+		name string
+		ddl  string
+		want []string
+	}{
+		{
+			name: "primary key",
+			ddl:  "CREATE TABLE T (ID NUMBER PRIMARY KEY);",
+			want: []string{"ID IS NOT NULL"},
+		},
+		{
+			name: "NOT NULL primary key",
+			ddl:  "CREATE TABLE T (ID NUMBER NOT NULL PRIMARY KEY);",
+			want: []string{"ID IS NOT NULL"},
+		},
+		{
+			name: "composite primary key with a NOT NULL column",
+			ddl: "CREATE TABLE T (A NUMBER NOT NULL, B NUMBER, " +
+				"PRIMARY KEY (A, B));",
+			want: []string{"A IS NOT NULL", "B IS NOT NULL"},
+		},
+		{
+			name: "check constraint",
+			ddl: "CREATE TABLE T (A NUMBER, " +
+				"CONSTRAINT A_POSITIVE CHECK (A > 0));",
+			want: []string{"A > 0"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schema, err := ParseDDL(test.ddl)
+			if err != nil {
+				t.Fatalf("ParseDDL failed: %v", err)
+			}
+
+			got := []string{}
+			for _, check := range schema.Checks {
+				got = append(got, check.Check)
+			}
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("checks got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseDDLConstraintStates(t *testing.T) {
+	// DDL as written by DBMS_METADATA.GET_DDL
+	schema, err := ParseDDL(`
+CREATE TABLE "HR"."P" (
+	"ID" NUMBER(5,0) NOT NULL ENABLE,
+	"NAME" VARCHAR2(30) DEFAULT NULL,
+	CONSTRAINT "P_PK" PRIMARY KEY ("ID")
+	USING INDEX PCTFREE 10 INITRANS 2 COMPUTE STATISTICS
+	STORAGE(INITIAL 65536 NEXT 1048576) TABLESPACE "USERS" ENABLE,
+	CONSTRAINT "P_NAME" UNIQUE ("NAME") USING INDEX "P_NAME_IDX"
+	DISABLE NOVALIDATE,
+	CONSTRAINT "P_ID" CHECK (ID > 0) DEFERRABLE INITIALLY DEFERRED RELY
+) SEGMENT CREATION IMMEDIATE TABLESPACE "USERS";
+
+CREATE TABLE C (
+	ID NUMBER PRIMARY KEY USING INDEX ENABLE NOT DEFERRABLE,
+	PID REFERENCES P ON DELETE CASCADE,
+	QID CONSTRAINT C_Q REFERENCES P (ID) ENABLE NOT NULL
+);
+`)
+	if err != nil {
+		t.Fatalf("ParseDDL failed: %v", err)
+	}
+	c := schema.Catalog()
+
+	if pks := c.GetPrimaryKeys("P"); !reflect.DeepEqual(pks, []string{"ID"}) {
+		t.Errorf("primary keys of P got %v", pks)
+	}
+
+	if col, ok := oracleManager.GetColumn(c, "P", "NAME"); !ok ||
+		col.Default != "NULL" || !col.Nullable {
+		t.Errorf("P.NAME got %+v", col)
+	}
+
+	if len(c.Uniques) != 1 || c.Uniques[0].Table != "P" {
+		t.Errorf("uniques got %v", c.Uniques)
+	}
+
+	want := []oracleManager.Reference{{
+		ConstraintName:   "SYS_C_R2",
+		TableReferencer:  "C",
+		ColumnReferencer: []string{"PID"},
+		TableReferenced:  "P",
+		ColumnReferenced: []string{"ID"},
+	}, {
+		ConstraintName:   "C_Q",
+		TableReferencer:  "C",
+		ColumnReferencer: []string{"QID"},
+		TableReferenced:  "P",
+		ColumnReferenced: []string{"ID"},
+	}}
+	if refs, err := c.GetReferences(); err != nil ||
+		!reflect.DeepEqual(refs, want) {
+		t.Errorf("references got %v, %v", refs, err)
+	}
+
+	// columns without a data type take the one of the referenced column
+	for _, name := range []string{"PID", "QID"} {
+		col, ok := oracleManager.GetColumn(c, "C", name)
+		if !ok || col.DataType != "NUMBER" || *col.Precision != 5 {
+			t.Errorf("C.%s got %+v", name, col)
+		}
+	}
+
+	_, err = ParseDDL("CREATE TABLE T (ID NOT NULL);")
+	if err == nil {
+		t.Errorf("ParseDDL of a column without a data type did not fail")
+	}
+}
+
+func TestParseDDLIndexes(t *testing.T) {
+	schema, err := ParseDDL(testDDL + `
+CREATE INDEX EMP_NAME ON EMP (NAME);
+CREATE UNIQUE INDEX hr.EMP_DEPT_SALARY ON hr.EMP (DEPT_ID, SALARY DESC)
+	TABLESPACE USERS;
+CREATE INDEX DEPT_UPPER ON DEPT (UPPER(NAME), CODE || 'X' ASC);
+`)
+	if err != nil {
+		t.Fatalf("ParseDDL failed: %v", err)
+	}
+
+	want := []oracleManager.IndexEntry{
+		{
+			Name: "EMP_NAME", Table: "EMP",
+			Columns: []oracleManager.IndexColumn{{Column: "NAME"}},
+		},
+		{
+			Name: "EMP_DEPT_SALARY", Table: "EMP", Unique: true,
+			Columns: []oracleManager.IndexColumn{
+				{Column: "DEPT_ID"}, {Column: "SALARY", Descending: true},
+			},
+		},
+		{
+			Name: "DEPT_UPPER", Table: "DEPT",
+			Columns: []oracleManager.IndexColumn{
+				{Expression: "UPPER(NAME)"}, {Expression: "CODE || 'X'"},
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(schema.Indexes, want) {
+		t.Errorf("indexes got %+v, want %+v", schema.Indexes, want)
+	}
+}
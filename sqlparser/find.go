@@ -10,7 +10,9 @@ import (
 
 // GetSelect gets the bson representing the find key selection document, or the
 // $project value in an aggregation pipeline.
-func (stmt *Statement) GetSelect() (bson.D, error) {
+func (stmt *Statement) GetSelect(
+	catalog oracleManager.Catalog,
+) (bson.D, error) {
 	if len(stmt.SelectColumn) == 0 {
 		return bson.D{}, nil
 	}
@@ -23,19 +25,21 @@ func (stmt *Statement) GetSelect() (bson.D, error) {
 
 		if selection.GroupFunction != "" {
 			k = selection.GroupFunction + "(" + selection.Name + ")"
-		} else if oracleManager.TableContainsColumn(stmt.JoinTable, selection.Name) {
+		} else if oracleManager.TableContainsColumn(
+			catalog, stmt.JoinTable, selection.Name,
+		) {
 			// if the column is in the joined table, we need to reference it as
 			// table.column, because the lookup + unwind will make the reference to
 			// this field as that
 
-			k = stmt.JoinTable + "." + keyManager.ToMongoId(
+			k = stmt.JoinTable + "." + keyManager.ToMongoId(catalog,
 				stmt.JoinTable,
 				selection.Name,
 			)
 		} else if stmt.IsAggregate() {
 			return bson.D{}, fmt.Errorf("not a single group aggregation")
 		} else {
-			k = keyManager.ToMongoId(
+			k = keyManager.ToMongoId(catalog,
 				stmt.FromTable,
 				selection.Name,
 			)
@@ -61,17 +65,19 @@ func (stmt *Statement) GetSelect() (bson.D, error) {
 
 // ToMongoFind gets the bsons representing a find for a statement. The first
 // document is the filter and the second is the key selection.
-func (stmt *Statement) ToMongoFind() (bson.D, bson.D, error) {
+func (stmt *Statement) ToMongoFind(
+	catalog oracleManager.Catalog,
+) (bson.D, bson.D, error) {
 	if stmt.IsAggregate() {
 		return bson.D{}, bson.D{}, fmt.Errorf("invalid statement for find")
 	}
 
-	selection, err := stmt.GetSelect()
+	selection, err := stmt.GetSelect(catalog)
 	if err != nil {
 		return bson.D{}, bson.D{}, err
 	}
 
-	where, err := stmt.Where.GetBson(stmt.FromTable, stmt.JoinTable)
+	where, err := stmt.Where.GetBson(catalog, stmt.FromTable, stmt.JoinTable)
 	if err != nil {
 		return bson.D{}, bson.D{}, err
 	}
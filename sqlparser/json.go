@@ -0,0 +1,161 @@
+package sqlparser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// The AST is encoded in JSON with the same field names as the structs. As
+// BooleanExpression is an interface, each expression also has a "Type" field
+// with the name of its struct, so that it can be decoded back.
+
+// decode decodes JSON keeping numbers as json.Number, so that integers from
+// the parser are not converted to floats.
+func decode(data []byte, v any) error {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	return d.Decode(v)
+}
+
+// jsonValue converts a value decoded with decode back to the form GetValue
+// creates.
+func jsonValue(v any) any {
+	n, ok := v.(json.Number)
+	if !ok {
+		return v
+	}
+
+	if i, err := n.Int64(); err == nil {
+		return i
+	}
+
+	f, _ := n.Float64()
+	return f
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (e EmptyComparision) MarshalJSON() ([]byte, error) {
+	return []byte(`{"Type":"EmptyComparision"}`), nil
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (c *Comparision) MarshalJSON() ([]byte, error) {
+	type plain Comparision
+	return json.Marshal(struct {
+		Type string
+		*plain
+	}{"Comparision", (*plain)(c)})
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (ic *InComparision) MarshalJSON() ([]byte, error) {
+	type plain InComparision
+	return json.Marshal(struct {
+		Type string
+		*plain
+	}{"InComparision", (*plain)(ic)})
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (bc *BooleanComposite) MarshalJSON() ([]byte, error) {
+	type plain BooleanComposite
+	return json.Marshal(struct {
+		Type string
+		*plain
+	}{"BooleanComposite", (*plain)(bc)})
+}
+
+// UnmarshalBoolExpr decodes a BooleanExpression from its JSON form. A null or
+// missing expression is decoded as an EmptyComparision.
+func UnmarshalBoolExpr(data []byte) (BooleanExpression, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || string(data) == "null" {
+		return EmptyComparision{}, nil
+	}
+
+	var typed struct{ Type string }
+	err := json.Unmarshal(data, &typed)
+	if err != nil {
+		return nil, err
+	}
+
+	switch typed.Type {
+	case "EmptyComparision":
+		return EmptyComparision{}, nil
+
+	case "Comparision":
+		c := &Comparision{}
+		err = decode(data, c)
+		c.Value = jsonValue(c.Value)
+		return c, err
+
+	case "InComparision":
+		ic := &InComparision{}
+		err = decode(data, ic)
+		for i := range ic.Values {
+			ic.Values[i] = jsonValue(ic.Values[i])
+		}
+		return ic, err
+
+	case "BooleanComposite":
+		bc := &BooleanComposite{}
+		err = json.Unmarshal(data, bc)
+		return bc, err
+	}
+
+	return nil, fmt.Errorf("invalid boolean expression type %q", typed.Type)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (bc *BooleanComposite) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		BoolOp  string
+		SubExpr []json.RawMessage
+	}
+
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return err
+	}
+
+	bc.BoolOp = raw.BoolOp
+	bc.SubExpr = []BooleanExpression{}
+
+	for _, se := range raw.SubExpr {
+		be, err := UnmarshalBoolExpr(se)
+		if err != nil {
+			return err
+		}
+
+		bc.SubExpr = append(bc.SubExpr, be)
+	}
+
+	return nil
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (stmt *Statement) UnmarshalJSON(data []byte) error {
+	type plain Statement
+	raw := struct {
+		*plain
+		Where json.RawMessage
+	}{plain: (*plain)(stmt)}
+
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return err
+	}
+
+	stmt.Where, err = UnmarshalBoolExpr(raw.Where)
+	return err
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (a *Assignment) UnmarshalJSON(data []byte) error {
+	type plain Assignment
+
+	err := decode(data, (*plain)(a))
+	a.Value = jsonValue(a.Value)
+	return err
+}
@@ -11,8 +11,9 @@ import (
 type Lexer struct {
 	s scanner.Scanner // the scanner itself
 
-	Value string // the current value read as a string
-	Token rune   // the current token mostly as returned from the scanner
+	Value  string // the current value read as a string
+	Token  rune   // the current token mostly as returned from the scanner
+	Offset int    // the byte offset of the current token in the input
 }
 
 // NewLexer creares a new Lexer from a reader.
@@ -33,6 +34,7 @@ func NewLexer(rd io.Reader) *Lexer {
 func (l *Lexer) Lex() bool {
 	l.Token = l.s.Scan()
 	l.Value = l.s.TokenText()
+	l.Offset = l.s.Position.Offset
 
 	// convert some tokens into more usable forms for SQL:
 	if l.Value == "<" {
@@ -0,0 +1,329 @@
+package sqlparser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lucasgpulcinelli/mongoQLer/keyManager"
+	"github.com/lucasgpulcinelli/mongoQLer/oracleManager"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// fieldName gets the name of the field that holds a selection column in the
+// documents that the Statement pipeline outputs.
+func (stmt *Statement) fieldName(
+	catalog oracleManager.Catalog, col Column,
+) string {
+	if col.GroupFunction != "" {
+		return col.GroupFunction + "(" + col.Name + ")"
+	}
+
+	if oracleManager.TableContainsColumn(catalog, stmt.JoinTable, col.Name) {
+		return stmt.JoinTable + "." +
+			keyManager.ToMongoId(catalog, stmt.JoinTable, col.Name)
+	}
+
+	return keyManager.ToMongoId(catalog, stmt.FromTable, col.Name)
+}
+
+// reshape gets the $replaceWith stage that creates the documents of a table
+// from a list of columns and values, where the values are expressions over the
+// incoming documents. Like in the tableToCollection package, primary keys are
+// placed in the "_id" sub document.
+func reshape(
+	catalog oracleManager.Catalog, table string, columns []string, values []any,
+) bson.D {
+	doc := bson.D{}
+	pks := bson.D{}
+
+	for i, col := range columns {
+		if keyManager.IsPk(catalog, table, col) {
+			pks = append(pks, bson.E{Key: col, Value: values[i]})
+		} else {
+			doc = append(doc, bson.E{Key: col, Value: values[i]})
+		}
+	}
+
+	// without a primary key, mongoDB will create an objectID for the document
+	if len(pks) != 0 {
+		doc = append(doc, bson.E{Key: "_id", Value: pks})
+	}
+
+	return bson.D{{Key: "$replaceWith", Value: doc}}
+}
+
+// literal gets the aggregation expression for a constant value.
+func literal(v any) bson.D {
+	return bson.D{{Key: "$literal", Value: v}}
+}
+
+// insertPipeline gets the pipeline that converts the source query of an
+// InsertStatement to documents of the target table, without the final stage.
+func (ins *InsertStatement) insertPipeline(
+	catalog oracleManager.Catalog,
+) (mongo.Pipeline, error) {
+	result, err := ins.Select.pipeline(catalog)
+	if err != nil {
+		return mongo.Pipeline{}, err
+	}
+
+	// with no column list and no selection, the documents are kept as is
+	if len(ins.IntoColumns) == 0 && len(ins.Select.SelectColumn) == 0 {
+		return result, nil
+	}
+
+	if len(ins.Select.SelectColumn) == 0 {
+		return mongo.Pipeline{}, fmt.Errorf(
+			"INSERT column list requires explicit SELECT columns",
+		)
+	}
+
+	columns := ins.IntoColumns
+	if len(columns) == 0 {
+		// the target columns are named after the selected ones
+		for _, col := range ins.Select.SelectColumn {
+			columns = append(columns, col.Name)
+			if col.GroupFunction != "" {
+				columns[len(columns)-1] = ins.Select.fieldName(catalog, col)
+			}
+		}
+	}
+
+	if len(columns) != len(ins.Select.SelectColumn) {
+		return mongo.Pipeline{}, fmt.Errorf(
+			"INSERT has %d columns but SELECT has %d",
+			len(columns), len(ins.Select.SelectColumn),
+		)
+	}
+
+	// each column becomes a field, which cannot be set twice in a document
+	seen := map[string]bool{}
+	for _, col := range columns {
+		if seen[col] {
+			return mongo.Pipeline{}, fmt.Errorf(
+				"column %s is inserted more than once in %s", col, ins.IntoTable,
+			)
+		}
+		seen[col] = true
+	}
+
+	values := []any{}
+	for _, col := range ins.Select.SelectColumn {
+		values = append(values, "$"+ins.Select.fieldName(catalog, col))
+	}
+
+	return append(result, reshape(catalog, ins.IntoTable, columns, values)), nil
+}
+
+// ToMongoMerge gets the (optimized) Pipeline representing an INSERT INTO ...
+// SELECT statement, ending in a $merge that inserts all documents in the target
+// collection and fails on duplicated keys, like oracle would.
+func (ins *InsertStatement) ToMongoMerge(
+	catalog oracleManager.Catalog,
+) (mongo.Pipeline, error) {
+	result, err := ins.insertPipeline(catalog)
+	if err != nil {
+		return mongo.Pipeline{}, err
+	}
+
+	return OptimizePipeline(append(result, bson.D{{Key: "$merge", Value: bson.D{
+		{Key: "into", Value: ins.IntoTable},
+		{Key: "whenMatched", Value: "fail"},
+		{Key: "whenNotMatched", Value: "insert"},
+	}}})), nil
+}
+
+// ToMongoOut gets the (optimized) Pipeline representing an INSERT INTO ...
+// SELECT statement ending in an $out. Note that $out replaces the whole target
+// collection, so this is only equivalent to the statement if the target table
+// is empty.
+func (ins *InsertStatement) ToMongoOut(
+	catalog oracleManager.Catalog,
+) (mongo.Pipeline, error) {
+	result, err := ins.insertPipeline(catalog)
+	if err != nil {
+		return mongo.Pipeline{}, err
+	}
+
+	return OptimizePipeline(
+		append(result, bson.D{{Key: "$out", Value: ins.IntoTable}}),
+	), nil
+}
+
+// SourceTable gets the table (and therefore collection) where the MERGE
+// pipeline should be run.
+func (merge *MergeStatement) SourceTable() string {
+	if merge.UsingSelect != nil {
+		return merge.UsingSelect.FromTable
+	}
+
+	return merge.UsingTable
+}
+
+// sourceField gets the field in the source documents holding a column.
+func (merge *MergeStatement) sourceField(
+	catalog oracleManager.Catalog, column string,
+) string {
+	if merge.UsingSelect == nil {
+		return keyManager.ToMongoId(catalog, merge.UsingTable, column)
+	}
+
+	return merge.UsingSelect.fieldName(catalog, Column{Name: column})
+}
+
+// sourceValue gets the expression for the value of an assignment over the
+// source documents.
+func (merge *MergeStatement) sourceValue(
+	catalog oracleManager.Catalog, a Assignment,
+) any {
+	if a.SourceColumn == "" {
+		return literal(a.Value)
+	}
+
+	return "$" + merge.sourceField(catalog, a.SourceColumn)
+}
+
+// GetMergeOn gets the value of the "on" option of $merge, that is, the fields
+// of the target collection that identify matching documents.
+func (merge *MergeStatement) GetMergeOn(
+	catalog oracleManager.Catalog,
+) (any, error) {
+	pks := catalog.GetPrimaryKeys(merge.IntoTable)
+
+	on := []string{}
+	pksUsed := 0
+	for _, a := range merge.On {
+		if keyManager.IsPk(catalog, merge.IntoTable, a.Column) {
+			pksUsed++
+			continue
+		}
+
+		on = append(on, a.Column)
+	}
+
+	// the primary keys are all in the _id sub document, so either all or none
+	// must be used, as mongoDB requires an unique index for the on fields
+	if pksUsed != 0 && pksUsed != len(pks) {
+		return nil, fmt.Errorf(
+			"MERGE ON must use all primary key columns of %s or none of them",
+			merge.IntoTable,
+		)
+	}
+
+	if pksUsed != 0 {
+		on = append([]string{"_id"}, on...)
+	}
+
+	if len(on) == 1 {
+		return on[0], nil
+	}
+
+	return on, nil
+}
+
+// ToMongoAggregate gets the (optimized) Pipeline representing a MERGE
+// statement, to be run in the SourceTable collection. The source documents are
+// converted to the format of the target collection and then passed to a
+// $merge, with whenMatched and whenNotMatched options following the WHEN
+// clauses.
+//
+// When both clauses are present, the documents passed to $merge are the ones
+// to be inserted, so all values in the UPDATE SET clause must either be
+// literals or source columns used in the ON or INSERT clauses.
+func (merge *MergeStatement) ToMongoAggregate(
+	catalog oracleManager.Catalog,
+) (mongo.Pipeline, error) {
+	if len(merge.On) == 0 {
+		return mongo.Pipeline{}, fmt.Errorf("MERGE without ON condition")
+	}
+
+	if merge.MatchedUpdate == nil && merge.NotMatchedInsert == nil {
+		return mongo.Pipeline{}, fmt.Errorf("MERGE without WHEN clauses")
+	}
+
+	result := mongo.Pipeline{}
+	if merge.UsingSelect != nil {
+		var err error
+		result, err = merge.UsingSelect.pipeline(catalog)
+		if err != nil {
+			return mongo.Pipeline{}, err
+		}
+	}
+
+	// the incoming documents for $merge contain the ON columns and, if there is
+	// no INSERT, the updated columns, so that they can be merged as a whole
+	incoming := append([]Assignment{}, merge.On...)
+	if merge.NotMatchedInsert != nil {
+		incoming = append(incoming, merge.NotMatchedInsert...)
+	} else {
+		incoming = append(incoming, merge.MatchedUpdate...)
+	}
+
+	columns := []string{}
+	values := []any{}
+	added := map[string]bool{}
+	for _, a := range incoming {
+		if added[strings.ToUpper(a.Column)] {
+			continue
+		}
+		added[strings.ToUpper(a.Column)] = true
+
+		columns = append(columns, a.Column)
+		values = append(values, merge.sourceValue(catalog, a))
+	}
+
+	result = append(result, reshape(catalog, merge.IntoTable, columns, values))
+
+	on, err := merge.GetMergeOn(catalog)
+	if err != nil {
+		return mongo.Pipeline{}, err
+	}
+
+	var whenMatched any = "keepExisting"
+	if merge.MatchedUpdate != nil && merge.NotMatchedInsert == nil {
+		whenMatched = "merge"
+	} else if merge.MatchedUpdate != nil {
+		set := bson.D{}
+		for _, a := range merge.MatchedUpdate {
+			k := keyManager.ToMongoId(catalog, merge.IntoTable, a.Column)
+
+			if a.SourceColumn == "" {
+				set = append(set, bson.E{Key: k, Value: literal(a.Value)})
+				continue
+			}
+
+			// search for the incoming field holding the source column
+			v := ""
+			for _, in := range incoming {
+				if strings.EqualFold(in.SourceColumn, a.SourceColumn) {
+					v = "$$new." + keyManager.ToMongoId(catalog, merge.IntoTable, in.Column)
+					break
+				}
+			}
+
+			if v == "" {
+				return mongo.Pipeline{}, fmt.Errorf(
+					"MERGE UPDATE uses %s, which is not in the ON or INSERT clauses",
+					a.SourceColumn,
+				)
+			}
+
+			set = append(set, bson.E{Key: k, Value: v})
+		}
+
+		whenMatched = mongo.Pipeline{bson.D{{Key: "$set", Value: set}}}
+	}
+
+	whenNotMatched := "discard"
+	if merge.NotMatchedInsert != nil {
+		whenNotMatched = "insert"
+	}
+
+	return OptimizePipeline(append(result, bson.D{{Key: "$merge", Value: bson.D{
+		{Key: "into", Value: merge.IntoTable},
+		{Key: "on", Value: on},
+		{Key: "whenMatched", Value: whenMatched},
+		{Key: "whenNotMatched", Value: whenNotMatched},
+	}}})), nil
+}
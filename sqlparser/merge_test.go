@@ -0,0 +1,271 @@
+package sqlparser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lucasgpulcinelli/mongoQLer/oracleManager"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// testDDL is the schema used by the tests of this package.
+const testDDL = `
+CREATE TABLE DEPT (
+	ID NUMBER(5) PRIMARY KEY,
+	NAME VARCHAR2(30) NOT NULL,
+	CODE CHAR(3)
+);
+
+CREATE TABLE EMP (
+	ID NUMBER(5) PRIMARY KEY,
+	NAME VARCHAR2(30),
+	SALARY NUMBER(8,2),
+	DEPT_ID NUMBER(5),
+	CONSTRAINT EMP_DEPT FOREIGN KEY (DEPT_ID) REFERENCES DEPT (ID)
+);
+
+CREATE TABLE BONUS (EMP_ID NUMBER(5), AMOUNT NUMBER(8,2));
+`
+
+// testCatalog gets the catalog of testDDL.
+func testCatalog(t *testing.T) *oracleManager.MemoryCatalog {
+	t.Helper()
+
+	schema, err := ParseDDL(testDDL)
+	if err != nil {
+		t.Fatalf("invalid test DDL: %v", err)
+	}
+
+	return schema.Catalog()
+}
+
+// docJSON gets a document in relaxed extended JSON, to be compared in tests.
+func docJSON(t *testing.T, d bson.D) string {
+	t.Helper()
+
+	b, err := bson.MarshalExtJSON(d, false, false)
+	if err != nil {
+		t.Fatalf("invalid document %v: %v", d, err)
+	}
+
+	return string(b)
+}
+
+// pipelineJSON gets a pipeline as an array of stages in relaxed extended JSON.
+func pipelineJSON(t *testing.T, p mongo.Pipeline) string {
+	t.Helper()
+
+	stages := []string{}
+	for _, stage := range p {
+		stages = append(stages, docJSON(t, stage))
+	}
+
+	return "[" + strings.Join(stages, ",") + "]"
+}
+
+// analyzed parses and analyzes a query against the test catalog.
+func analyzed(t *testing.T, sql string) *Statement {
+	t.Helper()
+
+	stmt, err := Parse(sql)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	err = stmt.Analyze(testCatalog(t))
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	return stmt
+}
+
+func TestInsertToMongo(t *testing.T) {
+	tests := []struct {
+		sql   string
+		merge string
+		out   string
+	}{
+		{
+			sql: "INSERT INTO BONUS (EMP_ID, AMOUNT) " +
+				"SELECT ID, SALARY FROM EMP WHERE SALARY > 10;",
+			merge: `[{"$match":{"SALARY":{"$gt":10}}},` +
+				`{"$project":{"_id.ID":1,"SALARY":1}},` +
+				`{"$replaceWith":{"EMP_ID":"$_id.ID","AMOUNT":"$SALARY"}},` +
+				`{"$merge":{"into":"BONUS","whenMatched":"fail",` +
+				`"whenNotMatched":"insert"}}]`,
+			out: `[{"$match":{"SALARY":{"$gt":10}}},` +
+				`{"$project":{"_id.ID":1,"SALARY":1}},` +
+				`{"$replaceWith":{"EMP_ID":"$_id.ID","AMOUNT":"$SALARY"}},` +
+				`{"$out":"BONUS"}]`,
+		},
+		{
+			sql: "INSERT INTO DEPT (ID, NAME) SELECT ID, NAME FROM EMP;",
+			merge: `[{"$project":{"_id.ID":1,"NAME":1}},` +
+				`{"$replaceWith":{"NAME":"$NAME","_id":{"ID":"$_id.ID"}}},` +
+				`{"$merge":{"into":"DEPT","whenMatched":"fail",` +
+				`"whenNotMatched":"insert"}}]`,
+			out: `[{"$project":{"_id.ID":1,"NAME":1}},` +
+				`{"$replaceWith":{"NAME":"$NAME","_id":{"ID":"$_id.ID"}}},` +
+				`{"$out":"DEPT"}]`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.sql, func(t *testing.T) {
+			c := testCatalog(t)
+
+			ins, err := ParseInsert(test.sql)
+			if err != nil {
+				t.Fatalf("ParseInsert failed: %v", err)
+			}
+			if err = ins.Analyze(c); err != nil {
+				t.Fatalf("Analyze failed: %v", err)
+			}
+
+			merge, err := ins.ToMongoMerge(c)
+			if err != nil {
+				t.Fatalf("ToMongoMerge failed: %v", err)
+			}
+			if got := pipelineJSON(t, merge); got != test.merge {
+				t.Errorf("ToMongoMerge got\n%s\nwant\n%s", got, test.merge)
+			}
+
+			out, err := ins.ToMongoOut(c)
+			if err != nil {
+				t.Fatalf("ToMongoOut failed: %v", err)
+			}
+			if got := pipelineJSON(t, out); got != test.out {
+				t.Errorf("ToMongoOut got\n%s\nwant\n%s", got, test.out)
+			}
+		})
+	}
+}
+
+func TestInsertToMongoErrors(t *testing.T) {
+	tests := []string{
+		"INSERT INTO BONUS SELECT ID, ID FROM EMP;",
+		"INSERT INTO BONUS (EMP_ID, EMP_ID) SELECT ID, SALARY FROM EMP;",
+		"INSERT INTO BONUS (EMP_ID) SELECT ID, SALARY FROM EMP;",
+		"INSERT INTO BONUS (EMP_ID, AMOUNT) SELECT * FROM EMP;",
+	}
+
+	for _, sql := range tests {
+		t.Run(sql, func(t *testing.T) {
+			c := testCatalog(t)
+
+			ins, err := ParseInsert(sql)
+			if err != nil {
+				t.Fatalf("ParseInsert failed: %v", err)
+			}
+
+			// the statement is rejected either by Analyze or ToMongoMerge
+			err = ins.Analyze(c)
+			if err == nil {
+				_, err = ins.ToMongoMerge(c)
+			}
+			if err == nil {
+				t.Errorf("INSERT was not rejected")
+			}
+		})
+	}
+}
+
+func TestMergeToMongoAggregate(t *testing.T) {
+	tests := []struct {
+		sql      string
+		source   string
+		pipeline string
+	}{
+		{
+			sql: "MERGE INTO DEPT D USING EMP E ON (D.ID = E.DEPT_ID) " +
+				"WHEN MATCHED THEN UPDATE SET D.NAME = E.NAME " +
+				"WHEN NOT MATCHED THEN INSERT (ID, NAME) " +
+				"VALUES (E.DEPT_ID, E.NAME);",
+			source: "EMP",
+			pipeline: `[{"$replaceWith":{"NAME":"$NAME",` +
+				`"_id":{"ID":"$DEPT_ID"}}},` +
+				`{"$merge":{"into":"DEPT","on":"_id",` +
+				`"whenMatched":[{"$set":{"NAME":"$$new.NAME"}}],` +
+				`"whenNotMatched":"insert"}}]`,
+		},
+		{
+			sql: "MERGE INTO BONUS B USING EMP E ON (B.EMP_ID = E.ID) " +
+				"WHEN MATCHED THEN UPDATE SET B.AMOUNT = 0;",
+			source: "EMP",
+			pipeline: `[{"$replaceWith":{"EMP_ID":"$_id.ID",` +
+				`"AMOUNT":{"$literal":0}}},` +
+				`{"$merge":{"into":"BONUS","on":"EMP_ID",` +
+				`"whenMatched":"merge","whenNotMatched":"discard"}}]`,
+		},
+		{
+			sql: "MERGE INTO BONUS B USING (SELECT ID, SALARY FROM EMP " +
+				"WHERE SALARY > 10) E ON (B.EMP_ID = E.ID) " +
+				"WHEN NOT MATCHED THEN INSERT (EMP_ID, AMOUNT) " +
+				"VALUES (E.ID, E.SALARY);",
+			source: "EMP",
+			pipeline: `[{"$match":{"SALARY":{"$gt":10}}},` +
+				`{"$project":{"_id.ID":1,"SALARY":1}},` +
+				`{"$replaceWith":{"EMP_ID":"$_id.ID","AMOUNT":"$SALARY"}},` +
+				`{"$merge":{"into":"BONUS","on":"EMP_ID",` +
+				`"whenMatched":"keepExisting","whenNotMatched":"insert"}}]`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.sql, func(t *testing.T) {
+			c := testCatalog(t)
+
+			merge, err := ParseMerge(test.sql)
+			if err != nil {
+				t.Fatalf("ParseMerge failed: %v", err)
+			}
+			if err = merge.Analyze(c); err != nil {
+				t.Fatalf("Analyze failed: %v", err)
+			}
+
+			if merge.SourceTable() != test.source {
+				t.Errorf("SourceTable got %s", merge.SourceTable())
+			}
+
+			p, err := merge.ToMongoAggregate(c)
+			if err != nil {
+				t.Fatalf("ToMongoAggregate failed: %v", err)
+			}
+			if got := pipelineJSON(t, p); got != test.pipeline {
+				t.Errorf("pipeline got\n%s\nwant\n%s", got, test.pipeline)
+			}
+		})
+	}
+}
+
+func TestMergeToMongoAggregateErrors(t *testing.T) {
+	tests := []string{
+		"MERGE INTO DEPT D USING EMP E ON (D.ID = E.DEPT_ID) " +
+			"WHEN MATCHED THEN UPDATE SET D.NAME = E.NAME " +
+			"WHEN NOT MATCHED THEN INSERT (ID, NAME) " +
+			"VALUES (E.DEPT_ID, 'new');",
+		"MERGE INTO EMP T USING EMP S ON (T.ID = S.ID) " +
+			"WHEN MATCHED THEN UPDATE SET T.SALARY = S.SALARY " +
+			"WHEN NOT MATCHED THEN INSERT (ID) VALUES (S.ID);",
+	}
+
+	for _, sql := range tests {
+		t.Run(sql, func(t *testing.T) {
+			c := testCatalog(t)
+
+			merge, err := ParseMerge(sql)
+			if err != nil {
+				t.Fatalf("ParseMerge failed: %v", err)
+			}
+			if err = merge.Analyze(c); err != nil {
+				t.Fatalf("Analyze failed: %v", err)
+			}
+
+			if _, err := merge.ToMongoAggregate(c); err == nil {
+				t.Errorf("ToMongoAggregate did not fail")
+			}
+		})
+	}
+}
@@ -0,0 +1,411 @@
+package sqlparser
+
+import (
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// a fieldSet is a set of top level document fields, where nil means that all
+// fields are in the set.
+type fieldSet map[string]bool
+
+// add adds the top level field of a path to the set.
+func (fs fieldSet) add(path string) {
+	if fs == nil {
+		return
+	}
+
+	fs[strings.Split(path, ".")[0]] = true
+}
+
+// stageOf gets the operator and value of a pipeline stage.
+func stageOf(stage bson.D) (string, any) {
+	if len(stage) != 1 {
+		return "", nil
+	}
+
+	return stage[0].Key, stage[0].Value
+}
+
+// asArray converts the arrays used in the pipelines generated by this package
+// to an array of any.
+func asArray(v any) ([]any, bool) {
+	switch a := v.(type) {
+	case []any:
+		return a, true
+	case bson.A:
+		return a, true
+	case []bson.D:
+		result := []any{}
+		for _, d := range a {
+			result = append(result, d)
+		}
+
+		return result, true
+	}
+
+	return nil, false
+}
+
+// conjuncts splits a filter in the conditions that must all be true for it to
+// match, such that each condition is a filter itself.
+func conjuncts(filter bson.D) []bson.D {
+	result := []bson.D{}
+
+	for _, e := range filter {
+		if e.Key != "$and" {
+			result = append(result, bson.D{e})
+			continue
+		}
+
+		// an $and that is not made of documents is kept whole
+		subs, ok := asArray(e.Value)
+		docs := []bson.D{}
+		for _, sub := range subs {
+			if d, isDoc := sub.(bson.D); isDoc {
+				docs = append(docs, d)
+			}
+		}
+
+		if !ok || len(docs) != len(subs) {
+			result = append(result, bson.D{e})
+			continue
+		}
+
+		for _, d := range docs {
+			result = append(result, conjuncts(d)...)
+		}
+	}
+
+	return result
+}
+
+// joinConjuncts creates a filter that matches only if all conditions match,
+// keeping the filter flat if no field repeats.
+func joinConjuncts(conds []bson.D) bson.D {
+	if len(conds) == 1 {
+		return conds[0]
+	}
+
+	result := bson.D{}
+	seen := map[string]bool{}
+	for _, cond := range conds {
+		for _, e := range cond {
+			if seen[e.Key] {
+				return bson.D{{Key: "$and", Value: conds}}
+			}
+
+			seen[e.Key] = true
+			result = append(result, e)
+		}
+	}
+
+	return result
+}
+
+// filterFields adds all fields used by a filter to a field set, returning false
+// if the filter uses an operator that can reference fields in other ways (such
+// as $expr).
+func filterFields(filter bson.D, fs fieldSet) bool {
+	for _, e := range filter {
+		switch e.Key {
+		case "$and", "$or", "$nor":
+			subs, ok := asArray(e.Value)
+			if !ok {
+				return false
+			}
+
+			for _, sub := range subs {
+				d, ok := sub.(bson.D)
+				if !ok || !filterFields(d, fs) {
+					return false
+				}
+			}
+		default:
+			if strings.HasPrefix(e.Key, "$") {
+				return false
+			}
+
+			fs.add(e.Key)
+		}
+	}
+
+	return true
+}
+
+// expressionFields adds all fields used by an aggregation expression to a
+// field set, returning false if the expression uses the whole document.
+func expressionFields(v any, fs fieldSet) bool {
+	switch vv := v.(type) {
+	case string:
+		if strings.HasPrefix(vv, "$$") {
+			return !strings.HasPrefix(vv, "$$ROOT") &&
+				!strings.HasPrefix(vv, "$$CURRENT")
+		}
+
+		if strings.HasPrefix(vv, "$") {
+			fs.add(vv[1:])
+		}
+	case bson.D:
+		for _, e := range vv {
+			if e.Key == "$literal" {
+				continue
+			}
+
+			if !expressionFields(e.Value, fs) {
+				return false
+			}
+		}
+	default:
+		if a, ok := asArray(v); ok {
+			for _, av := range a {
+				if !expressionFields(av, fs) {
+					return false
+				}
+			}
+		}
+	}
+
+	return true
+}
+
+// neededFields gets the fields that the stages need from their input
+// documents, or nil if all fields may be needed.
+func neededFields(stages mongo.Pipeline) fieldSet {
+	// documents at the end of the pipeline are the result, so all their fields
+	// are needed
+	var fs fieldSet
+
+	for i := len(stages) - 1; i >= 0; i-- {
+		op, v := stageOf(stages[i])
+		d, _ := v.(bson.D)
+
+		switch op {
+		default:
+			fs = nil
+
+		case "$limit", "$skip":
+
+		case "$count":
+			fs = fieldSet{}
+
+		case "$match":
+			if !filterFields(d, fs) {
+				fs = nil
+			}
+
+		case "$sort":
+			for _, e := range d {
+				fs.add(e.Key)
+			}
+
+		case "$unwind":
+			if s, ok := v.(string); ok {
+				fs.add(strings.TrimPrefix(s, "$"))
+			} else {
+				fs = nil
+			}
+
+		case "$lookup":
+			var as, local string
+			for _, e := range d {
+				if e.Key == "as" {
+					as, _ = e.Value.(string)
+				} else if e.Key == "localField" {
+					local, _ = e.Value.(string)
+				}
+			}
+
+			if fs != nil {
+				delete(fs, strings.Split(as, ".")[0])
+			}
+			fs.add(local)
+
+		case "$group", "$replaceWith":
+			// these stages create new documents, so only their expressions matter
+			fs = fieldSet{}
+			if !expressionFields(v, fs) {
+				fs = nil
+			}
+
+		case "$project":
+			// only projections that include fields create new documents
+			fs = fieldSet{}
+			for _, e := range d {
+				switch e.Value {
+				case 0, false, int32(0), int64(0):
+					if e.Key != "_id" {
+						return nil
+					}
+				case 1, true, int32(1), int64(1):
+					fs.add(e.Key)
+				default:
+					if !expressionFields(e.Value, fs) {
+						return nil
+					}
+				}
+			}
+		}
+	}
+
+	return fs
+}
+
+// lookupAs gets the "as" field of a $lookup stage.
+func lookupAs(lookup bson.D) string {
+	_, v := stageOf(lookup)
+	d, _ := v.(bson.D)
+
+	for _, e := range d {
+		if e.Key == "as" {
+			as, _ := e.Value.(string)
+			return as
+		}
+	}
+
+	return ""
+}
+
+// pushMatches moves the conditions of a $match after a $lookup and its
+// $unwind that do not use the joined documents to before the $lookup, as the
+// join is an inner join and they can be checked before it.
+func pushMatches(p mongo.Pipeline) mongo.Pipeline {
+	result := mongo.Pipeline{}
+
+	for i := 0; i < len(p); i++ {
+		op, _ := stageOf(p[i])
+		if op != "$lookup" || i+2 >= len(p) {
+			result = append(result, p[i])
+			continue
+		}
+
+		as := lookupAs(p[i])
+		unwindOp, unwindV := stageOf(p[i+1])
+		matchOp, matchV := stageOf(p[i+2])
+		matchD, _ := matchV.(bson.D)
+
+		if unwindOp != "$unwind" || unwindV != "$"+as || matchOp != "$match" {
+			result = append(result, p[i])
+			continue
+		}
+
+		before := []bson.D{}
+		after := []bson.D{}
+		for _, cond := range conjuncts(matchD) {
+			fs := fieldSet{}
+			if filterFields(cond, fs) && !fs[strings.Split(as, ".")[0]] {
+				before = append(before, cond)
+			} else {
+				after = append(after, cond)
+			}
+		}
+
+		if len(before) != 0 {
+			result = append(result,
+				bson.D{{Key: "$match", Value: joinConjuncts(before)}},
+			)
+		}
+
+		result = append(result, p[i], p[i+1])
+
+		if len(after) != 0 {
+			result = append(result,
+				bson.D{{Key: "$match", Value: joinConjuncts(after)}},
+			)
+		}
+
+		i += 2
+	}
+
+	return result
+}
+
+// mergeMatches drops empty $match stages and merges adjacent ones.
+func mergeMatches(p mongo.Pipeline) mongo.Pipeline {
+	result := mongo.Pipeline{}
+
+	for _, stage := range p {
+		op, v := stageOf(stage)
+		d, _ := v.(bson.D)
+
+		if op != "$match" {
+			result = append(result, stage)
+			continue
+		}
+
+		if len(d) == 0 {
+			continue
+		}
+
+		if len(result) != 0 {
+			prevOp, prevV := stageOf(result[len(result)-1])
+			prevD, _ := prevV.(bson.D)
+
+			if prevOp == "$match" {
+				result[len(result)-1] = bson.D{{Key: "$match",
+					Value: joinConjuncts(append(conjuncts(prevD), conjuncts(d)...)),
+				}}
+				continue
+			}
+		}
+
+		result = append(result, stage)
+	}
+
+	return result
+}
+
+// pushProjects adds a $project before each $lookup that keeps only the fields
+// that later stages need, so that less data goes through the join.
+func pushProjects(p mongo.Pipeline) mongo.Pipeline {
+	result := mongo.Pipeline{}
+
+	for i, stage := range p {
+		op, _ := stageOf(stage)
+		prevOp := ""
+		if i != 0 {
+			prevOp, _ = stageOf(p[i-1])
+		}
+
+		if op != "$lookup" || prevOp == "$project" {
+			result = append(result, stage)
+			continue
+		}
+
+		fs := neededFields(p[i:])
+		if len(fs) != 0 {
+			keys := []string{}
+			for k := range fs {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+
+			project := bson.D{}
+			for _, k := range keys {
+				project = append(project, bson.E{Key: k, Value: 1})
+			}
+
+			if !fs["_id"] {
+				project = append(project, bson.E{Key: "_id", Value: 0})
+			}
+
+			result = append(result, bson.D{{Key: "$project", Value: project}})
+		}
+
+		result = append(result, stage)
+	}
+
+	return result
+}
+
+// OptimizePipeline rewrites a pipeline such that it does less work for the
+// same result: conditions that do not use joined documents are checked before
+// the $lookup, empty $match stages are dropped and adjacent ones merged, and a
+// $project before the $lookup removes all fields that later stages do not
+// need.
+func OptimizePipeline(p mongo.Pipeline) mongo.Pipeline {
+	return pushProjects(mergeMatches(pushMatches(p)))
+}
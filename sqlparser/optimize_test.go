@@ -0,0 +1,556 @@
+package sqlparser
+
+import (
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// The pipelines are checked by running them with a small evaluator over
+// sample collections, which implements only the stages, operators and
+// accumulators this package generates, in the way mongoDB does.
+
+// testCollections are the sample documents of the tables of testDDL.
+var testCollections = map[string][]bson.D{
+	"DEPT": {
+		{{Key: "_id", Value: bson.D{{Key: "ID", Value: int64(1)}}},
+			{Key: "NAME", Value: "Sales"}, {Key: "CODE", Value: "X"}},
+		{{Key: "_id", Value: bson.D{{Key: "ID", Value: int64(2)}}},
+			{Key: "NAME", Value: "IT"}, {Key: "CODE", Value: "Y"}},
+		{{Key: "_id", Value: bson.D{{Key: "ID", Value: int64(3)}}},
+			{Key: "NAME", Value: "HR"}, {Key: "CODE", Value: "X"}},
+	},
+	"EMP": {
+		{{Key: "_id", Value: bson.D{{Key: "ID", Value: int64(1)}}},
+			{Key: "NAME", Value: "A"}, {Key: "SALARY", Value: 5.5},
+			{Key: "DEPT_ID", Value: int64(1)}},
+		{{Key: "_id", Value: bson.D{{Key: "ID", Value: int64(2)}}},
+			{Key: "NAME", Value: "B"}, {Key: "SALARY", Value: int64(20)},
+			{Key: "DEPT_ID", Value: int64(1)}},
+		{{Key: "_id", Value: bson.D{{Key: "ID", Value: int64(3)}}},
+			{Key: "NAME", Value: "C"}, {Key: "SALARY", Value: int64(30)},
+			{Key: "DEPT_ID", Value: int64(2)}},
+		{{Key: "_id", Value: bson.D{{Key: "ID", Value: int64(4)}}},
+			{Key: "NAME", Value: nil}, {Key: "SALARY", Value: int64(1000)},
+			{Key: "DEPT_ID", Value: int64(3)}},
+		{{Key: "_id", Value: bson.D{{Key: "ID", Value: int64(5)}}},
+			{Key: "NAME", Value: "E"}, {Key: "SALARY", Value: nil},
+			{Key: "DEPT_ID", Value: nil}},
+	},
+}
+
+// lookupPath gets the value of a dotted path in a document.
+func lookupPath(doc bson.D, path string) (any, bool) {
+	key, rest, nested := strings.Cut(path, ".")
+
+	for _, e := range doc {
+		if e.Key != key {
+			continue
+		}
+
+		if !nested {
+			return e.Value, true
+		}
+
+		sub, ok := e.Value.(bson.D)
+		if !ok {
+			return nil, false
+		}
+		return lookupPath(sub, rest)
+	}
+
+	return nil, false
+}
+
+// toNumber converts the numbers in the sample collections and pipelines to
+// float64.
+func toNumber(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+
+	return 0, false
+}
+
+// compareValues compares two values like mongoDB does for the types in the
+// sample collections, returning false if they cannot be compared.
+func compareValues(a, b any) (int, bool) {
+	if a == nil || b == nil {
+		return 0, a == nil && b == nil
+	}
+
+	if x, ok := toNumber(a); ok {
+		y, ok := toNumber(b)
+		switch {
+		case !ok:
+			return 0, false
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+		return 0, true
+	}
+
+	x, ok := a.(string)
+	y, ok2 := b.(string)
+	if !ok || !ok2 {
+		return 0, false
+	}
+
+	return strings.Compare(x, y), true
+}
+
+// matchesOperator evaluates a query operator over the value of a field.
+func matchesOperator(t *testing.T, op string, v, arg any) bool {
+	c, ok := compareValues(v, arg)
+
+	switch op {
+	case "$eq":
+		return ok && c == 0
+	case "$ne":
+		return !ok || c != 0
+	case "$gt":
+		return ok && c > 0
+	case "$gte":
+		return ok && c >= 0
+	case "$lt":
+		return ok && c < 0
+	case "$lte":
+		return ok && c <= 0
+	case "$in", "$nin":
+		values, _ := asArray(arg)
+		in := false
+		for _, a := range values {
+			in = in || matchesOperator(t, "$eq", v, a)
+		}
+		return in == (op == "$in")
+	}
+
+	t.Fatalf("unsupported query operator %s", op)
+	return false
+}
+
+// matches evaluates a $match filter over a document.
+func matches(t *testing.T, doc, filter bson.D) bool {
+	for _, e := range filter {
+		switch e.Key {
+		case "$and", "$or":
+			subs, _ := asArray(e.Value)
+			some := false
+			all := true
+			for _, sub := range subs {
+				m := matches(t, doc, sub.(bson.D))
+				some = some || m
+				all = all && m
+			}
+
+			if e.Key == "$and" && !all || e.Key == "$or" && !some {
+				return false
+			}
+			continue
+		}
+
+		v, _ := lookupPath(doc, e.Key)
+
+		ops, ok := e.Value.(bson.D)
+		if !ok {
+			ops = bson.D{{Key: "$eq", Value: e.Value}}
+		}
+
+		for _, op := range ops {
+			if !matchesOperator(t, op.Key, v, op.Value) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// evaluate evaluates an aggregation expression over a document.
+func evaluate(t *testing.T, doc bson.D, expr any) any {
+	switch e := expr.(type) {
+	case string:
+		if strings.HasPrefix(e, "$") {
+			v, _ := lookupPath(doc, e[1:])
+			return v
+		}
+		return e
+
+	case bson.D:
+		if len(e) == 1 && strings.HasPrefix(e[0].Key, "$") {
+			args, _ := asArray(e[0].Value)
+
+			switch e[0].Key {
+			case "$literal":
+				return e[0].Value
+			case "$ne":
+				c, ok := compareValues(
+					evaluate(t, doc, args[0]), evaluate(t, doc, args[1]),
+				)
+				return !ok || c != 0
+			case "$cond":
+				if evaluate(t, doc, args[0]) == true {
+					return evaluate(t, doc, args[1])
+				}
+				return evaluate(t, doc, args[2])
+			}
+
+			t.Fatalf("unsupported expression operator %s", e[0].Key)
+		}
+
+		result := bson.D{}
+		for _, f := range e {
+			result = append(result,
+				bson.E{Key: f.Key, Value: evaluate(t, doc, f.Value)},
+			)
+		}
+		return result
+	}
+
+	return expr
+}
+
+// project applies an inclusion projection over a document, where paths are
+// the included paths relative to the document.
+func project(doc bson.D, paths []string) bson.D {
+	result := bson.D{}
+
+	for _, e := range doc {
+		sub := []string{}
+		whole := false
+		for _, path := range paths {
+			key, rest, nested := strings.Cut(path, ".")
+			if key != e.Key {
+				continue
+			}
+
+			whole = whole || !nested
+			sub = append(sub, rest)
+		}
+
+		switch d, ok := e.Value.(bson.D); {
+		case whole:
+			result = append(result, e)
+		case len(sub) != 0 && ok:
+			result = append(result, bson.E{Key: e.Key, Value: project(d, sub)})
+		}
+	}
+
+	return result
+}
+
+// accumulate evaluates a $group accumulator over the documents of a group.
+func accumulate(t *testing.T, docs []bson.D, acc bson.D) any {
+	op, arg := acc[0].Key, acc[0].Value
+
+	if op == "$count" {
+		return int64(len(docs))
+	}
+
+	var result any
+	var sum float64
+	n := 0
+	integer := true
+	for _, doc := range docs {
+		// like in mongoDB, the accumulators ignore nulls and non numbers
+		v := evaluate(t, doc, arg)
+		if v == nil {
+			continue
+		}
+
+		switch op {
+		case "$min":
+			if c, ok := compareValues(v, result); !ok || c < 0 {
+				result = v
+			}
+		case "$max":
+			if c, ok := compareValues(v, result); !ok || c > 0 {
+				result = v
+			}
+		case "$sum", "$avg":
+			if x, ok := toNumber(v); ok {
+				_, isFloat := v.(float64)
+				integer = integer && !isFloat
+				sum += x
+				n++
+			}
+		default:
+			t.Fatalf("unsupported accumulator %s", op)
+		}
+	}
+
+	switch op {
+	case "$sum":
+		if integer {
+			return int64(sum)
+		}
+		return sum
+	case "$avg":
+		if n == 0 {
+			return nil
+		}
+		return sum / float64(n)
+	}
+
+	return result
+}
+
+// run runs a pipeline over a sample collection, ignoring its final $merge or
+// $out stage.
+func run(t *testing.T, collection string, p mongo.Pipeline) []bson.D {
+	docs := testCollections[collection]
+
+	for _, stage := range p {
+		op, v := stageOf(stage)
+		d, _ := v.(bson.D)
+		result := []bson.D{}
+
+		switch op {
+		case "$match":
+			for _, doc := range docs {
+				if matches(t, doc, d) {
+					result = append(result, doc)
+				}
+			}
+
+		case "$lookup":
+			from, _ := lookupPath(d, "from")
+			local, _ := lookupPath(d, "localField")
+			foreign, _ := lookupPath(d, "foreignField")
+			as := lookupAs(stage)
+
+			for _, doc := range docs {
+				lv, _ := lookupPath(doc, local.(string))
+				joined := bson.A{}
+				for _, other := range testCollections[from.(string)] {
+					fv, _ := lookupPath(other, foreign.(string))
+					if c, ok := compareValues(lv, fv); ok && c == 0 {
+						joined = append(joined, other)
+					}
+				}
+
+				result = append(result,
+					append(doc[:len(doc):len(doc)], bson.E{Key: as, Value: joined}),
+				)
+			}
+
+		case "$unwind":
+			field := strings.TrimPrefix(v.(string), "$")
+			for _, doc := range docs {
+				values, _ := lookupPath(doc, field)
+				for _, value := range values.(bson.A) {
+					unwound := bson.D{}
+					for _, e := range doc {
+						if e.Key == field {
+							e.Value = value
+						}
+						unwound = append(unwound, e)
+					}
+					result = append(result, unwound)
+				}
+			}
+
+		case "$project":
+			paths := []string{"_id"}
+			for _, e := range d {
+				if e.Key == "_id" || strings.HasPrefix(e.Key, "_id.") {
+					paths = paths[1:]
+				}
+				if e.Value != 0 {
+					paths = append(paths, e.Key)
+				}
+			}
+
+			for _, doc := range docs {
+				result = append(result, project(doc, paths))
+			}
+
+		case "$group":
+			if len(docs) == 0 {
+				break
+			}
+
+			group := bson.D{{Key: "_id", Value: nil}}
+			for _, e := range d[1:] {
+				group = append(group, bson.E{
+					Key: e.Key, Value: accumulate(t, docs, e.Value.(bson.D)),
+				})
+			}
+			result = append(result, group)
+
+		case "$replaceWith":
+			for _, doc := range docs {
+				result = append(result, evaluate(t, doc, d).(bson.D))
+			}
+
+		case "$merge", "$out":
+			result = docs
+
+		default:
+			t.Fatalf("unsupported stage %s", op)
+		}
+
+		docs = result
+	}
+
+	return docs
+}
+
+// docsJSON gets documents in relaxed extended JSON, to be compared in tests.
+func docsJSON(t *testing.T, docs []bson.D) string {
+	t.Helper()
+	return pipelineJSON(t, docs)
+}
+
+func TestOptimizePipeline(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{
+			sql: "SELECT NAME, AVG(SALARY) FROM EMP JOIN DEPT " +
+				"ON ID = DEPT_ID WHERE CODE = 'X';",
+			want: `[{"AVG(SALARY)":341.8333333333333}]`,
+		},
+		{
+			sql: "SELECT CODE FROM EMP JOIN DEPT ON ID = DEPT_ID " +
+				"WHERE SALARY > 10 AND CODE = 'X';",
+			want: `[{"DEPT":{"CODE":"X"}},{"DEPT":{"CODE":"X"}}]`,
+		},
+		{
+			sql: "SELECT * FROM EMP JOIN DEPT ON ID = DEPT_ID " +
+				"WHERE SALARY > 10 OR CODE = 'X';",
+		},
+		{
+			sql: "SELECT NAME FROM EMP JOIN DEPT ON ID = DEPT_ID " +
+				"WHERE NAME <> 'IT' AND DEPT_ID IN (1, 2);",
+		},
+		{
+			sql: "SELECT SUM(SALARY), MIN(NAME), COUNT(NAME) FROM EMP " +
+				"WHERE SALARY < 1000 OR NAME IS NULL;",
+			want: `[{"SUM(SALARY)":1055.5,"MIN(NAME)":"A","COUNT(NAME)":3}]`,
+		},
+		{
+			sql: "SELECT NAME, SALARY FROM EMP WHERE ID NOT IN (1, 3);",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.sql, func(t *testing.T) {
+			c := testCatalog(t)
+			stmt := analyzed(t, test.sql)
+
+			p, err := stmt.pipeline(c)
+			if err != nil {
+				t.Fatalf("pipeline failed: %v", err)
+			}
+
+			optimized := OptimizePipeline(p)
+
+			want := docsJSON(t, run(t, stmt.FromTable, p))
+			got := docsJSON(t, run(t, stmt.FromTable, optimized))
+			if got != want {
+				t.Errorf("optimized pipeline %s\ngot\n%s\nwant\n%s",
+					pipelineJSON(t, optimized), got, want,
+				)
+			}
+
+			if test.want != "" && want != test.want {
+				t.Errorf("pipeline %s\ngot\n%s\nwant\n%s",
+					pipelineJSON(t, p), want, test.want,
+				)
+			}
+
+			again := pipelineJSON(t, OptimizePipeline(optimized))
+			if again != pipelineJSON(t, optimized) {
+				t.Errorf("OptimizePipeline is not idempotent, got\n%s", again)
+			}
+		})
+	}
+}
+
+func TestOptimizeInsertPipeline(t *testing.T) {
+	tests := []string{
+		"INSERT INTO BONUS (EMP_ID, AMOUNT) " +
+			"SELECT ID, SALARY FROM EMP WHERE SALARY > 10;",
+		"INSERT INTO DEPT (ID, NAME) SELECT ID, NAME FROM EMP " +
+			"JOIN DEPT ON ID = DEPT_ID WHERE SALARY > 10 AND CODE = 'X';",
+	}
+
+	for _, sql := range tests {
+		t.Run(sql, func(t *testing.T) {
+			c := testCatalog(t)
+
+			ins, err := ParseInsert(sql)
+			if err != nil {
+				t.Fatalf("ParseInsert failed: %v", err)
+			}
+			if err = ins.Analyze(c); err != nil {
+				t.Fatalf("Analyze failed: %v", err)
+			}
+
+			p, err := ins.insertPipeline(c)
+			if err != nil {
+				t.Fatalf("insertPipeline failed: %v", err)
+			}
+
+			want := docsJSON(t, run(t, ins.Select.FromTable, p))
+			got := docsJSON(t, run(t, ins.Select.FromTable, OptimizePipeline(p)))
+			if got != want {
+				t.Errorf("optimized pipeline got\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestConjuncts(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter bson.D
+		want   string
+	}{
+		{
+			name:   "flat",
+			filter: bson.D{{Key: "A", Value: 1}, {Key: "B", Value: 2}},
+			want:   `[{"A":1},{"B":2}]`,
+		},
+		{
+			name: "nested $and",
+			filter: bson.D{{Key: "$and", Value: bson.A{
+				bson.D{{Key: "A", Value: 1}},
+				bson.D{{Key: "$and", Value: bson.A{
+					bson.D{{Key: "B", Value: 2}}, bson.D{{Key: "C", Value: 3}},
+				}}},
+			}}},
+			want: `[{"A":1},{"B":2},{"C":3}]`,
+		},
+		{
+			name: "$and with a value that is not a document",
+			filter: bson.D{
+				{Key: "$and", Value: bson.A{bson.D{{Key: "A", Value: 1}}, "x"}},
+				{Key: "B", Value: 2},
+			},
+			want: `[{"$and":[{"A":1},"x"]},{"B":2}]`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := pipelineJSON(t, conjuncts(test.filter))
+			if got != test.want {
+				t.Errorf("conjuncts got\n%s\nwant\n%s", got, test.want)
+			}
+		})
+	}
+}
@@ -16,12 +16,28 @@ import (
 func Parse(sql string) (*Statement, error) {
 	l := NewLexer(strings.NewReader(sql))
 
-	stmt := &Statement{}
-
 	if !l.Lex() {
 		return nil, fmt.Errorf("failed parsing any SQL text")
 	}
 
+	stmt, err := parseStatement(l)
+	if err != nil {
+		return nil, err
+	}
+
+	if l.Lex() {
+		return nil, fmt.Errorf("failed parsing SQL end: there is trailing input")
+	}
+
+	return stmt, nil
+}
+
+// parseStatement parses a query starting at the current token of the lexer,
+// stopping at the first token after it, such that queries can be used both as
+// whole statements and inside other ones.
+func parseStatement(l *Lexer) (*Statement, error) {
+	stmt := &Statement{}
+
 	if !SelectStmt(l, stmt) {
 		return nil, fmt.Errorf("failed parsing SQL SELECT")
 	}
@@ -38,10 +54,6 @@ func Parse(sql string) (*Statement, error) {
 		return nil, fmt.Errorf("failed parsing SQL WHERE")
 	}
 
-	if l.Lex() {
-		return nil, fmt.Errorf("failed parsing SQL end: there is trailing input")
-	}
-
 	return stmt, nil
 }
 
@@ -382,3 +394,342 @@ func CompExpr(l *Lexer, be *BooleanExpression) bool {
 
 	return false
 }
+
+// ParseInsert parses an INSERT INTO ... SELECT statement, returning the
+// InsertStatement that describes it.
+//
+// ParseInsert -> <INSERT> <INTO> <ID> OptIdList Statement
+// OptIdList -> <(> IdList <)> | eps
+func ParseInsert(sql string) (*InsertStatement, error) {
+	l := NewLexer(strings.NewReader(sql))
+
+	if !l.Lex() {
+		return nil, fmt.Errorf("failed parsing any SQL text")
+	}
+
+	if strings.ToUpper(l.Value) != "INSERT" || !l.Lex() ||
+		strings.ToUpper(l.Value) != "INTO" || !l.Lex() ||
+		l.Token != scanner.Ident {
+		return nil, fmt.Errorf("failed parsing SQL INSERT INTO")
+	}
+
+	ins := &InsertStatement{IntoTable: l.Value}
+
+	if !l.Lex() {
+		return nil, fmt.Errorf("failed parsing SQL INSERT INTO")
+	}
+
+	if l.Value == "(" {
+		if !l.Lex() || !IdList(l, &ins.IntoColumns) || l.Value != ")" ||
+			!l.Lex() {
+			return nil, fmt.Errorf("failed parsing SQL INSERT column list")
+		}
+	}
+
+	stmt, err := parseStatement(l)
+	if err != nil {
+		return nil, err
+	}
+	ins.Select = stmt
+
+	if l.Lex() {
+		return nil, fmt.Errorf("failed parsing SQL end: there is trailing input")
+	}
+
+	return ins, nil
+}
+
+// ParseMerge parses an oracle MERGE statement, returning the MergeStatement
+// that describes it.
+//
+// ParseMerge -> <MERGE> <INTO> <ID> OptAlias <USING> MergeSource OptAlias
+//
+//	<ON> <(> MergeOn <)> MergeClause { MergeClause }
+//
+// MergeSource -> <ID> | <(> Statement <)>
+// OptAlias -> <ID> | eps
+func ParseMerge(sql string) (*MergeStatement, error) {
+	l := NewLexer(strings.NewReader(sql))
+
+	if !l.Lex() {
+		return nil, fmt.Errorf("failed parsing any SQL text")
+	}
+
+	if strings.ToUpper(l.Value) != "MERGE" || !l.Lex() ||
+		strings.ToUpper(l.Value) != "INTO" || !l.Lex() ||
+		l.Token != scanner.Ident {
+		return nil, fmt.Errorf("failed parsing SQL MERGE INTO")
+	}
+
+	merge := &MergeStatement{IntoTable: l.Value}
+
+	if !l.Lex() || !OptAlias(l, &merge.IntoAlias, "USING") {
+		return nil, fmt.Errorf("failed parsing SQL MERGE INTO")
+	}
+
+	if strings.ToUpper(l.Value) != "USING" || !l.Lex() {
+		return nil, fmt.Errorf("failed parsing SQL USING")
+	}
+
+	if l.Value == "(" {
+		if !l.Lex() {
+			return nil, fmt.Errorf("failed parsing SQL USING")
+		}
+
+		stmt, err := parseStatement(l)
+		if err != nil {
+			return nil, err
+		}
+		merge.UsingSelect = stmt
+
+		if l.Value != ")" {
+			return nil, fmt.Errorf("failed parsing SQL USING: missing )")
+		}
+	} else if l.Token == scanner.Ident {
+		merge.UsingTable = l.Value
+	} else {
+		return nil, fmt.Errorf("failed parsing SQL USING")
+	}
+
+	if !l.Lex() || !OptAlias(l, &merge.UsingAlias, "ON") {
+		return nil, fmt.Errorf("failed parsing SQL USING")
+	}
+
+	if strings.ToUpper(l.Value) != "ON" || !l.Lex() || l.Value != "(" ||
+		!l.Lex() || !MergeOn(l, merge) || l.Value != ")" || !l.Lex() {
+		return nil, fmt.Errorf("failed parsing SQL ON")
+	}
+
+	if !MergeClause(l, merge) {
+		return nil, fmt.Errorf("failed parsing SQL WHEN")
+	}
+
+	for strings.ToUpper(l.Value) == "WHEN" {
+		if !MergeClause(l, merge) {
+			return nil, fmt.Errorf("failed parsing SQL WHEN")
+		}
+	}
+
+	if l.Value == ";" {
+		l.Lex()
+	}
+
+	if l.Token != scanner.EOF {
+		return nil, fmt.Errorf("failed parsing SQL end: there is trailing input")
+	}
+
+	return merge, nil
+}
+
+// OptAlias reads an alias for a table, if the current token is not the keyword
+// that should come after the alias.
+func OptAlias(l *Lexer, alias *string, next string) bool {
+	if l.Token != scanner.Ident || strings.ToUpper(l.Value) == next {
+		return true
+	}
+
+	*alias = l.Value
+	return l.Lex()
+}
+
+// IdList -> <ID> { <,> <ID> }
+func IdList(l *Lexer, ids *[]string) bool {
+	for {
+		if l.Token != scanner.Ident {
+			return false
+		}
+
+		*ids = append(*ids, l.Value)
+
+		if !l.Lex() {
+			return false
+		}
+
+		if l.Value != "," {
+			return true
+		}
+
+		if !l.Lex() {
+			return false
+		}
+	}
+}
+
+// QualifiedId -> <ID> { <.> <ID> }
+//
+// QualifiedId returns the qualifier of the identifier (such as the table alias
+// in "T.A") and the identifier itself.
+func QualifiedId(l *Lexer) (string, string, bool) {
+	qualifier, id := "", l.Value
+	if l.Token != scanner.Ident {
+		return "", "", false
+	}
+
+	// the identifier can be the last token in the statement
+	l.Lex()
+
+	for l.Value == "." {
+		if !l.Lex() || l.Token != scanner.Ident {
+			return "", "", false
+		}
+
+		qualifier, id = id, l.Value
+		l.Lex()
+	}
+
+	return qualifier, id, true
+}
+
+// MergeValue -> QualifiedId | <INT> | <NULL>
+//
+// MergeValue reads a value from the merge source: either a column or a literal.
+func MergeValue(l *Lexer, a *Assignment) bool {
+	if l.Token == scanner.Int || strings.HasPrefix(l.Value, "'") ||
+		strings.ToUpper(l.Value) == "NULL" {
+
+		a.Value = GetValue(l.Value)
+		l.Lex()
+		return true
+	}
+
+	_, id, ok := QualifiedId(l)
+	a.SourceColumn = id
+	return ok
+}
+
+// MergeOn -> QualifiedId <=> QualifiedId { <AND> QualifiedId <=> QualifiedId }
+func MergeOn(l *Lexer, merge *MergeStatement) bool {
+	for {
+		q1, id1, ok := QualifiedId(l)
+		if !ok || l.Value != "=" || !l.Lex() {
+			return false
+		}
+
+		q2, id2, ok := QualifiedId(l)
+		if !ok {
+			return false
+		}
+
+		// the target column is the one qualified by the target table, or the
+		// first one if none are qualified by it
+		if q2 != "" && (strings.EqualFold(q2, merge.IntoAlias) ||
+			strings.EqualFold(q2, merge.IntoTable)) {
+			id1, id2 = id2, id1
+		} else if q1 != "" && (strings.EqualFold(q1, merge.UsingAlias) ||
+			strings.EqualFold(q1, merge.UsingTable)) {
+			id1, id2 = id2, id1
+		}
+
+		merge.On = append(merge.On, Assignment{Column: id1, SourceColumn: id2})
+
+		if strings.ToUpper(l.Value) != "AND" {
+			return true
+		}
+
+		if !l.Lex() {
+			return false
+		}
+	}
+}
+
+// MergeClause -> <WHEN> <MATCHED> <THEN> <UPDATE> <SET> SetList
+//
+//	| <WHEN> <NOT> <MATCHED> <THEN> <INSERT> <(> IdList <)>
+//	  <VALUES> <(> MergeValue { <,> MergeValue } <)>
+//
+// SetList -> QualifiedId <=> MergeValue { <,> QualifiedId <=> MergeValue }
+func MergeClause(l *Lexer, merge *MergeStatement) bool {
+	if strings.ToUpper(l.Value) != "WHEN" || !l.Lex() {
+		return false
+	}
+
+	not := false
+	if strings.ToUpper(l.Value) == "NOT" {
+		not = true
+		if !l.Lex() {
+			return false
+		}
+	}
+
+	if strings.ToUpper(l.Value) != "MATCHED" || !l.Lex() ||
+		strings.ToUpper(l.Value) != "THEN" || !l.Lex() {
+		return false
+	}
+
+	if !not {
+		if merge.MatchedUpdate != nil || strings.ToUpper(l.Value) != "UPDATE" ||
+			!l.Lex() || strings.ToUpper(l.Value) != "SET" || !l.Lex() {
+			return false
+		}
+
+		for {
+			a := Assignment{}
+
+			_, id, ok := QualifiedId(l)
+			if !ok || l.Value != "=" || !l.Lex() || !MergeValue(l, &a) {
+				return false
+			}
+
+			a.Column = id
+			merge.MatchedUpdate = append(merge.MatchedUpdate, a)
+
+			if l.Value != "," {
+				return true
+			}
+
+			if !l.Lex() {
+				return false
+			}
+		}
+	}
+
+	if merge.NotMatchedInsert != nil || strings.ToUpper(l.Value) != "INSERT" ||
+		!l.Lex() || l.Value != "(" || !l.Lex() {
+		return false
+	}
+
+	columns := []string{}
+	for {
+		_, id, ok := QualifiedId(l)
+		if !ok {
+			return false
+		}
+
+		columns = append(columns, id)
+
+		if l.Value != "," {
+			break
+		}
+
+		if !l.Lex() {
+			return false
+		}
+	}
+
+	if l.Value != ")" || !l.Lex() || strings.ToUpper(l.Value) != "VALUES" ||
+		!l.Lex() || l.Value != "(" || !l.Lex() {
+		return false
+	}
+
+	for i, column := range columns {
+		a := Assignment{Column: column}
+
+		if !MergeValue(l, &a) {
+			return false
+		}
+
+		merge.NotMatchedInsert = append(merge.NotMatchedInsert, a)
+
+		if i != len(columns)-1 && (l.Value != "," || !l.Lex()) {
+			return false
+		}
+	}
+
+	if l.Value != ")" {
+		return false
+	}
+
+	// the clause can be the last thing in the statement
+	l.Lex()
+	return true
+}
@@ -0,0 +1,199 @@
+package sqlparser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// valueString converts a value from GetValue back to its SQL form.
+func valueString(v any) string {
+	switch vv := v.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return "'" + strings.ReplaceAll(vv, "'", "''") + "'"
+	}
+
+	return fmt.Sprint(v)
+}
+
+// String gets the SQL for a Column.
+func (col Column) String() string {
+	if col.GroupFunction == "" {
+		return col.Name
+	}
+
+	return col.GroupFunction + "(" + col.Name + ")"
+}
+
+// String implements the BooleanExpression interface. An EmptyComparision has
+// no SQL form, so it is an empty string.
+func (e EmptyComparision) String() string {
+	return ""
+}
+
+// String implements the BooleanExpression interface.
+func (c *Comparision) String() string {
+	if c.Value == nil && c.Op == "=" {
+		return c.Id + " IS NULL"
+	} else if c.Value == nil && c.Op == "<>" {
+		return c.Id + " IS NOT NULL"
+	}
+
+	return c.Id + " " + c.Op + " " + valueString(c.Value)
+}
+
+// String implements the BooleanExpression interface.
+func (ic *InComparision) String() string {
+	values := []string{}
+	for _, v := range ic.Values {
+		values = append(values, valueString(v))
+	}
+
+	not := ""
+	if ic.Not {
+		not = "NOT "
+	}
+
+	return ic.Id + " " + not + "IN (" + strings.Join(values, ", ") + ")"
+}
+
+// String implements the BooleanExpression interface. Sub expressions that are
+// composite themselves are always in parenthesis, as the parser requires them
+// to mix boolean operators.
+func (bc *BooleanComposite) String() string {
+	subs := []string{}
+	for _, se := range bc.SubExpr {
+		if _, ok := se.(*BooleanComposite); ok {
+			subs = append(subs, "("+se.String()+")")
+			continue
+		}
+
+		subs = append(subs, se.String())
+	}
+
+	return strings.Join(subs, " "+strings.ToUpper(bc.BoolOp)+" ")
+}
+
+// query gets the SQL for a Statement without the final semicolon, so that it
+// can be used inside other statements.
+func (stmt *Statement) query() string {
+	s := "SELECT "
+
+	if len(stmt.SelectColumn) == 0 {
+		s += "*"
+	} else {
+		cols := []string{}
+		for _, col := range stmt.SelectColumn {
+			cols = append(cols, col.String())
+		}
+
+		s += strings.Join(cols, ", ")
+	}
+
+	s += " FROM " + stmt.FromTable
+
+	if stmt.JoinTable != "" {
+		s += " JOIN " + stmt.JoinTable + " ON " + stmt.JoinToAttr + " = " +
+			stmt.JoinFromAttr
+	}
+
+	if stmt.Where != nil && stmt.Where.String() != "" {
+		s += " WHERE " + stmt.Where.String()
+	}
+
+	return s
+}
+
+// String gets the canonical SQL for a Statement, that parses back to the same
+// Statement.
+func (stmt *Statement) String() string {
+	return stmt.query() + ";"
+}
+
+// String gets the canonical SQL for an InsertStatement.
+func (ins *InsertStatement) String() string {
+	s := "INSERT INTO " + ins.IntoTable + " "
+
+	if len(ins.IntoColumns) != 0 {
+		s += "(" + strings.Join(ins.IntoColumns, ", ") + ") "
+	}
+
+	return s + ins.Select.String()
+}
+
+// qualify gets a column name qualified by a table alias, or by the table name
+// if there is no alias.
+func qualify(alias, table, column string) string {
+	if alias != "" {
+		return alias + "." + column
+	}
+
+	if table != "" {
+		return table + "." + column
+	}
+
+	return column
+}
+
+// String gets the canonical SQL for a MergeStatement. All columns are
+// qualified by their tables, so that the sides of the ON condition are kept
+// when parsed again.
+func (merge *MergeStatement) String() string {
+	target := func(col string) string {
+		return qualify(merge.IntoAlias, merge.IntoTable, col)
+	}
+
+	source := func(a Assignment) string {
+		if a.SourceColumn == "" {
+			return valueString(a.Value)
+		}
+
+		return qualify(merge.UsingAlias, merge.UsingTable, a.SourceColumn)
+	}
+
+	s := "MERGE INTO " + merge.IntoTable
+	if merge.IntoAlias != "" {
+		s += " " + merge.IntoAlias
+	}
+
+	s += " USING "
+	if merge.UsingSelect != nil {
+		s += "(" + merge.UsingSelect.query() + ")"
+	} else {
+		s += merge.UsingTable
+	}
+
+	if merge.UsingAlias != "" {
+		s += " " + merge.UsingAlias
+	}
+
+	on := []string{}
+	for _, a := range merge.On {
+		on = append(on, target(a.Column)+" = "+source(a))
+	}
+	s += " ON (" + strings.Join(on, " AND ") + ")"
+
+	if merge.MatchedUpdate != nil {
+		set := []string{}
+		for _, a := range merge.MatchedUpdate {
+			set = append(set, target(a.Column)+" = "+source(a))
+		}
+
+		s += " WHEN MATCHED THEN UPDATE SET " + strings.Join(set, ", ")
+	}
+
+	if merge.NotMatchedInsert != nil {
+		columns := []string{}
+		values := []string{}
+		for _, a := range merge.NotMatchedInsert {
+			columns = append(columns, target(a.Column))
+			values = append(values, source(a))
+		}
+
+		s += " WHEN NOT MATCHED THEN INSERT (" + strings.Join(columns, ", ") +
+			") VALUES (" + strings.Join(values, ", ") + ")"
+	}
+
+	return s + ";"
+}
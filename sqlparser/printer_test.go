@@ -0,0 +1,181 @@
+package sqlparser
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestStatementString(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{"SELECT * FROM EMP;", "SELECT * FROM EMP;"},
+		{
+			"select Name, count(ID) from EMP where SALARY >= 10 AND " +
+				"NAME IS NOT NULL;",
+			"SELECT Name, count(ID) FROM EMP WHERE SALARY >= 10 AND " +
+				"NAME IS NOT NULL;",
+		},
+		{
+			"SELECT * FROM EMP JOIN DEPT ON ID = DEPT_ID WHERE CODE = 'X' " +
+				"OR (ID NOT IN (1, 2) AND NAME IS NULL);",
+			"SELECT * FROM EMP JOIN DEPT ON ID = DEPT_ID WHERE CODE = 'X' " +
+				"OR (ID NOT IN (1, 2) AND NAME IS NULL);",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.sql, func(t *testing.T) {
+			stmt, err := Parse(test.sql)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+
+			if got := stmt.String(); got != test.want {
+				t.Fatalf("String got\n%s\nwant\n%s", got, test.want)
+			}
+
+			again, err := Parse(stmt.String())
+			if err != nil {
+				t.Fatalf("Parse of String failed: %v", err)
+			}
+			if !reflect.DeepEqual(again, stmt) {
+				t.Errorf("Parse of String got\n%#v\nwant\n%#v", again, stmt)
+			}
+		})
+	}
+}
+
+func TestInsertMergeString(t *testing.T) {
+	tests := []string{
+		"INSERT INTO BONUS (EMP_ID, AMOUNT) SELECT ID, SALARY FROM EMP " +
+			"WHERE SALARY > 10;",
+		"INSERT INTO BONUS SELECT * FROM EMP;",
+		"MERGE INTO DEPT D USING EMP E ON (D.ID = E.DEPT_ID) " +
+			"WHEN MATCHED THEN UPDATE SET D.NAME = E.NAME, D.CODE = NULL " +
+			"WHEN NOT MATCHED THEN INSERT (D.ID, D.NAME) " +
+			"VALUES (E.DEPT_ID, 'new');",
+		"MERGE INTO BONUS USING (SELECT ID FROM EMP WHERE SALARY > 10) E " +
+			"ON (BONUS.EMP_ID = E.ID) WHEN MATCHED THEN UPDATE SET " +
+			"BONUS.AMOUNT = 0;",
+	}
+
+	for _, sql := range tests {
+		t.Run(sql, func(t *testing.T) {
+			var got string
+			if ins, err := ParseInsert(sql); err == nil {
+				got = ins.String()
+			} else if merge, err := ParseMerge(sql); err == nil {
+				got = merge.String()
+			} else {
+				t.Fatalf("ParseMerge failed: %v", err)
+			}
+
+			if got != sql {
+				t.Errorf("String got\n%s\nwant\n%s", got, sql)
+			}
+		})
+	}
+}
+
+func TestStatementJSON(t *testing.T) {
+	tests := []string{
+		"SELECT * FROM EMP;",
+		"SELECT NAME, AVG(SALARY) FROM EMP JOIN DEPT ON ID = DEPT_ID " +
+			"WHERE CODE = 'X' AND (SALARY > 10 OR ID IN (1, 'A', NULL));",
+	}
+
+	for _, sql := range tests {
+		t.Run(sql, func(t *testing.T) {
+			stmt, err := Parse(sql)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+
+			data, err := json.Marshal(stmt)
+			if err != nil {
+				t.Fatalf("Marshal failed: %v", err)
+			}
+
+			got := &Statement{}
+			err = json.Unmarshal(data, got)
+			if err != nil {
+				t.Fatalf("Unmarshal of %s failed: %v", data, err)
+			}
+
+			if !reflect.DeepEqual(got, stmt) {
+				t.Errorf("Unmarshal of %s got\n%#v\nwant\n%#v", data, got, stmt)
+			}
+		})
+	}
+}
+
+func TestMergeJSON(t *testing.T) {
+	merge, err := ParseMerge("MERGE INTO DEPT D USING EMP E " +
+		"ON (D.ID = E.DEPT_ID) WHEN MATCHED THEN UPDATE SET D.CODE = 'X' " +
+		"WHEN NOT MATCHED THEN INSERT (ID, NAME) VALUES (E.DEPT_ID, 7);",
+	)
+	if err != nil {
+		t.Fatalf("ParseMerge failed: %v", err)
+	}
+
+	data, err := json.Marshal(merge)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	got := &MergeStatement{}
+	err = json.Unmarshal(data, got)
+	if err != nil {
+		t.Fatalf("Unmarshal of %s failed: %v", data, err)
+	}
+
+	if !reflect.DeepEqual(got, merge) {
+		t.Errorf("Unmarshal of %s got\n%#v\nwant\n%#v", data, got, merge)
+	}
+}
+
+func TestUnmarshalBoolExpr(t *testing.T) {
+	tests := []struct {
+		data string
+		want BooleanExpression
+	}{
+		{"null", EmptyComparision{}},
+		{`{"Type":"EmptyComparision"}`, EmptyComparision{}},
+		{
+			`{"Type":"Comparision","Id":"A","Value":10,"Op":">"}`,
+			&Comparision{Id: "A", Value: int64(10), Op: ">"},
+		},
+		{
+			`{"Type":"InComparision","Id":"A","Not":true,"Values":[1.5,"x"]}`,
+			&InComparision{Id: "A", Not: true, Values: []any{1.5, "x"}},
+		},
+		{
+			`{"Type":"BooleanComposite","BoolOp":"OR","SubExpr":[` +
+				`{"Type":"Comparision","Id":"A","Value":null,"Op":"="}]}`,
+			&BooleanComposite{BoolOp: "OR", SubExpr: []BooleanExpression{
+				&Comparision{Id: "A", Op: "="},
+			}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.data, func(t *testing.T) {
+			got, err := UnmarshalBoolExpr([]byte(test.data))
+			if err != nil {
+				t.Fatalf("UnmarshalBoolExpr failed: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("UnmarshalBoolExpr got %#v, want %#v", got, test.want)
+			}
+		})
+	}
+
+	_, err := UnmarshalBoolExpr([]byte(`{"Type":"Unknown"}`))
+	if err == nil {
+		t.Errorf("UnmarshalBoolExpr of an unknown type did not fail")
+	}
+}
@@ -19,7 +19,8 @@ type Column struct {
 
 // struct Statement represents a parsed SQL statement, with selection columns,
 // a single origin table, a single (optional) joined table with a single join
-// condition, and a filtering expression.
+// condition, and a filtering expression. Warnings holds the notes of the
+// semantic analysis that do not prevent the conversion.
 type Statement struct {
 	SelectColumn []Column
 
@@ -30,13 +31,62 @@ type Statement struct {
 	JoinToAttr   string
 
 	Where BooleanExpression
+
+	Warnings []string
+}
+
+// struct Assignment represents a value given to a column of a target table,
+// such as "SET A = S.B" or an INSERT column paired with its VALUES entry. The
+// value is either a column from the source of the statement (SourceColumn) or
+// a literal (Value, when SourceColumn is empty).
+type Assignment struct {
+	Column       string
+	SourceColumn string
+	Value        any
+}
+
+// struct InsertStatement represents a parsed INSERT INTO ... SELECT statement,
+// with the target table, the optional target columns (in the same order as the
+// selection columns) and the source query.
+type InsertStatement struct {
+	IntoTable   string
+	IntoColumns []string
+
+	Select *Statement
+
+	Warnings []string
+}
+
+// struct MergeStatement represents a parsed oracle MERGE statement, with a
+// target table, a source that is either a table or a query, the join
+// conditions between them (target columns paired with source columns) and the
+// optional WHEN MATCHED THEN UPDATE and WHEN NOT MATCHED THEN INSERT clauses
+// (nil when not present).
+type MergeStatement struct {
+	IntoTable string
+	IntoAlias string
+
+	UsingTable  string
+	UsingSelect *Statement
+	UsingAlias  string
+
+	On []Assignment
+
+	MatchedUpdate    []Assignment
+	NotMatchedInsert []Assignment
+
+	Warnings []string
 }
 
 // A BooleanExpression represents a parsed boolean comparision that can be
-// converted to a mongoDB bson document given the tables related in the query
-// (for _id management and joined table reference).
+// converted to a mongoDB bson document given the catalog and the tables related
+// in the query (for _id management and joined table reference), as well as
+// back to SQL.
 type BooleanExpression interface {
-	GetBson(tableFrom, tableJoin string) (bson.D, error)
+	GetBson(
+		catalog oracleManager.Catalog, tableFrom, tableJoin string,
+	) (bson.D, error)
+	String() string
 }
 
 // struct EmptyComparision represents a comparision that is always true
@@ -83,12 +133,16 @@ func (stmt *Statement) IsAggregate() bool {
 }
 
 // GetBson implements the BooleanExpression interface.
-func (e EmptyComparision) GetBson(_, _ string) (bson.D, error) {
+func (e EmptyComparision) GetBson(
+	_ oracleManager.Catalog, _, _ string,
+) (bson.D, error) {
 	return bson.D{}, nil
 }
 
 // GetBson implements the BooleanExpression interface.
-func (c *Comparision) GetBson(tableFrom, tableJoin string) (bson.D, error) {
+func (c *Comparision) GetBson(
+	catalog oracleManager.Catalog, tableFrom, tableJoin string,
+) (bson.D, error) {
 	operator := ""
 
 	switch c.Op {
@@ -109,10 +163,10 @@ func (c *Comparision) GetBson(tableFrom, tableJoin string) (bson.D, error) {
 	}
 
 	var k string
-	if oracleManager.TableContainsColumn(tableJoin, c.Id) {
-		k = tableJoin + "." + keyManager.ToMongoId(tableJoin, c.Id)
+	if oracleManager.TableContainsColumn(catalog, tableJoin, c.Id) {
+		k = tableJoin + "." + keyManager.ToMongoId(catalog, tableJoin, c.Id)
 	} else {
-		k = keyManager.ToMongoId(tableFrom, c.Id)
+		k = keyManager.ToMongoId(catalog, tableFrom, c.Id)
 	}
 
 	return bson.D{{
@@ -123,17 +177,19 @@ func (c *Comparision) GetBson(tableFrom, tableJoin string) (bson.D, error) {
 }
 
 // GetBson implements the BooleanExpression interface.
-func (ic *InComparision) GetBson(tableFrom, tableJoin string) (bson.D, error) {
+func (ic *InComparision) GetBson(
+	catalog oracleManager.Catalog, tableFrom, tableJoin string,
+) (bson.D, error) {
 	operator := "$in"
 	if ic.Not {
 		operator = "$nin"
 	}
 
 	var k string
-	if oracleManager.TableContainsColumn(tableJoin, ic.Id) {
-		k = tableJoin + "." + keyManager.ToMongoId(tableJoin, ic.Id)
+	if oracleManager.TableContainsColumn(catalog, tableJoin, ic.Id) {
+		k = tableJoin + "." + keyManager.ToMongoId(catalog, tableJoin, ic.Id)
 	} else {
-		k = keyManager.ToMongoId(tableFrom, ic.Id)
+		k = keyManager.ToMongoId(catalog, tableFrom, ic.Id)
 	}
 
 	return bson.D{{
@@ -144,7 +200,7 @@ func (ic *InComparision) GetBson(tableFrom, tableJoin string) (bson.D, error) {
 
 // GetBson implements the BooleanExpression interface.
 func (bc *BooleanComposite) GetBson(
-	tableFrom, tableJoin string,
+	catalog oracleManager.Catalog, tableFrom, tableJoin string,
 ) (bson.D, error) {
 
 	boolOpStr := ""
@@ -159,7 +215,7 @@ func (bc *BooleanComposite) GetBson(
 
 	sexprs := make([]bson.D, 0)
 	for _, se := range bc.SubExpr {
-		bs, err := se.GetBson(tableFrom, tableJoin)
+		bs, err := se.GetBson(catalog, tableFrom, tableJoin)
 		if err != nil {
 			return bson.D{}, err
 		}
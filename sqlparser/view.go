@@ -0,0 +1,45 @@
+package sqlparser
+
+import (
+	"strings"
+
+	"github.com/lucasgpulcinelli/mongoQLer/oracleManager"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ParseView parses the query of an oracle view, as in USER_VIEWS.TEXT, which
+// does not end in a semicolon.
+func ParseView(text string) (*Statement, error) {
+	text = strings.TrimSpace(text)
+	if !strings.HasSuffix(text, ";") {
+		text += ";"
+	}
+
+	return Parse(text)
+}
+
+// ToMongoPipeline gets the (optimized) Pipeline that gets the same documents
+// as the Statement from the FromTable collection. For aggregations it is the
+// same as ToMongoAggregate, and for finds it is a $match with the filter
+// followed by a $project with the selection. This is the form needed for a
+// mongoDB view, created with createView.
+func (stmt *Statement) ToMongoPipeline(
+	catalog oracleManager.Catalog,
+) (mongo.Pipeline, error) {
+	if stmt.IsAggregate() {
+		return stmt.ToMongoAggregate(catalog)
+	}
+
+	where, selection, err := stmt.ToMongoFind(catalog)
+	if err != nil {
+		return mongo.Pipeline{}, err
+	}
+
+	result := mongo.Pipeline{bson.D{{Key: "$match", Value: where}}}
+	if len(selection) != 0 {
+		result = append(result, bson.D{{Key: "$project", Value: selection}})
+	}
+
+	return OptimizePipeline(result), nil
+}
@@ -0,0 +1,52 @@
+package sqlparser
+
+import (
+	"testing"
+)
+
+func TestViewToMongoPipeline(t *testing.T) {
+	tests := []struct {
+		text     string
+		pipeline string
+	}{
+		{
+			text: "SELECT NAME, SALARY FROM EMP WHERE SALARY > 10",
+			pipeline: `[{"$match":{"SALARY":{"$gt":10}}},` +
+				`{"$project":{"NAME":1,"SALARY":1,"_id":0}}]`,
+		},
+		{
+			text:     "select * from EMP;\n",
+			pipeline: `[]`,
+		},
+		{
+			text: "SELECT COUNT(ID) FROM EMP WHERE SALARY > 10",
+			pipeline: `[{"$match":{"SALARY":{"$gt":10}}},` +
+				`{"$group":{"_id":null,"COUNT(ID)":{"$sum":` +
+				`{"$cond":[{"$ne":["$_id.ID",null]},1,0]}}}},` +
+				`{"$project":{"COUNT(ID)":1,"_id":0}}]`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			stmt, err := ParseView(test.text)
+			if err != nil {
+				t.Fatalf("ParseView failed: %v", err)
+			}
+
+			c := testCatalog(t)
+			if err = stmt.Analyze(c); err != nil {
+				t.Fatalf("Analyze failed: %v", err)
+			}
+
+			p, err := stmt.ToMongoPipeline(c)
+			if err != nil {
+				t.Fatalf("ToMongoPipeline failed: %v", err)
+			}
+
+			if got := pipelineJSON(t, p); got != test.pipeline {
+				t.Errorf("pipeline got\n%s\nwant\n%s", got, test.pipeline)
+			}
+		})
+	}
+}
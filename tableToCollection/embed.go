@@ -0,0 +1,493 @@
+package tableToCollection
+
+import (
+	"database/sql"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/lucasgpulcinelli/mongoQLer/keyManager"
+	"github.com/lucasgpulcinelli/mongoQLer/oracleManager"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// EmbedChunk is the number of tuples converted together, and the maximum
+// number of keys looked up in each query for the embedded references, so that
+// embedding takes one query per reference for each chunk instead of one per
+// tuple. Oracle accepts at most 1000 values in an IN list.
+const EmbedChunk = 500
+
+// tupleKey gets a string identifying the values of some columns of a tuple of
+// a table, for matching the tuples of a reference, and if any of them is null
+// (in which case the foreign key does not apply). The values are normalized
+// with keyValue, so that both sides of a reference give the same key.
+func tupleKey(
+	catalog oracleManager.Catalog, table string, cols []string, vs []any,
+	keyCols []string,
+) (string, bool) {
+	parts := []string{}
+
+	for _, keyCol := range keyCols {
+		var v any
+		for i, col := range cols {
+			if col == keyCol {
+				v = vs[i]
+				break
+			}
+		}
+
+		if v == nil {
+			return "", false
+		}
+
+		info, _ := oracleManager.GetColumn(catalog, table, keyCol)
+		parts = append(parts, keyValue(info, v))
+	}
+
+	return strings.Join(parts, "\x00"), true
+}
+
+// keyValue gets the text of a value in a key, by the type of its column: the
+// driver may scan the same NUMBER as an int64, a float64 or a string depending
+// on the precision of the column, so numbers are written as an exact fraction,
+// and CHARs have their blank padding removed, as their length may differ
+// between the referencer and the referenced column.
+func keyValue(col oracleManager.ColumnInfo, v any) string {
+	switch col.DataType {
+	case "NUMBER", "FLOAT", "BINARY_FLOAT", "BINARY_DOUBLE":
+		var text string
+		switch n := v.(type) {
+		case float64:
+			text = strconv.FormatFloat(n, 'g', -1, 64)
+		case float32:
+			text = strconv.FormatFloat(float64(n), 'g', -1, 32)
+		default:
+			text = strings.TrimSpace(toString(v))
+		}
+
+		if r, ok := new(big.Rat).SetString(text); ok {
+			return r.RatString()
+		}
+
+	case "CHAR", "NCHAR":
+		return strings.TrimRight(toString(v), " ")
+	}
+
+	return fmt.Sprint(v)
+}
+
+// tupleValues gets the values of some columns of a tuple.
+func tupleValues(cols []string, vs []any, keyCols []string) []any {
+	result := []any{}
+
+	for _, keyCol := range keyCols {
+		for i, col := range cols {
+			if col == keyCol {
+				result = append(result, vs[i])
+				break
+			}
+		}
+	}
+
+	return result
+}
+
+// fetchTuples gets all tuples of a table (as read by SelectTable) where the
+// columns have one of the keys, with queries of at most EmbedChunk keys, such
+// as "SELECT * FROM T WHERE (A, B) IN ((:0, :1), (:2, :3))". It returns the
+// column names of the table and the tuples.
+func fetchTuples(
+	db *sql.DB, catalog oracleManager.Catalog, table string, keyCols []string,
+	keys [][]any, lobs LobMapping,
+) ([]string, [][]any, error) {
+	query, err := SelectTable(catalog, table, lobs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	quoted := []string{}
+	for _, col := range keyCols {
+		quoted = append(quoted, quoteColumn(col))
+	}
+
+	var columns []string
+	tuples := [][]any{}
+
+	for start := 0; start < len(keys); start += EmbedChunk {
+		end := start + EmbedChunk
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		// create the query for getting the matching tuples of this chunk
+		lists := []string{}
+		args := []any{}
+		for _, key := range keys[start:end] {
+			binds := []string{}
+			for _, v := range key {
+				binds = append(binds, ":"+strconv.Itoa(len(args)))
+				args = append(args, v)
+			}
+
+			lists = append(lists, "("+strings.Join(binds, ", ")+")")
+		}
+
+		s := query + " WHERE (" + strings.Join(quoted, ", ") + ") IN (" +
+			strings.Join(lists, ", ") + ")"
+
+		// execute the query with the key values
+		rows, err := db.Query(s, args...)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		columns, err = rows.Columns()
+		if err != nil {
+			rows.Close()
+			return nil, nil, err
+		}
+
+		for rows.Next() {
+			// prepare the output tuple to receive data
+			vsOut := make([]any, len(columns))
+			for i := range columns {
+				vsOut[i] = new(any)
+			}
+
+			// scan all columns
+			err = rows.Scan(vsOut...)
+			if err != nil {
+				rows.Close()
+				return nil, nil, err
+			}
+
+			tuples = append(tuples, anypToAny(vsOut))
+		}
+
+		err = rows.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return columns, tuples, nil
+}
+
+// embedReference gets the values embedded in each tuple for a reference: the
+// document referenced if isReferenceTo is true, and the array of documents
+// referencing it if it is false. All tuples are looked up at once with
+// fetchTuples, and the documents found have their own references embedded
+// recursively, one level at a time, until depth levels. It also returns the
+// encoded size of each value, from the sizes of the documents in it.
+func embedReference(
+	db *sql.DB, catalog oracleManager.Catalog,
+	embedsTo, embedsFrom []oracleManager.Reference,
+	ref oracleManager.Reference, cols []string, tuples [][]any,
+	isReferenceTo bool, depth int, opts Options,
+) ([]any, []int, error) {
+
+	// first, determine our tables and columns based on our type of reference
+	var table, tableS string
+	var columns, columnsS []string
+	if isReferenceTo {
+		table, columns = ref.TableReferencer, ref.ColumnReferencer
+		tableS, columnsS = ref.TableReferenced, ref.ColumnReferenced
+	} else {
+		table, columns = ref.TableReferenced, ref.ColumnReferenced
+		tableS, columnsS = ref.TableReferencer, ref.ColumnReferencer
+	}
+
+	// get the distinct keys of the tuples, where a null value in the key means
+	// that the foreign key reference does not apply
+	keys := [][]any{}
+	seen := map[string]bool{}
+	for _, vs := range tuples {
+		key, ok := tupleKey(catalog, table, cols, vs, columns)
+		if !ok || seen[key] {
+			continue
+		}
+
+		seen[key] = true
+		keys = append(keys, tupleValues(cols, vs, columns))
+	}
+
+	colsS, tuplesS, err := fetchTuples(db, catalog, tableS, columnsS, keys,
+		opts.Lobs,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// convert the tuples found to documents. If there are any references that
+	// should be embed, this function will call embedReference back, making the
+	// root of the embedding recursion.
+	docsS, sizesS, err := writeDocuments(db, catalog, tableS, embedsTo,
+		embedsFrom, colsS, tuplesS, depth, opts,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// group the documents found by their key
+	byKey := map[string][]int{}
+	for i, vs := range tuplesS {
+		key, _ := tupleKey(catalog, tableS, colsS, vs, columnsS)
+		byKey[key] = append(byKey[key], i)
+	}
+
+	result := make([]any, len(tuples))
+	sizes := make([]int, len(tuples))
+	for i, vs := range tuples {
+		key, ok := tupleKey(catalog, table, cols, vs, columns)
+		if !ok {
+			// return a null reference
+			continue
+		}
+
+		found := byKey[key]
+
+		if !isReferenceTo {
+			docs := []bson.D{}
+			sizes[i] = emptyDocumentSize
+			for j, k := range found {
+				docs = append(docs, docsS[k])
+				sizes[i] += fieldSize(strconv.Itoa(j), sizesS[k])
+			}
+
+			result[i] = docs
+			continue
+		}
+
+		// if we are referencing some table, we will only have one object
+		// embedded, so only one tuple is acceptable
+		if len(found) == 0 {
+			return nil, nil, fmt.Errorf(
+				"embed query of %s in %s returned no data", ref.ConstraintName,
+				table,
+			)
+		}
+		if len(found) > 1 {
+			return nil, nil, fmt.Errorf(
+				"embed query of %s in %s returned more than one tuple",
+				ref.ConstraintName, table,
+			)
+		}
+
+		result[i] = docsS[found[0]]
+		sizes[i] = sizesS[found[0]]
+	}
+
+	return result, sizes, nil
+}
+
+// writeDocuments creates the mongoDB documents for tuples of a certain table,
+// with a list of columns and the values of each tuple, embedding the
+// references in the embeds arrays using the database connection provided.
+// All primary keys in the tuples will be converted to a sub document in the
+// "_id" field. The documents embed references up to depth levels deep, or
+// without limit if depth is negative, and after that the references are kept
+// as plain key fields. The values are converted with the type and LOB
+// policies in opts.
+//
+// The encoded size of each document is counted as it is built, and returned
+// along with the documents. The ones above MaxDocumentSize are handled as in
+// GuardSize at their own level, so that an embedded document has its arrays
+// cut before it is embedded in another one.
+func writeDocuments(
+	db *sql.DB, catalog oracleManager.Catalog, table string,
+	embedsTo, embedsFrom []oracleManager.Reference, cols []string,
+	tuples [][]any, depth int, opts Options,
+) ([]bson.D, []int, error) {
+
+	// the final documents, starting with the embedded references
+	docs := make([]bson.D, len(tuples))
+	sizes := make([]int, len(tuples))
+	for i := range docs {
+		docs[i] = bson.D{}
+		sizes[i] = emptyDocumentSize
+	}
+
+	if len(tuples) == 0 {
+		return docs, sizes, nil
+	}
+
+	// add appends a field to a document, with the encoded size of its value
+	add := func(i int, key string, v any, size int) {
+		docs[i] = append(docs[i], bson.E{Key: key, Value: v})
+		sizes[i] += fieldSize(key, size)
+	}
+
+	// past the last level nothing is embedded, and the embedded documents have
+	// one level less to go
+	switch {
+	case depth == 0:
+		embedsTo, embedsFrom = nil, nil
+	case depth > 0:
+		depth--
+	}
+
+	// map to determine if the column provided is a reference
+	refCols := map[string]bool{}
+
+	// for each embedding reference that we make the reference to
+	for _, embed := range embedsTo {
+		// if the reference does not relate to us, ignore it
+		if embed.TableReferencer != table {
+			continue
+		}
+
+		// if it does, the columns that generate the reference are replaced by the
+		// referenced document
+		for _, colRef := range embed.ColumnReferencer {
+			refCols[colRef] = true
+		}
+
+		subDocs, subSizes, err := embedReference(db, catalog, embedsTo,
+			embedsFrom, embed, cols, tuples, true, depth, opts,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// and add it to our own documents, generating the embedding
+		for i := range docs {
+			add(i, embed.ConstraintName, subDocs[i], subSizes[i])
+		}
+	}
+
+	// for each embedding reference that we receive the reference from
+	for _, embed := range embedsFrom {
+		// if the reference does not relate to us, ignore it
+		if embed.TableReferenced != table {
+			continue
+		}
+
+		subDocs, subSizes, err := embedReference(db, catalog, embedsTo,
+			embedsFrom, embed, cols, tuples, false, depth, opts,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for i := range docs {
+			add(i, embed.ConstraintName, subDocs[i], subSizes[i])
+		}
+	}
+
+	// for each reference kept as a link other than the foreign key columns
+	for _, link := range opts.Links {
+		values, ok, err := linkFields(db, catalog, table, link, cols, tuples,
+			opts,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		// the foreign key columns of the referencer are replaced by the link
+		if link.TableReferencer == table && link.Mode != LinkChildIds {
+			for _, colRef := range link.ColumnReferencer {
+				refCols[colRef] = true
+			}
+		}
+
+		for i := range docs {
+			size, err := valueSize(values[i])
+			if err != nil {
+				return nil, nil, err
+			}
+
+			add(i, link.ConstraintName, values[i], size)
+		}
+	}
+
+	for i, vs := range tuples {
+		// the primary key sub document
+		pks := bson.D{}
+
+		// for each column
+		for j, name := range cols {
+			if refCols[name] && !keyManager.IsPk(catalog, table, name) ||
+				isExtraColumn(name) {
+				// if it is a reference, the embedding is already done
+				continue
+			}
+
+			fields, err := opts.Types.Fields(catalog, table, name, vs[j])
+			if err != nil {
+				return nil, nil, err
+			}
+
+			// LOBs above their threshold were left out of the tuple
+			if length, rowid, ok := largeLob(cols, vs, name); ok {
+				col, _ := oracleManager.GetColumn(catalog, table, name)
+
+				file, err := offloadLob(db, catalog, table, col, length, rowid,
+					opts.Lobs, opts.LobSink,
+				)
+				if err != nil {
+					return nil, nil, err
+				}
+
+				fields = bson.D{{Key: name, Value: file}}
+			}
+
+			if keyManager.IsPk(catalog, table, name) {
+				// if it is a primary key, add it to the _id document
+				pks = append(pks, fields...)
+				continue
+			}
+
+			// if not, add it to the main document
+			for _, field := range fields {
+				size, err := valueSize(field.Value)
+				if err != nil {
+					return nil, nil, err
+				}
+
+				add(i, field.Key, field.Value, size)
+			}
+		}
+
+		// if the primary key is empty, the ROWID gets an ObjectId from the
+		// IdMap, and if there is none mongoDB should create an objectID for us.
+		// If not, we should use our primary key
+		var id any = pks
+		if len(pks) == 0 {
+			var err error
+			id, err = tupleId(catalog, table, cols, vs, opts)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+
+		if id != nil {
+			size, err := valueSize(id)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			add(i, "_id", id, size)
+		}
+	}
+
+	// the documents that do not fit in mongoDB have their own arrays cut
+	for i := range docs {
+		if sizes[i] <= MaxDocumentSize {
+			continue
+		}
+
+		var err error
+		docs[i], sizes[i], err = guardSize(table, docs[i], sizes[i], embedsFrom,
+			opts,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return docs, sizes, nil
+}
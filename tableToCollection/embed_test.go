@@ -0,0 +1,46 @@
+package tableToCollection
+
+import (
+	"testing"
+)
+
+func TestTupleKey(t *testing.T) {
+	cols := []string{"ID", "CODE", "RATE", "NAME"}
+
+	tests := []struct {
+		name    string
+		vs      []any
+		keyCols []string
+		want    string
+		ok      bool
+	}{
+		{"integer", []any{int64(1)}, []string{"ID"}, "1", true},
+		{"integer as double", []any{1.0}, []string{"ID"}, "1", true},
+		{"integer as text", []any{"1.00"}, []string{"ID"}, "1", true},
+		{"fraction", []any{nil, nil, 0.1}, []string{"RATE"}, "1/10", true},
+		{"fraction as text", []any{nil, nil, " 0.10"}, []string{"RATE"},
+			"1/10", true},
+		{"padded CHAR", []any{nil, "AB   "}, []string{"CODE"}, "AB", true},
+		{"VARCHAR2 keeps blanks", []any{nil, nil, nil, "AB "},
+			[]string{"NAME"}, "AB ", true},
+		{"many columns", []any{int64(2), "X  "}, []string{"CODE", "ID"},
+			"X\x002", true},
+		{"null column", []any{int64(2), nil}, []string{"ID", "CODE"}, "",
+			false},
+		{"missing column", []any{int64(2)}, []string{"OTHER"}, "", false},
+	}
+
+	c := testCatalog(t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vs := append(test.vs, make([]any, len(cols)-len(test.vs))...)
+
+			got, ok := tupleKey(c, "T", cols, vs, test.keyCols)
+			if got != test.want || ok != test.ok {
+				t.Errorf("tupleKey got %q, %v, want %q, %v",
+					got, ok, test.want, test.ok,
+				)
+			}
+		})
+	}
+}
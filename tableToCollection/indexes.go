@@ -0,0 +1,235 @@
+package tableToCollection
+
+import (
+	"fmt"
+
+	"github.com/lucasgpulcinelli/mongoQLer/keyManager"
+	"github.com/lucasgpulcinelli/mongoQLer/oracleManager"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// struct IndexProposal represents an index that should be created in a
+// collection because of how a reference is converted, with the reason for it.
+type IndexProposal struct {
+	Collection string
+	Keys       bson.D
+	Reason     string
+}
+
+// ProposeIndexes proposes indexes for the references of a catalog, following
+// the same embedding plan given to GetCollection:
+//   - references kept as links need an index on the referencer columns, used
+//     as the foreignField of the $lookup from the referenced collection;
+//   - references embedded as objects are queried by the key of the embedded
+//     document, under the constraint name;
+//   - references embedded as arrays are queried by the key of the array
+//     elements, which makes a multikey index.
+//
+// Only the first level of embedding is considered, and indexes already
+// covered by (a prefix of) an unique constraint or index are not proposed.
+func ProposeIndexes(
+	catalog oracleManager.Catalog, refs []oracleManager.Reference,
+	embedsTo, embedsFrom []oracleManager.Reference,
+) ([]IndexProposal, error) {
+	existing, err := existingIndexes(catalog)
+	if err != nil {
+		return nil, err
+	}
+
+	isIn := func(ref oracleManager.Reference,
+		embeds []oracleManager.Reference,
+	) bool {
+		for _, embed := range embeds {
+			if embed.ConstraintName == ref.ConstraintName {
+				return true
+			}
+		}
+		return false
+	}
+
+	result := []IndexProposal{}
+	for _, ref := range refs {
+		proposal := IndexProposal{Keys: bson.D{}}
+
+		switch {
+		case isIn(ref, embedsTo):
+			// the referenced document is in the referencer one, and its key
+			// identifies it
+			proposal.Collection = ref.TableReferencer
+			proposal.Reason = fmt.Sprintf(
+				"%s is embedded as an object in %s", ref.ConstraintName,
+				ref.TableReferencer,
+			)
+			for _, col := range ref.ColumnReferenced {
+				proposal.Keys = append(proposal.Keys, bson.E{
+					Key: ref.ConstraintName + "." +
+						keyManager.ToMongoId(catalog, ref.TableReferenced, col),
+					Value: 1,
+				})
+			}
+
+		case isIn(ref, embedsFrom):
+			// the referencer documents are an array in the referenced one, and
+			// their primary key identifies them
+			proposal.Collection = ref.TableReferenced
+			proposal.Reason = fmt.Sprintf(
+				"%s is embedded as an array in %s (multikey index)",
+				ref.ConstraintName, ref.TableReferenced,
+			)
+			for _, col := range catalog.GetPrimaryKeys(ref.TableReferencer) {
+				proposal.Keys = append(proposal.Keys, bson.E{
+					Key: ref.ConstraintName + "." +
+						keyManager.ToMongoId(catalog, ref.TableReferencer, col),
+					Value: 1,
+				})
+			}
+
+		default:
+			proposal.Collection = ref.TableReferencer
+			proposal.Reason = fmt.Sprintf(
+				"%s is kept as a link, used in $lookup", ref.ConstraintName,
+			)
+			for _, col := range ref.ColumnReferencer {
+				proposal.Keys = append(proposal.Keys, bson.E{
+					Key:   keyManager.ToMongoId(catalog, ref.TableReferencer, col),
+					Value: 1,
+				})
+			}
+		}
+
+		// a referencer without primary key has nothing to identify it
+		if len(proposal.Keys) == 0 {
+			continue
+		}
+
+		if isCovered(proposal, existing[proposal.Collection]) {
+			continue
+		}
+
+		existing[proposal.Collection] = append(
+			existing[proposal.Collection], keyNames(proposal.Keys),
+		)
+		result = append(result, proposal)
+	}
+
+	return result, nil
+}
+
+// existingIndexes gets the keys of the unique constraints and indexes in the
+// catalog for each table, as they are converted to mongoDB.
+func existingIndexes(
+	catalog oracleManager.Catalog,
+) (map[string][][]string, error) {
+	uniques, err := catalog.GetUniques()
+	if err != nil {
+		return nil, err
+	}
+
+	indexes, err := catalog.GetIndexes()
+	if err != nil {
+		return nil, err
+	}
+
+	existing := map[string][][]string{}
+
+	for _, un := range uniques {
+		keys := []string{}
+		for _, col := range un.Columns {
+			keys = append(keys, keyManager.ToMongoId(catalog, un.Table, col))
+		}
+
+		existing[un.Table] = append(existing[un.Table], keys)
+	}
+
+	for _, index := range indexes {
+		keys := []string{}
+		for _, col := range index.Columns {
+			// expressions end the usable prefix of the index
+			if col.Column == "" {
+				break
+			}
+
+			keys = append(keys,
+				keyManager.ToMongoId(catalog, index.Table, col.Column),
+			)
+		}
+
+		existing[index.Table] = append(existing[index.Table], keys)
+	}
+
+	return existing, nil
+}
+
+// keyNames gets the field names of the keys of an index.
+func keyNames(keys bson.D) []string {
+	names := []string{}
+	for _, e := range keys {
+		names = append(names, e.Key)
+	}
+
+	return names
+}
+
+// isCovered indicates if the keys of a proposal are a prefix of the keys of
+// any of the existing indexes.
+func isCovered(proposal IndexProposal, existing [][]string) bool {
+	names := keyNames(proposal.Keys)
+
+	for _, keys := range existing {
+		if len(keys) < len(names) {
+			continue
+		}
+
+		covered := true
+		for i, name := range names {
+			if keys[i] != name {
+				covered = false
+				break
+			}
+		}
+
+		if covered {
+			return true
+		}
+	}
+
+	return false
+}
+
+// UniquePartialFilter gets the partialFilterExpression for an unique index on
+// some columns of a table, or nil if it is not needed. In oracle, any number
+// of rows can have all columns of an unique constraint NULL, so when all of
+// them are nullable only the documents with some non-null column are indexed.
+// For more than one column this uses a top level $or, which needs a recent
+// mongoDB version. The $type of each column follows its policy in types.
+func UniquePartialFilter(
+	catalog oracleManager.Catalog, table string, columns []string,
+	types TypeMapping,
+) bson.D {
+	conditions := bson.A{}
+
+	for _, column := range columns {
+		info, ok := oracleManager.GetColumn(catalog, table, column)
+
+		// columns that are not known are considered not nullable, as before
+		if !ok || !info.Nullable || keyManager.IsPk(catalog, table, column) {
+			return nil
+		}
+
+		aliases := types.BsonTypes(catalog, table, info.Name)
+		conditions = append(conditions, bson.D{{
+			Key:   keyManager.ToMongoId(catalog, table, column),
+			Value: bson.D{{Key: "$type", Value: aliases}},
+		}})
+	}
+
+	if len(conditions) == 0 {
+		return nil
+	}
+
+	if len(conditions) == 1 {
+		return conditions[0].(bson.D)
+	}
+
+	return bson.D{{Key: "$or", Value: conditions}}
+}
@@ -0,0 +1,192 @@
+package tableToCollection
+
+import (
+	"testing"
+
+	"github.com/lucasgpulcinelli/mongoQLer/oracleManager"
+	"github.com/lucasgpulcinelli/mongoQLer/sqlparser"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// testDDL is the schema used by the tests of this package.
+const testDDL = `
+CREATE TABLE T (
+	ID NUMBER(5) PRIMARY KEY,
+	BIG NUMBER(15),
+	HUGE NUMBER(30),
+	PRICE NUMBER(8,2) CHECK (PRICE >= 0),
+	RATE FLOAT,
+	CODE CHAR(5),
+	NAME VARCHAR2(30) NOT NULL,
+	BORN DATE,
+	TS_OFFSET TIMESTAMP WITH TIME ZONE,
+	GUID RAW(16),
+	DATA RAW(100),
+	DOC CLOB,
+	X XMLTYPE
+);
+
+CREATE TABLE DEPT (
+	ID NUMBER(5) PRIMARY KEY,
+	NAME VARCHAR2(30),
+	CONSTRAINT DEPT_NAME UNIQUE (NAME)
+);
+
+CREATE TABLE EMP (
+	ID NUMBER(5) PRIMARY KEY,
+	NAME VARCHAR2(30) NOT NULL,
+	DEPT_ID NUMBER(5),
+	CONSTRAINT EMP_DEPT FOREIGN KEY (DEPT_ID) REFERENCES DEPT (ID)
+);
+`
+
+// testCatalog gets the catalog of testDDL.
+func testCatalog(t *testing.T) *oracleManager.MemoryCatalog {
+	t.Helper()
+
+	schema, err := sqlparser.ParseDDL(testDDL)
+	if err != nil {
+		t.Fatalf("invalid test DDL: %v", err)
+	}
+
+	return schema.Catalog()
+}
+
+// extJSON gets a document in relaxed extended JSON, to be compared in tests.
+func extJSON(t *testing.T, d bson.D) string {
+	t.Helper()
+
+	b, err := bson.MarshalExtJSON(d, false, false)
+	if err != nil {
+		t.Fatalf("invalid document %v: %v", d, err)
+	}
+
+	return string(b)
+}
+
+// ref creates a reference between two tables for the tests.
+func ref(name, referencer, referenced string) oracleManager.Reference {
+	return oracleManager.Reference{
+		ConstraintName:   name,
+		TableReferencer:  referencer,
+		ColumnReferencer: []string{referenced + "_ID"},
+		TableReferenced:  referenced,
+		ColumnReferenced: []string{"ID"},
+	}
+}
+
+func TestProposeIndexes(t *testing.T) {
+	empDept := ref("EMP_DEPT", "EMP", "DEPT")
+	empDept.ColumnReferencer = []string{"DEPT_ID"}
+	refs := []oracleManager.Reference{empDept}
+
+	tests := []struct {
+		name       string
+		embedsTo   []oracleManager.Reference
+		embedsFrom []oracleManager.Reference
+		indexed    bool
+		collection string
+		keys       string
+	}{
+		{
+			name:       "link",
+			collection: "EMP",
+			keys:       `{"DEPT_ID":1}`,
+		},
+		{
+			name:    "link already indexed",
+			indexed: true,
+		},
+		{
+			name:       "embedded as an object",
+			embedsTo:   refs,
+			collection: "EMP",
+			keys:       `{"EMP_DEPT._id.ID":1}`,
+		},
+		{
+			name:       "embedded as an array",
+			embedsFrom: refs,
+			collection: "DEPT",
+			keys:       `{"EMP_DEPT._id.ID":1}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := testCatalog(t)
+			if test.indexed {
+				c.Indexes = append(c.Indexes, oracleManager.IndexEntry{
+					Name: "EMP_DEPT_NAME", Table: "EMP",
+					Columns: []oracleManager.IndexColumn{
+						{Column: "DEPT_ID"}, {Column: "NAME"},
+					},
+				})
+			}
+
+			proposals, err := ProposeIndexes(c, refs, test.embedsTo,
+				test.embedsFrom,
+			)
+			if err != nil {
+				t.Fatalf("ProposeIndexes failed: %v", err)
+			}
+
+			if test.collection == "" {
+				if len(proposals) != 0 {
+					t.Errorf("ProposeIndexes got %v", proposals)
+				}
+				return
+			}
+
+			if len(proposals) != 1 {
+				t.Fatalf("ProposeIndexes got %v", proposals)
+			}
+			if proposals[0].Collection != test.collection ||
+				extJSON(t, proposals[0].Keys) != test.keys {
+				t.Errorf("ProposeIndexes got %s %v, want %s %s",
+					proposals[0].Collection, proposals[0].Keys,
+					test.collection, test.keys,
+				)
+			}
+		})
+	}
+}
+
+func TestUniquePartialFilter(t *testing.T) {
+	tests := []struct {
+		table   string
+		columns []string
+		types   TypeMapping
+		want    string
+	}{
+		{"DEPT", []string{"NAME"}, nil, `{"NAME":{"$type":["string"]}}`},
+		{
+			"DEPT", []string{"NAME"}, TypeMapping{"DEPT.NAME": PolicyBinary},
+			`{"NAME":{"$type":["binData"]}}`,
+		},
+		{
+			"T", []string{"CODE", "DOC"}, nil,
+			`{"$or":[{"CODE":{"$type":["string"]}},` +
+				`{"DOC":{"$type":["string","object"]}}]}`,
+		},
+		{"T", []string{"CODE", "NAME"}, nil, ""},
+		{"DEPT", []string{"ID"}, nil, ""},
+		{"DEPT", []string{"UNKNOWN"}, nil, ""},
+		{"DEPT", nil, nil, ""},
+	}
+
+	c := testCatalog(t)
+	for _, test := range tests {
+		got := UniquePartialFilter(c, test.table, test.columns, test.types)
+
+		s := ""
+		if got != nil {
+			s = extJSON(t, got)
+		}
+
+		if s != test.want {
+			t.Errorf("UniquePartialFilter of %s %v got %s, want %s",
+				test.table, test.columns, s, test.want,
+			)
+		}
+	}
+}
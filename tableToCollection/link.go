@@ -0,0 +1,402 @@
+package tableToCollection
+
+import (
+	"bufio"
+	"database/sql"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/lucasgpulcinelli/mongoQLer/keyManager"
+	"github.com/lucasgpulcinelli/mongoQLer/oracleManager"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// type LinkMode represents how a reference that is not embedded is kept in
+// the documents.
+type LinkMode int
+
+const (
+	// LinkColumns keeps the foreign key columns as they are.
+	LinkColumns LinkMode = iota
+
+	// LinkId replaces the foreign key columns of the referencer by an object
+	// with the _id of the referenced document, as in { _id: { ... } }.
+	LinkId
+
+	// LinkDBRef replaces the foreign key columns of the referencer by a DBRef
+	// to the referenced document, as in { $ref: TABLE, $id: { ... } }.
+	LinkDBRef
+
+	// LinkChildIds adds to the referenced documents an array with the _id of
+	// the documents referencing them, keeping the foreign key columns.
+	LinkChildIds
+)
+
+// struct Link represents a reference that is not embedded, with how it is
+// kept in the documents.
+type Link struct {
+	oracleManager.Reference
+	Mode LinkMode
+}
+
+// linkFields gets the value of the field added for a link to each tuple of a
+// table, named after the constraint, or false if the link does not add a
+// field to the documents of the table.
+func linkFields(
+	db *sql.DB, catalog oracleManager.Catalog, table string, link Link,
+	cols []string, tuples [][]any, opts Options,
+) ([]any, bool, error) {
+	switch {
+	case link.Mode == LinkChildIds && link.TableReferenced == table:
+		ids, err := childIds(db, catalog, link.Reference, cols, tuples, opts)
+		return ids, true, err
+
+	case (link.Mode == LinkId || link.Mode == LinkDBRef) &&
+		link.TableReferencer == table:
+		ids, err := linkIds(db, catalog, link.Reference, cols, tuples, opts)
+		if err != nil {
+			return nil, false, err
+		}
+
+		// a null foreign key is a null link
+		for i, id := range ids {
+			switch {
+			case id == nil:
+			case link.Mode == LinkId:
+				ids[i] = bson.D{{Key: "_id", Value: id}}
+			default:
+				ids[i] = bson.D{
+					{Key: "$ref", Value: link.TableReferenced},
+					{Key: "$id", Value: id},
+				}
+			}
+		}
+
+		return ids, true, nil
+	}
+
+	return nil, false, nil
+}
+
+// struct IdMap represents the ObjectIds given to the tuples of tables without
+// a primary key, by their ROWID, so that the documents and the links to them
+// have the same _id in all conversions that share the map, including the ones
+// of later sessions when it is saved and loaded back.
+type IdMap struct {
+	mutex sync.Mutex
+	ids   map[string]primitive.ObjectID
+}
+
+// NewIdMap creates an empty IdMap.
+func NewIdMap() *IdMap {
+	return &IdMap{ids: map[string]primitive.ObjectID{}}
+}
+
+// Id gets the ObjectId of a tuple of a table by its ROWID, creating a new one
+// the first time the tuple is seen.
+func (m *IdMap) Id(table, rowid string) primitive.ObjectID {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	key := table + "\x00" + rowid
+	id, ok := m.ids[key]
+	if !ok {
+		id = primitive.NewObjectID()
+		m.ids[key] = id
+	}
+
+	return id
+}
+
+// struct idEntry represents an entry of an IdMap as saved by IdMap.Save, one
+// document per tuple.
+type idEntry struct {
+	Id    primitive.ObjectID `bson:"_id"`
+	Table string             `bson:"table"`
+	Rowid string             `bson:"rowid"`
+}
+
+// Save writes the entries of the map to w as JSON lines, one document with
+// the ObjectId as _id and the table and ROWID of its tuple per line, so that
+// the file can be loaded back with Load, or imported with mongoimport as a
+// mapping collection. It returns how many entries were written.
+func (m *IdMap) Save(w io.Writer) (int, error) {
+	m.mutex.Lock()
+	entries := []idEntry{}
+	for key, id := range m.ids {
+		table, rowid, _ := strings.Cut(key, "\x00")
+		entries = append(entries, idEntry{Id: id, Table: table, Rowid: rowid})
+	}
+	m.mutex.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Table != entries[j].Table {
+			return entries[i].Table < entries[j].Table
+		}
+		return entries[i].Rowid < entries[j].Rowid
+	})
+
+	i := 0
+	return WriteJSONLines(w, func() (bson.D, bool) {
+		if i == len(entries) {
+			return nil, false
+		}
+
+		e := entries[i]
+		i++
+		return bson.D{
+			{Key: "_id", Value: e.Id},
+			{Key: "table", Value: e.Table},
+			{Key: "rowid", Value: e.Rowid},
+		}, true
+	})
+}
+
+// Load reads entries written by Save from r into the map, replacing the
+// ObjectIds of the tuples already in it. It returns how many entries were
+// read.
+func (m *IdMap) Load(r io.Reader) (int, error) {
+	entries := []idEntry{}
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		var e idEntry
+		err := bson.UnmarshalExtJSON(scanner.Bytes(), false, &e)
+		if err != nil {
+			return 0, fmt.Errorf("line %d of the _id map: %w", line, err)
+		}
+		if e.Table == "" || e.Rowid == "" || e.Id.IsZero() {
+			return 0, fmt.Errorf("line %d of the _id map: expected _id, table "+
+				"and rowid", line,
+			)
+		}
+
+		entries = append(entries, e)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, e := range entries {
+		m.ids[e.Table+"\x00"+e.Rowid] = e.Id
+	}
+
+	return len(entries), nil
+}
+
+// tupleId gets the _id of the document of a tuple of a table: its primary key
+// (in the order of the columns, as in writeDocuments), or the ObjectId of its
+// ROWID in opts.Ids for tables without a primary key. It returns nil when the
+// tuple cannot be identified.
+func tupleId(
+	catalog oracleManager.Catalog, table string, cols []string, vs []any,
+	opts Options,
+) (any, error) {
+	pks := bson.D{}
+	for i, col := range cols {
+		if !keyManager.IsPk(catalog, table, col) {
+			continue
+		}
+
+		fields, err := opts.Types.Fields(catalog, table, col, vs[i])
+		if err != nil {
+			return nil, err
+		}
+		pks = append(pks, fields...)
+	}
+
+	if len(pks) != 0 {
+		return pks, nil
+	}
+
+	for i, col := range cols {
+		if col == rowidColumn && vs[i] != nil && opts.Ids != nil {
+			return opts.Ids.Id(table, fmt.Sprint(vs[i])), nil
+		}
+	}
+
+	return nil, nil
+}
+
+// referencesPk indicates if a reference is to the whole primary key of the
+// referenced table, so that the _id of the referenced documents can be built
+// from the foreign key columns alone.
+func referencesPk(
+	catalog oracleManager.Catalog, ref oracleManager.Reference,
+) bool {
+	pks := catalog.GetPrimaryKeys(ref.TableReferenced)
+	if len(pks) != len(ref.ColumnReferenced) {
+		return false
+	}
+
+	for _, col := range ref.ColumnReferenced {
+		if !keyManager.IsPk(catalog, ref.TableReferenced, col) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// linkIds gets the _id of the document referenced by each tuple in a
+// reference, or nil for the tuples with a null foreign key. When the
+// reference is to the primary key, the _id is built from the foreign key
+// columns, and otherwise the referenced tuples are looked up with
+// fetchTuples.
+func linkIds(
+	db *sql.DB, catalog oracleManager.Catalog, ref oracleManager.Reference,
+	cols []string, tuples [][]any, opts Options,
+) ([]any, error) {
+	result := make([]any, len(tuples))
+
+	if referencesPk(catalog, ref) {
+		for i, vs := range tuples {
+			_, ok := tupleKey(catalog, ref.TableReferencer, cols, vs,
+				ref.ColumnReferencer,
+			)
+			if !ok {
+				continue
+			}
+
+			// the referenced columns in the order of the referenced table
+			values := tupleValues(cols, vs, ref.ColumnReferencer)
+			id := bson.D{}
+			for _, col := range catalog.GetColumns(ref.TableReferenced) {
+				for j, colRef := range ref.ColumnReferenced {
+					if colRef != col.Name {
+						continue
+					}
+
+					fields, err := opts.Types.Fields(catalog, ref.TableReferenced,
+						colRef, values[j],
+					)
+					if err != nil {
+						return nil, err
+					}
+					id = append(id, fields...)
+				}
+			}
+
+			result[i] = id
+		}
+
+		return result, nil
+	}
+
+	byKey, err := fetchIds(db, catalog, ref.TableReferenced,
+		ref.ColumnReferenced, ref.TableReferencer, cols, tuples,
+		ref.ColumnReferencer, opts,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, vs := range tuples {
+		key, ok := tupleKey(catalog, ref.TableReferencer, cols, vs,
+			ref.ColumnReferencer,
+		)
+		if !ok {
+			continue
+		}
+
+		ids := byKey[key]
+		if len(ids) != 1 {
+			return nil, fmt.Errorf("link query of %s in %s returned %d tuples",
+				ref.ConstraintName, ref.TableReferencer, len(ids),
+			)
+		}
+
+		result[i] = ids[0]
+	}
+
+	return result, nil
+}
+
+// childIds gets, for each tuple of the referenced table in a reference, the
+// array with the _id of the documents referencing it.
+func childIds(
+	db *sql.DB, catalog oracleManager.Catalog, ref oracleManager.Reference,
+	cols []string, tuples [][]any, opts Options,
+) ([]any, error) {
+	byKey, err := fetchIds(db, catalog, ref.TableReferencer,
+		ref.ColumnReferencer, ref.TableReferenced, cols, tuples,
+		ref.ColumnReferenced, opts,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]any, len(tuples))
+	for i, vs := range tuples {
+		ids := bson.A{}
+
+		key, ok := tupleKey(catalog, ref.TableReferenced, cols, vs,
+			ref.ColumnReferenced,
+		)
+		if ok {
+			ids = append(ids, byKey[key]...)
+		}
+
+		result[i] = ids
+	}
+
+	return result, nil
+}
+
+// fetchIds looks up the tuples of a table whose columns have the values of
+// the keyCols of some tuples of keyTable, getting the _id of their documents
+// grouped by the key, as in tupleKey.
+func fetchIds(
+	db *sql.DB, catalog oracleManager.Catalog, table string,
+	columns []string, keyTable string, cols []string, tuples [][]any,
+	keyCols []string, opts Options,
+) (map[string]bson.A, error) {
+	keys := [][]any{}
+	seen := map[string]bool{}
+	for _, vs := range tuples {
+		key, ok := tupleKey(catalog, keyTable, cols, vs, keyCols)
+		if !ok || seen[key] {
+			continue
+		}
+
+		seen[key] = true
+		keys = append(keys, tupleValues(cols, vs, keyCols))
+	}
+
+	colsS, tuplesS, err := fetchTuples(db, catalog, table, columns, keys,
+		opts.Lobs,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	byKey := map[string]bson.A{}
+	for _, vs := range tuplesS {
+		id, err := tupleId(catalog, table, colsS, vs, opts)
+		if err != nil {
+			return nil, err
+		}
+		if id == nil {
+			return nil, fmt.Errorf("documents of %s cannot be linked: it has no "+
+				"primary key or ROWID", table,
+			)
+		}
+
+		key, _ := tupleKey(catalog, table, colsS, vs, columns)
+		byKey[key] = append(byKey[key], id)
+	}
+
+	return byKey, nil
+}
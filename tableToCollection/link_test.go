@@ -0,0 +1,65 @@
+package tableToCollection
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestIdMapSaveLoad(t *testing.T) {
+	m := NewIdMap()
+	a := m.Id("T", "AAAB")
+	b := m.Id("T", "AAAA")
+	c := m.Id("S", "AAAA")
+
+	if m.Id("T", "AAAB") != a || a == b || b == c {
+		t.Fatalf("Id got %v, %v and %v", a, b, c)
+	}
+
+	var buffer bytes.Buffer
+	n, err := m.Save(&buffer)
+	if err != nil || n != 3 {
+		t.Fatalf("Save got %d, %v", n, err)
+	}
+
+	// the entries are sorted by table and ROWID
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	want := `{"_id":{"$oid":"` + c.Hex() + `"},"table":"S","rowid":"AAAA"}`
+	if len(lines) != 3 || lines[0] != want {
+		t.Errorf("Save got\n%s\nwant the first line\n%s", buffer.String(), want)
+	}
+
+	loaded := NewIdMap()
+	loaded.Id("T", "AAAB")
+	n, err = loaded.Load(&buffer)
+	if err != nil || n != 3 {
+		t.Fatalf("Load got %d, %v", n, err)
+	}
+
+	if loaded.Id("T", "AAAB") != a || loaded.Id("T", "AAAA") != b ||
+		loaded.Id("S", "AAAA") != c {
+		t.Errorf("Load did not restore the ObjectIds")
+	}
+}
+
+func TestIdMapLoadErrors(t *testing.T) {
+	tests := []string{
+		`{"_id": 1, "table": "T", "rowid": "AAAA"}`,
+		`{"_id": {"$oid": "65f0a0a0a0a0a0a0a0a0a0a0"}, "table": "T"}`,
+		`{"_id": {"$oid": "65f0a0a0a0a0a0a0a0a0a0a0"}, "table": "T", ` +
+			`"rowid": "AAAA"}` + "\nnot json",
+	}
+
+	for _, test := range tests {
+		m := NewIdMap()
+		if _, err := m.Load(strings.NewReader(test)); err == nil {
+			t.Errorf("Load of %s did not fail", test)
+		}
+
+		// nothing is loaded from an invalid file
+		var buffer bytes.Buffer
+		if n, _ := m.Save(&buffer); n != 0 {
+			t.Errorf("Load of %s kept %d entries", test, n)
+		}
+	}
+}
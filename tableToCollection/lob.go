@@ -0,0 +1,371 @@
+package tableToCollection
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/lucasgpulcinelli/mongoQLer/oracleManager"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	// DefaultLobThreshold is the largest LOB (in characters for CLOBs and
+	// bytes for BLOBs) kept inline in a document by default, the ones above
+	// it go to GridFS.
+	DefaultLobThreshold = 4 << 20
+
+	// DefaultLobBucket is the GridFS bucket the LOBs go to by default.
+	DefaultLobBucket = "fs"
+)
+
+const (
+	// the extra columns selected for tables with LOBs or without a primary
+	// key: the ROWID of the tuple and the length of each LOB column, in order
+	rowidColumn     = "TUPLE$ROWID"
+	lobLengthPrefix = "LOB$LENGTH$"
+
+	// the size of the pieces read with DBMS_LOB.SUBSTR, which in SQL returns at
+	// most 4000 bytes (up to 4 per character) for CLOBs and 2000 for BLOBs
+	clobPiece = 1000
+	blobPiece = 2000
+)
+
+// quoteColumn gets the name of a column quoted for a query, so that columns
+// with lowercase letters or named after reserved words are read as they are
+// in the catalog.
+func quoteColumn(column string) string {
+	return `"` + column + `"`
+}
+
+// struct LobPolicy represents how the values of a LOB column are converted:
+// the ones up to Threshold stay inline in the document, while the larger ones
+// are streamed to the Bucket in GridFS.
+type LobPolicy struct {
+	Threshold int64
+	Bucket    string
+}
+
+// type LobMapping represents the policies that override the default one
+// (DefaultLobThreshold and DefaultLobBucket) for some LOB columns, with keys
+// such as "TABLE.COLUMN".
+type LobMapping map[string]LobPolicy
+
+// ParseLobMapping parses LOB policy overrides separated by commas or spaces,
+// each one as "TABLE.COLUMN=threshold" or "TABLE.COLUMN=threshold:bucket",
+// such as "T.PHOTO=1048576:photos".
+func ParseLobMapping(text string) (LobMapping, error) {
+	m := LobMapping{}
+
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n'
+	})
+
+	for _, field := range fields {
+		column, value, ok := strings.Cut(field, "=")
+		if !ok || !strings.Contains(column, ".") {
+			return nil, fmt.Errorf("invalid LOB override %q, expected "+
+				"TABLE.COLUMN=threshold:bucket", field,
+			)
+		}
+
+		threshold, bucket, _ := strings.Cut(value, ":")
+		if bucket == "" {
+			bucket = DefaultLobBucket
+		}
+
+		n, err := strconv.ParseInt(threshold, 10, 64)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid LOB threshold %q for %s",
+				threshold, column,
+			)
+		}
+
+		m[strings.ToUpper(column)] = LobPolicy{Threshold: n, Bucket: bucket}
+	}
+
+	return m, nil
+}
+
+// Policy gets the LobPolicy of a column of a table.
+func (m LobMapping) Policy(table, column string) LobPolicy {
+	if policy, ok := m[table+"."+column]; ok {
+		return policy
+	}
+
+	return LobPolicy{Threshold: DefaultLobThreshold, Bucket: DefaultLobBucket}
+}
+
+// struct LobFile represents a LOB above the threshold of its column, to be
+// written to GridFS, with its length (in characters for CLOBs and bytes for
+// BLOBs) and a Reader that gets its content from oracle piece by piece.
+type LobFile struct {
+	Table, Column string
+	Bucket        string
+	Name          string
+	ContentType   string
+	Length        int64
+	Reader        io.Reader
+}
+
+// type LobSink represents where the LOBs above their threshold go, such as a
+// GridFS bucket, returning the id of the file written.
+type LobSink func(file LobFile) (any, error)
+
+// isLob indicates if a column is a CLOB, NCLOB or BLOB.
+func isLob(col oracleManager.ColumnInfo) bool {
+	switch col.DataType {
+	case "CLOB", "NCLOB", "BLOB":
+		return true
+	}
+
+	return false
+}
+
+// isTable indicates if a source is a table, which has a ROWID. Only tables
+// have LOBs read piece by piece (by ROWID), for views and synonyms the whole
+// LOB is read by the driver as before.
+func isTable(catalog oracleManager.Catalog, table string) (bool, error) {
+	sources, err := catalog.GetSources()
+	if err != nil {
+		return false, err
+	}
+
+	for _, src := range sources {
+		if src.Name == table && src.Kind == oracleManager.SourceTable {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// SelectTable gets the query that reads all tuples of a table to be converted
+// by StreamCollection. For tables with LOB columns, the LOBs above their
+// threshold are read as NULL, with the length of each LOB in extra columns,
+// so that they are not read by the driver at once but streamed to GridFS
+// later. These tables and the ones without a primary key also get the ROWID
+// of the tuple, which identifies it.
+func SelectTable(
+	catalog oracleManager.Catalog, table string, lobs LobMapping,
+) (string, error) {
+	from := " FROM " + oracleManager.QualifiedName(catalog.GetOwner(), table)
+
+	ok, err := isTable(catalog, table)
+	if err != nil {
+		return "", err
+	}
+
+	hasLobs := false
+	for _, col := range catalog.GetColumns(table) {
+		hasLobs = hasLobs || isLob(col)
+	}
+
+	if !ok || !hasLobs && len(catalog.GetPrimaryKeys(table)) != 0 {
+		return "SELECT *" + from, nil
+	}
+
+	columns := []string{}
+	lengths := []string{}
+	for _, col := range catalog.GetColumns(table) {
+		name := quoteColumn(col.Name)
+		if !isLob(col) {
+			columns = append(columns, name)
+			continue
+		}
+
+		length := "DBMS_LOB.GETLENGTH(" + name + ")"
+		columns = append(columns, fmt.Sprintf(
+			"CASE WHEN %s <= %d THEN %s END AS %s",
+			length, lobs.Policy(table, col.Name).Threshold, name, name,
+		))
+		lengths = append(lengths, fmt.Sprintf(`%s AS "%s%s"`,
+			length, lobLengthPrefix, col.Name,
+		))
+	}
+
+	columns = append(columns, lengths...)
+	columns = append(columns,
+		fmt.Sprintf(`ROWIDTOCHAR(ROWID) AS "%s"`, rowidColumn),
+	)
+
+	return "SELECT " + strings.Join(columns, ", ") + from, nil
+}
+
+// isExtraColumn indicates if a column was added by SelectTable.
+func isExtraColumn(column string) bool {
+	return column == rowidColumn || strings.HasPrefix(column, lobLengthPrefix)
+}
+
+// largeLob gets the length and ROWID of the LOB in a column of a tuple, if it
+// was left out of the tuple by SelectTable for being above the threshold.
+func largeLob(cols []string, vs []any, column string) (int64, string, bool) {
+	var length, rowid any
+	for i, col := range cols {
+		switch col {
+		case lobLengthPrefix + column:
+			length = vs[i]
+		case rowidColumn:
+			rowid = vs[i]
+		}
+	}
+
+	// NULL LOBs have no length, and inline ones are in the column itself
+	for i, col := range cols {
+		if col == column && vs[i] != nil {
+			return 0, "", false
+		}
+	}
+
+	n, err := toInt64(length)
+	if length == nil || rowid == nil || err != nil {
+		return 0, "", false
+	}
+
+	return n, fmt.Sprint(rowid), true
+}
+
+// struct lobReader represents an io.Reader of a LOB in oracle. The LOB is read
+// with a single query that gets it one piece (of DBMS_LOB.SUBSTR) per row, so
+// that the driver fetches many pieces at a time from the same cursor, without
+// having the whole LOB in memory.
+type lobReader struct {
+	db    *sql.DB
+	query string
+	args  []any
+
+	rows   *sql.Rows
+	pieces int
+	buffer []byte
+}
+
+// newLobReader creates the lobReader of the LOB in a column of the tuple of a
+// table with a ROWID.
+func newLobReader(
+	db *sql.DB, catalog oracleManager.Catalog, table string,
+	col oracleManager.ColumnInfo, length int64, rowid string,
+) *lobReader {
+	piece := int64(clobPiece)
+	if col.DataType == "BLOB" {
+		piece = blobPiece
+	}
+
+	// the pieces are numbered by a row generator joined with the tuple
+	query := "SELECT DBMS_LOB.SUBSTR(L." + quoteColumn(col.Name) +
+		", :0, (P.N - 1) * :1 + 1) FROM " +
+		oracleManager.QualifiedName(catalog.GetOwner(), table) + " L, " +
+		"(SELECT LEVEL AS N FROM DUAL CONNECT BY LEVEL <= :2) P " +
+		"WHERE L.ROWID = CHARTOROWID(:3) ORDER BY P.N"
+
+	return &lobReader{
+		db:    db,
+		query: query,
+		args:  []any{piece, piece, (length + piece - 1) / piece, rowid},
+	}
+}
+
+// Read implements the io.Reader interface.
+func (r *lobReader) Read(p []byte) (int, error) {
+	for len(r.buffer) == 0 {
+		if r.rows == nil {
+			rows, err := r.db.Query(r.query, r.args...)
+			if err != nil {
+				return 0, err
+			}
+			r.rows = rows
+		}
+
+		if !r.rows.Next() {
+			err := r.rows.Err()
+			if err == nil {
+				err = io.EOF
+			}
+
+			r.Close()
+			return 0, err
+		}
+
+		var v any
+		err := r.rows.Scan(&v)
+		if err != nil {
+			return 0, err
+		}
+
+		switch data := v.(type) {
+		case string:
+			r.buffer = []byte(data)
+		case []byte:
+			r.buffer = data
+		default:
+			return 0, fmt.Errorf("LOB piece %d is empty", r.pieces)
+		}
+
+		r.pieces++
+	}
+
+	n := copy(p, r.buffer)
+	r.buffer = r.buffer[n:]
+
+	return n, nil
+}
+
+// Close implements the io.Closer interface, closing the query of the LOB if
+// it was not read until the end.
+func (r *lobReader) Close() error {
+	if r.rows == nil {
+		return nil
+	}
+
+	err := r.rows.Close()
+	r.rows = nil
+	return err
+}
+
+// offloadLob sends a LOB above the threshold of its column to the sink,
+// returning the value that replaces it in the document: the id of the file,
+// its bucket, length and content type.
+func offloadLob(
+	db *sql.DB, catalog oracleManager.Catalog, table string,
+	col oracleManager.ColumnInfo, length int64, rowid string,
+	lobs LobMapping, sink LobSink,
+) (bson.D, error) {
+	policy := lobs.Policy(table, col.Name)
+
+	if sink == nil {
+		return nil, fmt.Errorf(
+			"%s.%s has a LOB of length %d, above the threshold of %d: it can "+
+				"only be streamed to GridFS when applying to mongoDB",
+			table, col.Name, length, policy.Threshold,
+		)
+	}
+
+	reader := newLobReader(db, catalog, table, col, length, rowid)
+	defer reader.Close()
+
+	file := LobFile{
+		Table:       table,
+		Column:      col.Name,
+		Bucket:      policy.Bucket,
+		Name:        table + "." + col.Name + "." + rowid,
+		ContentType: "application/octet-stream",
+		Length:      length,
+		Reader:      reader,
+	}
+	if col.DataType != "BLOB" {
+		file.ContentType = "text/plain; charset=utf-8"
+	}
+
+	id, err := sink(file)
+	if err != nil {
+		return nil, fmt.Errorf("streaming %s to GridFS: %w", file.Name, err)
+	}
+
+	return bson.D{
+		{Key: "fileId", Value: id},
+		{Key: "bucket", Value: file.Bucket},
+		{Key: "length", Value: length},
+		{Key: "contentType", Value: file.ContentType},
+	}, nil
+}
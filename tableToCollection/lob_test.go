@@ -0,0 +1,97 @@
+package tableToCollection
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSelectTable(t *testing.T) {
+	tests := []struct {
+		table string
+		lobs  LobMapping
+		want  string
+	}{
+		{"DEPT", nil, `SELECT * FROM "DEPT"`},
+		{
+			"T", nil,
+			`SELECT "ID", "BIG", "HUGE", "PRICE", "RATE", "CODE", "NAME", ` +
+				`"BORN", "TS_OFFSET", "GUID", "DATA", CASE WHEN ` +
+				`DBMS_LOB.GETLENGTH("DOC") <= 4194304 THEN "DOC" END AS "DOC", ` +
+				`"X", DBMS_LOB.GETLENGTH("DOC") AS "LOB$LENGTH$DOC", ` +
+				`ROWIDTOCHAR(ROWID) AS "TUPLE$ROWID" FROM "T"`,
+		},
+		{
+			"T", LobMapping{"T.DOC": {Threshold: 10, Bucket: "docs"}},
+			`SELECT "ID", "BIG", "HUGE", "PRICE", "RATE", "CODE", "NAME", ` +
+				`"BORN", "TS_OFFSET", "GUID", "DATA", CASE WHEN ` +
+				`DBMS_LOB.GETLENGTH("DOC") <= 10 THEN "DOC" END AS "DOC", ` +
+				`"X", DBMS_LOB.GETLENGTH("DOC") AS "LOB$LENGTH$DOC", ` +
+				`ROWIDTOCHAR(ROWID) AS "TUPLE$ROWID" FROM "T"`,
+		},
+	}
+
+	c := testCatalog(t)
+	for _, test := range tests {
+		got, err := SelectTable(c, test.table, test.lobs)
+		if err != nil {
+			t.Fatalf("SelectTable failed: %v", err)
+		}
+
+		if got != test.want {
+			t.Errorf("SelectTable of %s got\n%s\nwant\n%s",
+				test.table, got, test.want,
+			)
+		}
+	}
+}
+
+func TestSelectTableWithoutPk(t *testing.T) {
+	c := testCatalog(t)
+	delete(c.PrimaryKeys, "DEPT")
+
+	got, err := SelectTable(c, "DEPT", nil)
+	want := `SELECT "ID", "NAME", ROWIDTOCHAR(ROWID) AS "TUPLE$ROWID" FROM "DEPT"`
+	if err != nil || got != want {
+		t.Errorf("SelectTable got %s, %v, want %s", got, err, want)
+	}
+}
+
+func TestParseLobMapping(t *testing.T) {
+	tests := []struct {
+		text string
+		want LobMapping
+	}{
+		{"", LobMapping{}},
+		{
+			"t.doc=10, T.PHOTO=1048576:photos",
+			LobMapping{
+				"T.DOC":   {Threshold: 10, Bucket: DefaultLobBucket},
+				"T.PHOTO": {Threshold: 1048576, Bucket: "photos"},
+			},
+		},
+		{"DOC=10", nil},
+		{"T.DOC", nil},
+		{"T.DOC=-1", nil},
+		{"T.DOC=big:docs", nil},
+	}
+
+	for _, test := range tests {
+		got, err := ParseLobMapping(test.text)
+		if test.want == nil {
+			if err == nil {
+				t.Errorf("ParseLobMapping(%q) did not fail", test.text)
+			}
+			continue
+		}
+
+		if err != nil || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ParseLobMapping(%q) got %v, %v", test.text, got, err)
+		}
+	}
+
+	policy := LobMapping{}.Policy("T", "DOC")
+	if policy.Threshold != DefaultLobThreshold ||
+		policy.Bucket != DefaultLobBucket {
+		t.Errorf("default LobPolicy got %v", policy)
+	}
+}
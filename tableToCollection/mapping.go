@@ -0,0 +1,358 @@
+package tableToCollection
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lucasgpulcinelli/mongoQLer/oracleManager"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// type TypePolicy represents how the values of an oracle column are converted
+// to BSON, instead of keeping whatever the driver scanned.
+type TypePolicy int
+
+const (
+	PolicyAuto       TypePolicy = iota // chosen from the column metadata
+	PolicyDriver                       // the value scanned by the driver
+	PolicyInt                          // int32
+	PolicyLong                         // int64
+	PolicyDecimal                      // Decimal128, rounded to the scale
+	PolicyDouble                       // double
+	PolicyString                       // string
+	PolicyTrimmed                      // string without the CHAR padding
+	PolicyDate                         // datetime
+	PolicyDateOffset                   // datetime, plus a COLUMN_OFFSET field
+	PolicyUUID                         // binary with the UUID subtype
+	PolicyBinary                       // binary with the generic subtype
+)
+
+// policyNames are the names of the policies, as written by the user.
+var policyNames = map[string]TypePolicy{
+	"auto":       PolicyAuto,
+	"driver":     PolicyDriver,
+	"int":        PolicyInt,
+	"long":       PolicyLong,
+	"decimal":    PolicyDecimal,
+	"double":     PolicyDouble,
+	"string":     PolicyString,
+	"trimmed":    PolicyTrimmed,
+	"date":       PolicyDate,
+	"dateOffset": PolicyDateOffset,
+	"uuid":       PolicyUUID,
+	"binary":     PolicyBinary,
+}
+
+// OffsetSuffix is added to the name of a column converted with
+// PolicyDateOffset for the field with its time zone offset, such as "-03:00".
+const OffsetSuffix = "_OFFSET"
+
+// DefaultPolicy gets the policy used for a column when it is not overridden:
+//   - NUMBER(p, 0) is an int for up to 9 digits, a long for up to 18 digits
+//     (or INTEGER) and a decimal for more;
+//   - NUMBER(p, s) is a decimal, and NUMBER, FLOAT and BINARY_* are doubles;
+//   - DATE and TIMESTAMP are dates, with the offset for WITH TIME ZONE;
+//   - RAW(16) is an UUID, other RAWs and BLOBs are binary;
+//   - CHAR is a trimmed string, other text and INTERVALs are strings.
+//
+// Columns with a data type that is not known keep the driver value.
+func DefaultPolicy(col oracleManager.ColumnInfo) TypePolicy {
+	switch dataType := col.DataType; {
+	case dataType == "NUMBER" && col.Scale != nil && *col.Scale > 0:
+		return PolicyDecimal
+
+	case dataType == "NUMBER" && col.Scale != nil:
+		if col.Precision == nil {
+			return PolicyLong
+		}
+
+		switch digits := *col.Precision - *col.Scale; {
+		case digits <= 9:
+			return PolicyInt
+		case digits <= 18:
+			return PolicyLong
+		}
+		return PolicyDecimal
+
+	case dataType == "NUMBER" || dataType == "FLOAT" ||
+		dataType == "BINARY_FLOAT" || dataType == "BINARY_DOUBLE":
+		return PolicyDouble
+
+	case strings.HasSuffix(dataType, "WITH TIME ZONE") &&
+		!strings.HasSuffix(dataType, "LOCAL TIME ZONE"):
+		return PolicyDateOffset
+
+	case dataType == "DATE" || strings.HasPrefix(dataType, "TIMESTAMP"):
+		return PolicyDate
+
+	case dataType == "RAW" && col.DataLength == 16:
+		return PolicyUUID
+
+	case dataType == "RAW" || dataType == "LONG RAW" || dataType == "BLOB":
+		return PolicyBinary
+
+	case dataType == "CHAR" || dataType == "NCHAR":
+		return PolicyTrimmed
+
+	case dataType == "VARCHAR2" || dataType == "NVARCHAR2" ||
+		dataType == "CLOB" || dataType == "NCLOB" || dataType == "LONG" ||
+		strings.HasPrefix(dataType, "INTERVAL"):
+		return PolicyString
+	}
+
+	return PolicyDriver
+}
+
+// policyTypes gets the BSON types (as $type aliases) of the values converted
+// with a policy, not including null.
+func policyTypes(policy TypePolicy) []string {
+	switch policy {
+	case PolicyInt, PolicyLong, PolicyDecimal, PolicyDouble:
+		return []string{"number"}
+	case PolicyString, PolicyTrimmed:
+		return []string{"string"}
+	case PolicyDate, PolicyDateOffset:
+		return []string{"date"}
+	case PolicyUUID, PolicyBinary:
+		return []string{"binData"}
+	}
+
+	return nonNullTypes
+}
+
+// type TypeMapping represents the policies that override the DefaultPolicy of
+// some columns, with keys such as "TABLE.COLUMN".
+type TypeMapping map[string]TypePolicy
+
+// ParseTypeMapping parses policy overrides separated by commas or spaces, each
+// one as "TABLE.COLUMN=policy", such as "T.PRICE=double, T.CODE=string".
+func ParseTypeMapping(text string) (TypeMapping, error) {
+	m := TypeMapping{}
+
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n'
+	})
+
+	for _, field := range fields {
+		column, name, ok := strings.Cut(field, "=")
+		if !ok || !strings.Contains(column, ".") {
+			return nil, fmt.Errorf(
+				"invalid type override %q, expected TABLE.COLUMN=policy", field,
+			)
+		}
+
+		policy, ok := policyNames[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown type policy %q for %s", name, column)
+		}
+
+		m[strings.ToUpper(column)] = policy
+	}
+
+	return m, nil
+}
+
+// Policy gets the policy for a column of a table, which is its override or
+// its DefaultPolicy. Columns not in the catalog (such as from queries) keep
+// the driver value unless overridden.
+func (m TypeMapping) Policy(
+	catalog oracleManager.Catalog, table, column string,
+) (TypePolicy, oracleManager.ColumnInfo) {
+	col, _ := oracleManager.GetColumn(catalog, table, column)
+
+	if policy := m[table+"."+column]; policy != PolicyAuto {
+		return policy, col
+	}
+
+	return DefaultPolicy(col), col
+}
+
+// Fields gets the fields of a document for the value of a column, converted
+// with its policy: the column itself and, for PolicyDateOffset, the time zone
+// offset in another field.
+func (m TypeMapping) Fields(
+	catalog oracleManager.Catalog, table, column string, v any,
+) (bson.D, error) {
+	policy, col := m.Policy(catalog, table, column)
+
+	value, err := convertValue(policy, col, v)
+	if err != nil {
+		return nil, fmt.Errorf("column %s.%s: %w", table, column, err)
+	}
+
+	fields := bson.D{{Key: column, Value: value}}
+
+	if t, ok := v.(time.Time); ok && policy == PolicyDateOffset {
+		_, offset := t.Zone()
+
+		sign := '+'
+		if offset < 0 {
+			sign, offset = '-', -offset
+		}
+
+		fields = append(fields, bson.E{
+			Key: column + OffsetSuffix,
+			Value: fmt.Sprintf("%c%02d:%02d",
+				sign, offset/3600, offset%3600/60,
+			),
+		})
+	}
+
+	return fields, nil
+}
+
+// convertValue converts a value scanned by the driver with a policy. NULL is
+// kept as nil for any policy.
+func convertValue(
+	policy TypePolicy, col oracleManager.ColumnInfo, v any,
+) (any, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	switch policy {
+	case PolicyInt:
+		i, err := toInt64(v)
+		if err != nil {
+			return nil, err
+		}
+		if i < math.MinInt32 || i > math.MaxInt32 {
+			return nil, fmt.Errorf("value %d does not fit in an int", i)
+		}
+		return int32(i), nil
+
+	case PolicyLong:
+		return toInt64(v)
+
+	case PolicyDecimal:
+		return toDecimal(col, v)
+
+	case PolicyDouble:
+		return toFloat64(v)
+
+	case PolicyString:
+		return toString(v), nil
+
+	case PolicyTrimmed:
+		return strings.TrimRight(toString(v), " "), nil
+
+	case PolicyDate, PolicyDateOffset:
+		t, ok := v.(time.Time)
+		if !ok {
+			return nil, fmt.Errorf("value %v of type %T is not a date", v, v)
+		}
+		return primitive.NewDateTimeFromTime(t), nil
+
+	case PolicyUUID:
+		bs, ok := v.([]byte)
+		if !ok || len(bs) != 16 {
+			return nil, fmt.Errorf("value %v is not a 16 byte UUID", v)
+		}
+		return primitive.Binary{Subtype: bson.TypeBinaryUUID, Data: bs}, nil
+
+	case PolicyBinary:
+		switch bs := v.(type) {
+		case []byte:
+			return primitive.Binary{Subtype: bson.TypeBinaryGeneric, Data: bs}, nil
+		case string:
+			return primitive.Binary{
+				Subtype: bson.TypeBinaryGeneric, Data: []byte(bs),
+			}, nil
+		}
+		return nil, fmt.Errorf("value %v of type %T is not binary", v, v)
+	}
+
+	return v, nil
+}
+
+// toInt64 converts a numeric value (or its text) to an int64, as long as it
+// is an integer.
+func toInt64(v any) (int64, error) {
+	switch n := v.(type) {
+	case int64:
+		return n, nil
+	case uint64:
+		if n <= math.MaxInt64 {
+			return int64(n), nil
+		}
+	case float64:
+		if n == math.Trunc(n) && n >= math.MinInt64 && n < math.MaxInt64 {
+			return int64(n), nil
+		}
+	case string:
+		i, err := strconv.ParseInt(strings.TrimSpace(n), 10, 64)
+		if err == nil {
+			return i, nil
+		}
+	}
+
+	return 0, fmt.Errorf("value %v of type %T is not a long", v, v)
+}
+
+// toFloat64 converts a numeric value (or its text) to a float64.
+func toFloat64(v any) (float64, error) {
+	switch n := v.(type) {
+	case float64:
+		return n, nil
+	case float32:
+		return float64(n), nil
+	case int64:
+		return float64(n), nil
+	case uint64:
+		return float64(n), nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		if err == nil {
+			return f, nil
+		}
+	}
+
+	return 0, fmt.Errorf("value %v of type %T is not a double", v, v)
+}
+
+// toDecimal converts a numeric value (or its text) to a Decimal128. Doubles
+// are rounded to the scale of the column, which undoes the error of the
+// driver reading a NUMBER(p, s) as a double when p is at most 15.
+func toDecimal(col oracleManager.ColumnInfo, v any) (any, error) {
+	var s string
+
+	switch n := v.(type) {
+	case float64:
+		if col.Scale != nil && *col.Scale >= 0 {
+			s = strconv.FormatFloat(n, 'f', int(*col.Scale), 64)
+		} else {
+			s = strconv.FormatFloat(n, 'g', -1, 64)
+		}
+	case int64, uint64:
+		s = fmt.Sprint(n)
+	case string:
+		s = strings.TrimSpace(n)
+	default:
+		return nil, fmt.Errorf("value %v of type %T is not a decimal", v, v)
+	}
+
+	d, err := primitive.ParseDecimal128(s)
+	if err != nil {
+		return nil, fmt.Errorf("value %v is not a decimal: %w", v, err)
+	}
+
+	return d, nil
+}
+
+// toString converts a value to its text, with dates in RFC 3339.
+func toString(v any) string {
+	switch s := v.(type) {
+	case string:
+		return s
+	case []byte:
+		return string(s)
+	case time.Time:
+		return s.Format(time.RFC3339Nano)
+	}
+
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,171 @@
+package tableToCollection
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lucasgpulcinelli/mongoQLer/oracleManager"
+)
+
+func TestDefaultPolicy(t *testing.T) {
+	tests := map[string]TypePolicy{
+		"ID":        PolicyInt,
+		"BIG":       PolicyLong,
+		"HUGE":      PolicyDecimal,
+		"PRICE":     PolicyDecimal,
+		"RATE":      PolicyDouble,
+		"CODE":      PolicyTrimmed,
+		"NAME":      PolicyString,
+		"BORN":      PolicyDate,
+		"TS_OFFSET": PolicyDateOffset,
+		"GUID":      PolicyUUID,
+		"DATA":      PolicyBinary,
+		"DOC":       PolicyString,
+		"X":         PolicyDriver,
+	}
+
+	c := testCatalog(t)
+	for column, want := range tests {
+		col, ok := oracleManager.GetColumn(c, "T", column)
+		if !ok {
+			t.Fatalf("column %s not in the test catalog", column)
+		}
+
+		if got := DefaultPolicy(col); got != want {
+			t.Errorf("DefaultPolicy of %s (%s) got %d, want %d",
+				column, col.DataType, got, want,
+			)
+		}
+	}
+}
+
+func TestParseTypeMapping(t *testing.T) {
+	tests := []struct {
+		text string
+		want TypeMapping
+	}{
+		{"", TypeMapping{}},
+		{
+			"t.price=double, T.CODE=string\nT.ID=auto",
+			TypeMapping{
+				"T.PRICE": PolicyDouble, "T.CODE": PolicyString,
+				"T.ID": PolicyAuto,
+			},
+		},
+		{"T.TS_OFFSET=dateOffset", TypeMapping{"T.TS_OFFSET": PolicyDateOffset}},
+		{"PRICE=double", nil},
+		{"T.PRICE", nil},
+		{"T.PRICE=money", nil},
+	}
+
+	for _, test := range tests {
+		got, err := ParseTypeMapping(test.text)
+		if test.want == nil {
+			if err == nil {
+				t.Errorf("ParseTypeMapping(%q) did not fail", test.text)
+			}
+			continue
+		}
+
+		if err != nil || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ParseTypeMapping(%q) got %v, %v", test.text, got, err)
+		}
+	}
+}
+
+func TestFields(t *testing.T) {
+	zone := time.FixedZone("", -(3*3600 + 30*60))
+	date := time.Date(2020, 1, 2, 3, 4, 5, 0, zone)
+	types := TypeMapping{
+		"T.ID": PolicyString, "T.PRICE": PolicyDouble, "T.BORN": PolicyString,
+	}
+
+	tests := []struct {
+		types  TypeMapping
+		column string
+		value  any
+		want   string
+	}{
+		{nil, "ID", 10.0, `{"ID":10}`},
+		{nil, "BIG", "123456789012", `{"BIG":123456789012}`},
+		{nil, "PRICE", 19.990000000000002,
+			`{"PRICE":{"$numberDecimal":"19.99"}}`},
+		{nil, "HUGE", "123456789012345678901234567890",
+			`{"HUGE":{"$numberDecimal":"123456789012345678901234567890"}}`},
+		{nil, "RATE", int64(2), `{"RATE":2.0}`},
+		{nil, "CODE", "AB   ", `{"CODE":"AB"}`},
+		{nil, "NAME", nil, `{"NAME":null}`},
+		{nil, "BORN", date, `{"BORN":{"$date":"2020-01-02T06:34:05Z"}}`},
+		{nil, "TS_OFFSET", date, `{"TS_OFFSET":{"$date":` +
+			`"2020-01-02T06:34:05Z"},"TS_OFFSET_OFFSET":"-03:30"}`},
+		{nil, "GUID", make([]byte, 16), `{"GUID":{"$binary":{"base64":` +
+			`"AAAAAAAAAAAAAAAAAAAAAA==","subType":"04"}}}`},
+		{nil, "DATA", "ab",
+			`{"DATA":{"$binary":{"base64":"YWI=","subType":"00"}}}`},
+		{types, "ID", int64(10), `{"ID":"10"}`},
+		{types, "PRICE", "19.99", `{"PRICE":19.99}`},
+		{types, "BORN", date, `{"BORN":"2020-01-02T03:04:05-03:30"}`},
+	}
+
+	c := testCatalog(t)
+	for _, test := range tests {
+		got, err := test.types.Fields(c, "T", test.column, test.value)
+		if err != nil {
+			t.Errorf("Fields of %s failed: %v", test.column, err)
+			continue
+		}
+
+		if s := extJSON(t, got); s != test.want {
+			t.Errorf("Fields of %s got %s, want %s", test.column, s, test.want)
+		}
+	}
+}
+
+func TestFieldsErrors(t *testing.T) {
+	tests := []struct {
+		column string
+		value  any
+	}{
+		{"ID", 1.5},
+		{"ID", int64(1) << 40},
+		{"BORN", "2020-01-02"},
+		{"GUID", []byte{1, 2}},
+		{"PRICE", "abc"},
+	}
+
+	c := testCatalog(t)
+	for _, test := range tests {
+		_, err := TypeMapping(nil).Fields(c, "T", test.column, test.value)
+		if err == nil {
+			t.Errorf("Fields of %s with %v did not fail", test.column, test.value)
+		}
+	}
+}
+
+func TestBsonTypes(t *testing.T) {
+	types := TypeMapping{"T.ID": PolicyString, "T.DOC": PolicyBinary}
+
+	tests := []struct {
+		types  TypeMapping
+		column string
+		want   []string
+	}{
+		{nil, "ID", []string{"number"}},
+		{nil, "DOC", []string{"string", "object"}},
+		{nil, "BORN", []string{"date"}},
+		{nil, "X", nonNullTypes},
+		{types, "ID", []string{"string"}},
+		{types, "DOC", []string{"binData", "object"}},
+	}
+
+	c := testCatalog(t)
+	for _, test := range tests {
+		got := test.types.BsonTypes(c, "T", test.column)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("BsonTypes of %s got %v, want %v",
+				test.column, got, test.want,
+			)
+		}
+	}
+}
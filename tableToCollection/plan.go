@@ -0,0 +1,110 @@
+package tableToCollection
+
+import (
+	"fmt"
+
+	"github.com/lucasgpulcinelli/mongoQLer/oracleManager"
+)
+
+// struct embedEdge represents a table whose documents embed the documents of
+// another because of a reference.
+type embedEdge struct {
+	from, to string
+	ref      string
+}
+
+// ValidatePlan checks that an embedding plan does not embed a table in itself,
+// directly or through other tables, which would make the embedding recursion
+// never end. The references in embedsTo embed the referenced table in the
+// referencer, and the ones in embedsFrom embed the referencer table in the
+// referenced. The error describes the path of the cycle found.
+func ValidatePlan(embedsTo, embedsFrom []oracleManager.Reference) error {
+	edges := map[string][]embedEdge{}
+	tables := []string{}
+
+	addEdge := func(e embedEdge) {
+		if _, ok := edges[e.from]; !ok {
+			tables = append(tables, e.from)
+		}
+		edges[e.from] = append(edges[e.from], e)
+	}
+
+	for _, ref := range embedsTo {
+		addEdge(embedEdge{ref.TableReferencer, ref.TableReferenced,
+			ref.ConstraintName,
+		})
+	}
+	for _, ref := range embedsFrom {
+		addEdge(embedEdge{ref.TableReferenced, ref.TableReferencer,
+			ref.ConstraintName,
+		})
+	}
+
+	// depth first search, where a table still in the path is a cycle
+	const (
+		unvisited = iota
+		inPath
+		done
+	)
+
+	state := map[string]int{}
+	path := []embedEdge{}
+
+	var visit func(table string) error
+	visit = func(table string) error {
+		state[table] = inPath
+
+		for _, e := range edges[table] {
+			switch state[e.to] {
+			case inPath:
+				// the cycle starts where the path left e.to, or now, if e.to is
+				// the current table
+				start := len(path)
+				for i, p := range path {
+					if p.from == e.to {
+						start = i
+						break
+					}
+				}
+
+				return fmt.Errorf("embedding plan has a cycle: %s",
+					describePath(append(path[start:], e)),
+				)
+
+			case unvisited:
+				path = append(path, e)
+				err := visit(e.to)
+				if err != nil {
+					return err
+				}
+				path = path[:len(path)-1]
+			}
+		}
+
+		state[table] = done
+		return nil
+	}
+
+	for _, table := range tables {
+		if state[table] != unvisited {
+			continue
+		}
+
+		err := visit(table)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// describePath gets a path of embeddings as text, such as "A -(FK1)-> B".
+func describePath(path []embedEdge) string {
+	s := path[0].from
+	for _, e := range path {
+		s += fmt.Sprintf(" -(%s)-> %s", e.ref, e.to)
+	}
+
+	return s
+}
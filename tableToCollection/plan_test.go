@@ -0,0 +1,74 @@
+package tableToCollection
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lucasgpulcinelli/mongoQLer/oracleManager"
+)
+
+func TestValidatePlan(t *testing.T) {
+	tests := []struct {
+		name       string
+		embedsTo   []oracleManager.Reference
+		embedsFrom []oracleManager.Reference
+		cycle      string
+	}{
+		{
+			name:     "chain",
+			embedsTo: []oracleManager.Reference{ref("B_A", "B", "A")},
+			embedsFrom: []oracleManager.Reference{
+				ref("C_A", "C", "A"), ref("D_C", "D", "C"),
+			},
+		},
+		{
+			name: "same table embedded twice",
+			embedsTo: []oracleManager.Reference{
+				ref("C_E", "C", "E"), ref("D_E", "D", "E"),
+			},
+			embedsFrom: []oracleManager.Reference{
+				ref("C_A", "C", "A"), ref("D_A", "D", "A"),
+			},
+		},
+		{
+			name:     "self reference",
+			embedsTo: []oracleManager.Reference{ref("A_A", "A", "A")},
+			cycle:    "A -(A_A)-> A",
+		},
+		{
+			name:       "both directions of a reference",
+			embedsTo:   []oracleManager.Reference{ref("B_A", "B", "A")},
+			embedsFrom: []oracleManager.Reference{ref("B_A", "B", "A")},
+			cycle:      "B -(B_A)-> A -(B_A)-> B",
+		},
+		{
+			name: "longer cycle",
+			embedsTo: []oracleManager.Reference{
+				ref("X_A", "X", "A"), ref("A_B", "A", "B"),
+			},
+			embedsFrom: []oracleManager.Reference{ref("X_B", "X", "B")},
+			cycle:      "X -(X_A)-> A -(A_B)-> B -(X_B)-> X",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidatePlan(test.embedsTo, test.embedsFrom)
+
+			if test.cycle == "" {
+				if err != nil {
+					t.Errorf("ValidatePlan failed: %v", err)
+				}
+				return
+			}
+
+			if err == nil ||
+				!strings.HasPrefix(err.Error(), "embedding plan has a cycle") {
+				t.Fatalf("ValidatePlan got %v, want a cycle", err)
+			}
+			if !strings.Contains(err.Error(), test.cycle) {
+				t.Errorf("ValidatePlan got %v, want the cycle %s", err, test.cycle)
+			}
+		})
+	}
+}
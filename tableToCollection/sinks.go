@@ -0,0 +1,50 @@
+package tableToCollection
+
+import (
+	"bufio"
+	"io"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// WriteJSONLines writes the documents read with next to w as canonical
+// extended JSON, one document per line, as read by mongoimport. Canonical
+// extended JSON keeps the BSON types, such as int64 numbers that would
+// otherwise be imported as int32. It returns how many documents were written.
+func WriteJSONLines(w io.Writer, next func() (bson.D, bool)) (int, error) {
+	bw := bufio.NewWriter(w)
+	n := 0
+
+	for doc, ok := next(); ok; doc, ok = next() {
+		bs, err := bson.MarshalExtJSON(doc, true, false)
+		if err != nil {
+			return n, err
+		}
+
+		_, err = bw.Write(append(bs, '\n'))
+		if err != nil {
+			return n, err
+		}
+
+		n++
+	}
+
+	return n, bw.Flush()
+}
+
+// Preview reads at most n documents with next, for showing only the start of
+// a big collection.
+func Preview(next func() (bson.D, bool), n int) []bson.D {
+	docs := []bson.D{}
+
+	for len(docs) < n {
+		doc, ok := next()
+		if !ok {
+			break
+		}
+
+		docs = append(docs, doc)
+	}
+
+	return docs
+}
@@ -0,0 +1,38 @@
+package tableToCollection
+
+import (
+	"bytes"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestWriteJSONLines(t *testing.T) {
+	docs := []bson.D{
+		{{Key: "ID", Value: int64(3)}, {Key: "N", Value: int32(4)}},
+		{{Key: "PRICE", Value: 1.5}, {Key: "NAME", Value: "A"}},
+	}
+
+	i := 0
+	next := func() (bson.D, bool) {
+		if i == len(docs) {
+			return nil, false
+		}
+
+		i++
+		return docs[i-1], true
+	}
+
+	w := &bytes.Buffer{}
+	n, err := WriteJSONLines(w, next)
+	if err != nil || n != 2 {
+		t.Fatalf("WriteJSONLines got %d, %v", n, err)
+	}
+
+	// the types must survive mongoimport, even for small int64 numbers
+	want := `{"ID":{"$numberLong":"3"},"N":{"$numberInt":"4"}}` + "\n" +
+		`{"PRICE":{"$numberDouble":"1.5"},"NAME":"A"}` + "\n"
+	if w.String() != want {
+		t.Errorf("WriteJSONLines got\n%s\nwant\n%s", w.String(), want)
+	}
+}
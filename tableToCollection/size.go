@@ -0,0 +1,429 @@
+package tableToCollection
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lucasgpulcinelli/mongoQLer/oracleManager"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaxDocumentSize is the largest encoded document that mongoDB accepts.
+const MaxDocumentSize = 16 << 20
+
+// OverflowSuffix is added to the name of an embedded array that did not fit
+// in its document, for the field that tells where the rest of it is.
+const OverflowSuffix = "_OVERFLOW"
+
+// type SizeStrategy represents what is done with documents above
+// MaxDocumentSize because of the arrays embedded in them.
+type SizeStrategy int
+
+const (
+	// SizeFail fails the conversion, reporting the size of the document and
+	// of its embedded arrays.
+	SizeFail SizeStrategy = iota
+
+	// SizeCap keeps in the array the documents that fit, and only the _id of
+	// the others in the overflow field.
+	SizeCap
+
+	// SizeBucket keeps in the array the documents that fit, and the others in
+	// bucket documents of a side collection (the subset pattern), with the
+	// overflow field telling the collection and number of buckets. The buckets
+	// are identified by the _id of their parent, so documents without one
+	// cannot be split.
+	SizeBucket
+)
+
+// type OverflowSink represents where the bucket documents of SizeBucket go,
+// such as a collection in mongoDB.
+type OverflowSink func(collection string, doc bson.D) error
+
+// struct embeddedArray represents an array embedded in a document, with the
+// encoded size of each of its elements (as in the array).
+type embeddedArray struct {
+	field int
+	name  string
+	docs  []bson.D
+	sizes []int
+	total int
+}
+
+// emptyDocumentSize is the encoded size of an empty document or array: its
+// length and the terminating byte.
+const emptyDocumentSize = 5
+
+// fieldSize gets the encoded size of an element of a document or array from
+// its key and the encoded size of its value: the type byte, the key as a C
+// string and the value.
+func fieldSize(key string, size int) int {
+	return 1 + len(key) + 1 + size
+}
+
+// valueSize gets the encoded size of a value, without encoding the strings
+// and binaries, which are the values that can be large.
+func valueSize(v any) (int, error) {
+	switch vv := v.(type) {
+	case nil:
+		return 0, nil
+	case string:
+		return 4 + len(vv) + 1, nil
+	case primitive.Binary:
+		if vv.Subtype != bson.TypeBinaryBinaryOld {
+			return 4 + 1 + len(vv.Data), nil
+		}
+	}
+
+	_, bs, err := bson.MarshalValue(v)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(bs), nil
+}
+
+// elementSize gets the encoded size of a value as an element of a document
+// or array, with its key.
+func elementSize(key string, v any) (int, error) {
+	size, err := valueSize(v)
+	if err != nil {
+		return 0, err
+	}
+
+	return fieldSize(key, size), nil
+}
+
+// embeddedArrays gets the arrays embedded in a document of a table, from the
+// references in embedsFrom, in order of decreasing size.
+func embeddedArrays(
+	doc bson.D, table string, embedsFrom []oracleManager.Reference,
+) ([]embeddedArray, error) {
+	result := []embeddedArray{}
+
+	for i, e := range doc {
+		docs, ok := e.Value.([]bson.D)
+		if !ok {
+			continue
+		}
+
+		isEmbedded := false
+		for _, embed := range embedsFrom {
+			if embed.TableReferenced == table && embed.ConstraintName == e.Key {
+				isEmbedded = true
+			}
+		}
+		if !isEmbedded {
+			continue
+		}
+
+		array := embeddedArray{field: i, name: e.Key}
+		for j, d := range docs {
+			size, err := elementSize(strconv.Itoa(j), d)
+			if err != nil {
+				return nil, err
+			}
+
+			array.docs = append(array.docs, d)
+			array.sizes = append(array.sizes, size)
+			array.total += size
+		}
+
+		result = append(result, array)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].total > result[j].total
+	})
+
+	return result, nil
+}
+
+// documentId gets the _id of a document, or nil if it has none.
+func documentId(doc bson.D) any {
+	for _, e := range doc {
+		if e.Key == "_id" {
+			return e.Value
+		}
+	}
+
+	return nil
+}
+
+// sizeReport describes a document above MaxDocumentSize and its largest
+// embedded arrays.
+func sizeReport(
+	table string, doc bson.D, size int, arrays []embeddedArray,
+) error {
+	parts := []string{}
+	for _, array := range arrays {
+		parts = append(parts, fmt.Sprintf("%s with %d documents in %d bytes",
+			array.name, len(array.docs), array.total,
+		))
+	}
+
+	arraysText := ""
+	if len(parts) != 0 {
+		arraysText = "; its embedded arrays are " + strings.Join(parts, ", ")
+	}
+
+	return fmt.Errorf("document %v of %s has %d bytes, above the limit of %d%s",
+		documentId(doc), table, size, MaxDocumentSize, arraysText,
+	)
+}
+
+// GuardSize checks the encoded size of a document of a table, and if it is
+// above MaxDocumentSize, applies the strategy in opts to the arrays embedded
+// with the references in embedsFrom, starting from the largest one. The
+// documents cut from the arrays go to opts.OverflowSink with SizeBucket. If
+// the document cannot be made to fit, the error reports the size of each
+// array. The conversions of this package already do this for every document
+// they build, including the embedded ones, so this is only needed for
+// documents built elsewhere.
+func GuardSize(
+	table string, doc bson.D, embedsFrom []oracleManager.Reference,
+	opts Options,
+) (bson.D, error) {
+	bs, err := bson.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(bs) <= MaxDocumentSize {
+		return doc, nil
+	}
+
+	doc, _, err = guardSize(table, doc, len(bs), embedsFrom, opts)
+	return doc, err
+}
+
+// guardSize applies the strategy in opts to a document above MaxDocumentSize,
+// with its encoded size already known, as in GuardSize. It returns the
+// document and its new size.
+func guardSize(
+	table string, doc bson.D, size int, embedsFrom []oracleManager.Reference,
+	opts Options,
+) (bson.D, int, error) {
+	arrays, err := embeddedArrays(doc, table, embedsFrom)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if opts.Size == SizeFail {
+		return nil, 0, sizeReport(table, doc, size, arrays)
+	}
+
+	if opts.Size == SizeBucket && opts.OverflowSink == nil {
+		return nil, 0, fmt.Errorf("%w: the overflow of its embedded arrays "+
+			"can only go to a side collection when applying to mongoDB",
+			sizeReport(table, doc, size, arrays),
+		)
+	}
+
+	result := append(bson.D{}, doc...)
+	for _, array := range arrays {
+		if size <= MaxDocumentSize {
+			break
+		}
+
+		// the array is replaced by its documents left, plus the overflow field
+		var kept []bson.D
+		var overflow bson.E
+		kept, overflow, err = splitArray(table, documentId(doc), array,
+			size-MaxDocumentSize, opts,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		keptSize := emptyDocumentSize
+		for i := range kept {
+			keptSize += array.sizes[i]
+		}
+
+		overflowSize, err := elementSize(overflow.Key, overflow.Value)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		result[array.field].Value = kept
+		result = append(result, overflow)
+		size += keptSize - (emptyDocumentSize + array.total) + overflowSize
+	}
+
+	if size > MaxDocumentSize {
+		return nil, 0, sizeReport(table, doc, size, arrays)
+	}
+
+	return result, size, nil
+}
+
+// splitArray cuts the end of an embedded array until the document shrinks by
+// at least excess bytes, counting the overflow field added for the documents
+// cut. It returns the documents left and the overflow field.
+func splitArray(
+	table string, parentId any, array embeddedArray, excess int,
+	opts Options,
+) ([]bson.D, bson.E, error) {
+	overflowName := array.name + OverflowSuffix
+	collection := table + "_" + array.name
+
+	// the overflow field of SizeBucket has a fixed form, while the one of
+	// SizeCap has the _id of each document cut, which is counted as if in the
+	// last position of the array (as an upper bound)
+	fixed, err := elementSize(overflowName, bson.D{
+		{Key: "collection", Value: collection},
+		{Key: "buckets", Value: int32(0)},
+		{Key: "count", Value: int32(0)},
+	})
+	if err != nil {
+		return nil, bson.E{}, err
+	}
+
+	idSizes := make([]int, len(array.docs))
+	if opts.Size == SizeCap {
+		fixed, err = elementSize(overflowName, bson.A{})
+		if err != nil {
+			return nil, bson.E{}, err
+		}
+
+		for i, d := range array.docs {
+			id := documentId(d)
+			if id == nil {
+				return nil, bson.E{}, fmt.Errorf("cannot keep the overflow of "+
+					"%s in %v as references: its documents have no _id",
+					array.name, parentId,
+				)
+			}
+
+			idSizes[i], err = elementSize(strconv.Itoa(len(array.docs)), id)
+			if err != nil {
+				return nil, bson.E{}, err
+			}
+		}
+	}
+
+	// cut documents from the end until enough bytes are saved
+	keep := len(array.docs)
+	saved := -fixed
+	for keep > 0 && saved < excess {
+		keep--
+		saved += array.sizes[keep] - idSizes[keep]
+	}
+
+	cut := array.docs[keep:]
+
+	if opts.Size == SizeCap {
+		ids := bson.A{}
+		for _, d := range cut {
+			ids = append(ids, documentId(d))
+		}
+
+		return array.docs[:keep], bson.E{Key: overflowName, Value: ids}, nil
+	}
+
+	// buckets of parents without _id would all have the same _id
+	if parentId == nil {
+		return nil, bson.E{}, fmt.Errorf("cannot keep the overflow of %s of "+
+			"%s in buckets: the document has no _id", array.name, table,
+		)
+	}
+
+	buckets, err := writeBuckets(collection, parentId, cut, opts.OverflowSink)
+	if err != nil {
+		return nil, bson.E{}, err
+	}
+
+	return array.docs[:keep], bson.E{Key: overflowName, Value: bson.D{
+		{Key: "collection", Value: collection},
+		{Key: "buckets", Value: int32(buckets)},
+		{Key: "count", Value: int32(len(cut))},
+	}}, nil
+}
+
+// writeBuckets sends the documents cut from an array to the sink, in as few
+// bucket documents as fit in MaxDocumentSize, each one with the _id of the
+// parent document and the number of the bucket as its _id. It returns the
+// number of buckets written.
+func writeBuckets(
+	collection string, parentId any, docs []bson.D, sink OverflowSink,
+) (int, error) {
+	newBucket := func(n int) bson.D {
+		return bson.D{
+			{Key: "_id", Value: bson.D{
+				{Key: "parent", Value: parentId},
+				{Key: "bucket", Value: int32(n)},
+			}},
+			{Key: "items", Value: []bson.D{}},
+		}
+	}
+
+	buckets := 0
+	bucket := newBucket(buckets)
+
+	bs, err := bson.Marshal(bucket)
+	if err != nil {
+		return 0, err
+	}
+	empty := len(bs)
+	size := empty
+
+	flush := func() error {
+		if len(bucket[1].Value.([]bson.D)) == 0 {
+			return nil
+		}
+
+		err := sink(collection, bucket)
+		if err != nil {
+			return fmt.Errorf("writing bucket %d of %v to %s: %w",
+				buckets, parentId, collection, err,
+			)
+		}
+
+		buckets++
+		bucket = newBucket(buckets)
+		size = empty
+		return nil
+	}
+
+	for _, d := range docs {
+		items := bucket[1].Value.([]bson.D)
+
+		itemSize, err := elementSize(strconv.Itoa(len(items)), d)
+		if err != nil {
+			return 0, err
+		}
+
+		if size+itemSize > MaxDocumentSize && len(items) != 0 {
+			err = flush()
+			if err != nil {
+				return 0, err
+			}
+
+			items = bucket[1].Value.([]bson.D)
+			itemSize, err = elementSize("0", d)
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		if empty+itemSize > MaxDocumentSize {
+			return 0, fmt.Errorf("a document of %v has %d bytes, which does not "+
+				"fit in a bucket of %s", parentId, itemSize, collection,
+			)
+		}
+
+		bucket[1].Value = append(items, d)
+		size += itemSize
+	}
+
+	err = flush()
+	if err != nil {
+		return 0, err
+	}
+
+	return buckets, nil
+}
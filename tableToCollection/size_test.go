@@ -0,0 +1,189 @@
+package tableToCollection
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lucasgpulcinelli/mongoQLer/oracleManager"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// largeDocument creates a document of DEPT embedding n documents of EMP of
+// about 1MB each in the EMP_DEPT array.
+func largeDocument(n int) bson.D {
+	emps := []bson.D{}
+	for i := 0; i < n; i++ {
+		emps = append(emps, bson.D{
+			{Key: "_id", Value: bson.D{{Key: "ID", Value: int64(i)}}},
+			{Key: "DATA", Value: strings.Repeat("x", 1<<20)},
+		})
+	}
+
+	return bson.D{
+		{Key: "_id", Value: bson.D{{Key: "ID", Value: int64(1)}}},
+		{Key: "NAME", Value: "Sales"},
+		{Key: "EMP_DEPT", Value: emps},
+	}
+}
+
+// encodedSize gets the size of a document as encoded by the driver.
+func encodedSize(t *testing.T, doc bson.D) int {
+	t.Helper()
+
+	bs, err := bson.Marshal(doc)
+	if err != nil {
+		t.Fatalf("invalid document: %v", err)
+	}
+
+	return len(bs)
+}
+
+// field gets the value of a field of a document.
+func field(doc bson.D, key string) any {
+	for _, e := range doc {
+		if e.Key == key {
+			return e.Value
+		}
+	}
+
+	return nil
+}
+
+func TestGuardSize(t *testing.T) {
+	embedsFrom := []oracleManager.Reference{ref("EMP_DEPT", "EMP", "DEPT")}
+
+	tests := []struct {
+		name     string
+		children int
+		size     SizeStrategy
+		sink     bool
+		noId     bool
+		kept     int
+		fails    bool
+	}{
+		{name: "small document", children: 3, size: SizeFail, kept: 3},
+		{name: "fail", children: 40, size: SizeFail, fails: true},
+		{name: "cap", children: 40, size: SizeCap, kept: 15},
+		{name: "bucket", children: 40, size: SizeBucket, sink: true, kept: 15},
+		{name: "bucket without sink", children: 40, size: SizeBucket,
+			fails: true},
+		{name: "bucket without _id", children: 40, size: SizeBucket,
+			sink: true, noId: true, fails: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			buckets := []bson.D{}
+			opts := Options{Size: test.size}
+			if test.sink {
+				opts.OverflowSink = func(collection string, doc bson.D) error {
+					if collection != "DEPT_EMP_DEPT" {
+						t.Errorf("bucket written to %s", collection)
+					}
+
+					buckets = append(buckets, doc)
+					return nil
+				}
+			}
+
+			doc := largeDocument(test.children)
+			if test.noId {
+				doc = doc[1:]
+			}
+
+			doc, err := GuardSize("DEPT", doc, embedsFrom, opts)
+			if test.fails {
+				if err == nil {
+					t.Fatalf("GuardSize did not fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GuardSize failed: %v", err)
+			}
+
+			if size := encodedSize(t, doc); size > MaxDocumentSize {
+				t.Errorf("document has %d bytes", size)
+			}
+
+			kept := field(doc, "EMP_DEPT").([]bson.D)
+			if len(kept) != test.kept {
+				t.Errorf("EMP_DEPT kept %d documents, want %d",
+					len(kept), test.kept,
+				)
+			}
+
+			overflow := field(doc, "EMP_DEPT"+OverflowSuffix)
+			cut := test.children - test.kept
+			switch test.size {
+			case SizeCap:
+				ids, _ := overflow.(bson.A)
+				if len(ids) != cut || fmt.Sprint(ids[0]) != "[{ID 15}]" {
+					t.Errorf("overflow got %v", overflow)
+				}
+
+			case SizeBucket:
+				items := 0
+				for _, bucket := range buckets {
+					items += len(field(bucket, "items").([]bson.D))
+					if size := encodedSize(t, bucket); size > MaxDocumentSize {
+						t.Errorf("bucket has %d bytes", size)
+					}
+				}
+
+				want := bson.D{
+					{Key: "collection", Value: "DEPT_EMP_DEPT"},
+					{Key: "buckets", Value: int32(len(buckets))},
+					{Key: "count", Value: int32(cut)},
+				}
+				if items != cut || fmt.Sprint(overflow) != fmt.Sprint(want) {
+					t.Errorf("overflow got %v with %d documents in buckets",
+						overflow, items,
+					)
+				}
+
+			default:
+				if overflow != nil {
+					t.Errorf("overflow got %v", overflow)
+				}
+			}
+		})
+	}
+}
+
+func TestGuardSizeNotEmbedded(t *testing.T) {
+	// arrays not embedded by the plan are not cut
+	_, err := GuardSize("DEPT", largeDocument(20), nil, Options{Size: SizeCap})
+	if err == nil {
+		t.Errorf("GuardSize did not fail")
+	}
+}
+
+func TestValueSize(t *testing.T) {
+	values := []any{
+		nil, "", "text", int32(1), int64(1), 1.5, true,
+		primitive.NewDecimal128(1, 2),
+		primitive.Binary{Subtype: 4, Data: make([]byte, 16)},
+		primitive.Binary{Subtype: 2, Data: make([]byte, 3)},
+		time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		bson.D{{Key: "A", Value: "b"}},
+		[]bson.D{{{Key: "A", Value: int64(1)}}, {}},
+		bson.A{"a", nil},
+	}
+
+	for _, v := range values {
+		size, err := elementSize("KEY", v)
+		if err != nil {
+			t.Fatalf("elementSize of %v failed: %v", v, err)
+		}
+
+		doc := bson.D{{Key: "KEY", Value: v}}
+		want := encodedSize(t, doc) - emptyDocumentSize
+		if size != want {
+			t.Errorf("elementSize of %#v got %d, want %d", v, size, want)
+		}
+	}
+}
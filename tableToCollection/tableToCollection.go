@@ -2,10 +2,7 @@ package tableToCollection
 
 import (
 	"database/sql"
-	"fmt"
-	"strconv"
 
-	"github.com/lucasgpulcinelli/mongoQLer/keyManager"
 	"github.com/lucasgpulcinelli/mongoQLer/oracleManager"
 	"go.mongodb.org/mongo-driver/bson"
 )
@@ -23,273 +20,195 @@ func anypToAny(v []any) []any {
 	return vv
 }
 
-// GetCollection generates, based on an oracle database connection, a series of
-// documents with the data from a table or query. It will embed all references
-// in the embeds* arrays with the whole document related to the reference.
-// This function takes in a database connection, an open query with data to be
-// turned into the document, the table related to the query, and the list of
-// references that should be embed.
-//
-// This function does recursion, and does not check if the embed references
-// loop back to an initial table in order to stop infinite recursion.
-func GetCollection(
-	db *sql.DB, rows *sql.Rows, table string,
-	embedsTo, embedsFrom []oracleManager.Reference,
-) ([]bson.D, error) {
-
-	result := []bson.D{}
-
-	// get column names
-	cols, err := rows.Columns()
-	if err != nil {
-		return nil, err
-	}
-
-	// for each tuple
-	for rows.Next() {
-		// get its values (type does not matter)
-		vs := make([]any, len(cols))
-		for i := range cols {
-			vs[i] = new(any)
-		}
-
-		err = rows.Scan(vs...)
-		if err != nil {
-			return nil, err
-		}
+// StreamBuffer is the number of converted documents a Stream keeps ready
+// before the conversion waits for them to be read.
+const StreamBuffer = 256
+
+// struct Stream represents a conversion of rows to documents running in the
+// background, with at most StreamBuffer documents in memory at a time that
+// were not read yet.
+type Stream struct {
+	docs chan bson.D
+	stop chan struct{}
+	err  error
+}
 
-		// convert the tuple pointers to their values
-		vv := anypToAny(vs)
+// Next gets the next document of the stream, waiting for it to be converted.
+// It returns false when there are no more documents, and then Err tells if the
+// conversion stopped because of an error.
+func (s *Stream) Next() (bson.D, bool) {
+	doc, ok := <-s.docs
+	return doc, ok
+}
 
-		// and create the document with these values
-		doc, err := writeDocument(db, table, embedsTo, embedsFrom, cols, vv)
-		if err != nil {
-			return nil, err
-		}
+// Err gets the error that stopped the conversion, which can only be called
+// after Next returned false.
+func (s *Stream) Err() error {
+	return s.err
+}
 
-		// appending the document to the result array
-		result = append(result, doc)
+// Close stops the conversion before all documents were read. It can be called
+// more than once, but not at the same time.
+func (s *Stream) Close() {
+	select {
+	case <-s.stop:
+	default:
+		close(s.stop)
 	}
 
-	return result, nil
+	// wait for the conversion to stop
+	for range s.docs {
+	}
 }
 
-// writeDocument creates a mongoDB document from a list of columns and values
-// from a certain table, embedding the references in the embeds array using the
-// database connection provided.
-// All primary keys in the tuple will be converted to a sub document in the
-// "_id" field.
-func writeDocument(
-	db *sql.DB, table string, embedsTo, embedsFrom []oracleManager.Reference,
-	cols []string, vs []any,
-) (bson.D, error) {
-
-	// the final document
-	doc := bson.D{}
-	// the primary key sub document
-	pks := bson.D{}
-
-	// map to determine if the column provided is a reference
-	refCols := map[string]bool{}
-
-	// for each embedding reference that we make the reference to
-	for _, embed := range embedsTo {
-		// if the reference does not relate to us, ignore it
-		if embed.TableReferencer != table {
-			continue
-		}
-
-		// if it does
-
-		// for each column that generate the reference, get the reference values
-		vsRef := []any{}
-		for _, colRef := range embed.ColumnReferencer {
-			refCols[colRef] = true
-
-			for i, col := range cols {
-				if col == colRef {
-
-					vsRef = append(vsRef, vs[i])
-					break
-				}
-			}
-		}
-
-		// generate the subdocument with the reference value
-		subDoc, err := embedReference(db, embedsTo, embedsFrom, embed, vsRef, true)
-		if err != nil {
-			return nil, err
-		}
+// struct Options represents how tuples are converted to documents: the
+// maximum embedding depth (0 for no limit), the policies that override the
+// BSON types of some columns and the LOB thresholds and buckets of others,
+// the sink where the LOBs above their threshold go, the strategy for
+// documents above MaxDocumentSize, with the sink of its overflow documents,
+// and the references kept as links other than the foreign key columns, with
+// the IdMap of the tables without a primary key. The zero value embeds
+// everything, uses the default policies, fails on documents that are too big
+// and keeps the other references as columns.
+type Options struct {
+	MaxDepth int
+	Types    TypeMapping
+	Lobs     LobMapping
+	LobSink  LobSink
+
+	Size         SizeStrategy
+	OverflowSink OverflowSink
+
+	Links []Link
+	Ids   *IdMap
+}
 
-		// and add it to our own document, generating the embedding
-		doc = append(doc, bson.E{Key: embed.ConstraintName, Value: subDoc})
+// StreamCollection starts converting the rows of a table or query to
+// documents like GetCollection does, but without keeping all of them in
+// memory: the documents are read from the Stream returned as they are
+// converted. The rows are closed by the stream when it ends.
+func StreamCollection(
+	db *sql.DB, catalog oracleManager.Catalog, rows *sql.Rows, table string,
+	embedsTo, embedsFrom []oracleManager.Reference, opts Options,
+) *Stream {
+	s := &Stream{
+		docs: make(chan bson.D, StreamBuffer),
+		stop: make(chan struct{}),
 	}
 
-	// for each embedding reference that we receive the reference from
-	for _, embed := range embedsFrom {
-		// if the reference does not relate to us, ignore it
-		if embed.TableReferenced != table {
-			continue
-		}
-
-		vsRef := []any{}
-		for _, colRef := range embed.ColumnReferenced {
-
-			for i, col := range cols {
-				if col == colRef {
-					// same conversion as before
+	go func() {
+		defer close(s.docs)
 
-					vsRef = append(vsRef, vs[i])
-					break
-				}
-			}
+		// no limit is a negative depth for writeDocuments
+		depth := opts.MaxDepth
+		if depth <= 0 {
+			depth = -1
 		}
 
-		// generate the subdocument with the reference value
-		subDoc, err := embedReference(db, embedsTo, embedsFrom, embed, vsRef,
-			false,
-		)
-		if err != nil {
-			return nil, err
+		s.err = ValidatePlan(embedsTo, embedsFrom)
+		if s.err == nil {
+			s.err = streamRows(db, catalog, rows, table, embedsTo, embedsFrom,
+				depth, opts, s,
+			)
 		}
 
-		// and add it to our own document, generating the embedding
-		doc = append(doc, bson.E{Key: embed.ConstraintName, Value: subDoc})
-	}
-
-	// for each column
-	for i, name := range cols {
-		if keyManager.IsPk(table, name) {
-			// if it is a primary key, add it to the _id document
-			pks = append(pks, bson.E{Key: name, Value: vs[i]})
-		} else if refCols[name] != true {
-			// if it is not a reference (in that case the embedding is already done)
-			// add it to the main document
-			doc = append(doc, bson.E{Key: name, Value: vs[i]})
+		err := rows.Close()
+		if s.err == nil {
+			s.err = err
 		}
-	}
-
-	// if the primary key is empty, mongoDB should create an objectID for us, if
-	// not, we should use our primary key
-	if len(pks) != 0 {
-		doc = append(doc, bson.E{Key: "_id", Value: pks})
-	}
+	}()
 
-	return doc, nil
+	return s
 }
 
-// embedReference creates a document containing matching data from a reference
-// with certain tuple values. It embeds references as objects if isReferenceTo
-// is true and as arrays if it is false.
-func embedReference(
-	db *sql.DB, embedsTo, embedsFrom []oracleManager.Reference,
-	ref oracleManager.Reference, vs []any, isReferenceTo bool,
-) (any, error) {
-
-	// if any value in the tuple is null, foreign key references do not apply,
-	// so return a null reference
-	for _, v := range vs {
-		if v == nil {
-			return nil, nil
-		}
-	}
-
-	// first, determine our tables and columns based on our type of reference
-	var tableS string
-	var columnsS []string
-	if isReferenceTo {
-		tableS = ref.TableReferenced
-		columnsS = ref.ColumnReferenced
-	} else {
-		tableS = ref.TableReferencer
-		columnsS = ref.ColumnReferencer
-	}
-
-	// create the query for getting the matching reference
-	s := "SELECT * FROM " + tableS + " WHERE "
-
-	for i, colName := range columnsS {
-		s += colName + " = :" + strconv.FormatInt(int64(i), 10) + " AND "
-	}
-
-	s = s[:len(s)-5]
-
-	// execute the query with the tuple values
-	rows, err := db.Query(s, vs...)
-	if err != nil {
-		return nil, err
-	}
-
-	columns, err := rows.Columns()
+// streamRows converts the tuples in rows to documents in chunks of
+// EmbedChunk, sending them in the stream until the rows end or the stream is
+// closed.
+func streamRows(
+	db *sql.DB, catalog oracleManager.Catalog, rows *sql.Rows, table string,
+	embedsTo, embedsFrom []oracleManager.Reference, depth int, opts Options,
+	s *Stream,
+) error {
+	// get column names
+	cols, err := rows.Columns()
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	// prepare the output tuple to receive data
-	vsOut := make([]any, len(columns))
-	for i := range columns {
-		vsOut[i] = new(any)
-	}
-
-	if isReferenceTo {
-		if !rows.Next() {
-			return nil, fmt.Errorf("embed query returned no data")
-		}
+	for more := true; more; {
+		// read a chunk of tuples (type does not matter)
+		tuples := [][]any{}
+		for len(tuples) < EmbedChunk {
+			if more = rows.Next(); !more {
+				break
+			}
 
-		// scan all columns
-		err = rows.Scan(vsOut...)
-		if err != nil {
-			return nil, err
-		}
+			vs := make([]any, len(cols))
+			for i := range cols {
+				vs[i] = new(any)
+			}
 
-		// if we are referencing some table, we will only have one object embedded,
-		// so only one tuple is acceptable
-		if rows.Next() {
-			return nil, fmt.Errorf("embed query returned more than one tuple")
-		}
+			err = rows.Scan(vs...)
+			if err != nil {
+				return err
+			}
 
-		err = rows.Close()
-		if err != nil {
-			return nil, err
+			// convert the tuple pointers to their values
+			tuples = append(tuples, anypToAny(vs))
 		}
 
-		vvOut := anypToAny(vsOut)
-
-		// return a new document with the data from these tuples. If there are
-		// any references that should be embed, this function will call
-		// embedReference back, making the root of the embedding recursion.
-		return writeDocument(
-			db, ref.TableReferenced, embedsTo, embedsFrom, columns, vvOut,
+		// and create the documents with these values, embedding the references
+		// of the whole chunk at once, which also makes them fit in mongoDB
+		docs, _, err := writeDocuments(db, catalog, table, embedsTo,
+			embedsFrom, cols, tuples, depth, opts,
 		)
-
-	}
-
-	subDocs := []bson.D{}
-
-	for rows.Next() {
-		// scan all columns
-		err = rows.Scan(vsOut...)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
-		vvOut := anypToAny(vsOut)
-
-		doc, err := writeDocument(db, ref.TableReferencer, embedsTo, embedsFrom,
-			columns, vvOut,
-		)
-
-		if err != nil {
-			return nil, err
+		// sending them to whoever reads the stream
+		for _, doc := range docs {
+			select {
+			case s.docs <- doc:
+			case <-s.stop:
+				return nil
+			}
 		}
-
-		subDocs = append(subDocs, doc)
 	}
 
-	err = rows.Close()
-	if err != nil {
-		return nil, err
+	return rows.Err()
+}
+
+// GetCollection generates, based on an oracle database connection, a series of
+// documents with the data from a table or query. It will embed all references
+// in the embeds* arrays with the whole document related to the reference.
+// This function takes in a database connection and its catalog, an open query
+// with data to be turned into the document, the table related to the query,
+// and the list of references that should be embed. The tuples are converted
+// in chunks of EmbedChunk, so each embedded reference takes one query per
+// chunk instead of one per tuple.
+//
+// This function does recursion, so the embedding plan is first checked with
+// ValidatePlan, and documents nested more than opts.MaxDepth levels deep (if
+// it is positive) keep their references as plain key fields instead of
+// embedding them. The values are converted to BSON with the type policies in
+// opts (see DefaultPolicy). For tables read with SelectTable, the LOBs above
+// their threshold are streamed to opts.LobSink, and the document keeps the id
+// of the file. Documents above MaxDocumentSize because of their embedded
+// arrays are handled as in GuardSize, at each level of the embedding, from the
+// innermost documents out. All documents are kept in memory, for
+// big tables use StreamCollection instead.
+func GetCollection(
+	db *sql.DB, catalog oracleManager.Catalog, rows *sql.Rows, table string,
+	embedsTo, embedsFrom []oracleManager.Reference, opts Options,
+) ([]bson.D, error) {
+	s := StreamCollection(db, catalog, rows, table, embedsTo, embedsFrom, opts)
+
+	result := []bson.D{}
+	for doc, ok := s.Next(); ok; doc, ok = s.Next() {
+		// appending the document to the result array
+		result = append(result, doc)
 	}
 
-	return subDocs, nil
+	return result, s.Err()
 }
@@ -0,0 +1,40 @@
+package tableToCollection
+
+import (
+	"github.com/lucasgpulcinelli/mongoQLer/oracleManager"
+)
+
+// nonNullTypes are all the BSON types (as $type aliases) that a converted
+// value can have, except null.
+var nonNullTypes = []string{
+	"double", "string", "object", "array", "binData", "objectId", "bool",
+	"date", "int", "timestamp", "long", "decimal",
+}
+
+// BsonTypes gets the BSON types (as $type and bsonType aliases) that the
+// values of an oracle column have after being converted to a document with
+// its DefaultPolicy, not including null. LOBs can also be objects, for the
+// ones streamed to GridFS. Columns with a data type that is not known get all
+// non-null types.
+func BsonTypes(col oracleManager.ColumnInfo) []string {
+	return bsonTypes(DefaultPolicy(col), col)
+}
+
+// BsonTypes gets the BSON types of the values of a column of a table, like the
+// BsonTypes function, but with the policy that the TypeMapping gives it.
+func (m TypeMapping) BsonTypes(
+	catalog oracleManager.Catalog, table, column string,
+) []string {
+	return bsonTypes(m.Policy(catalog, table, column))
+}
+
+// bsonTypes gets the BSON types of the values of a column converted with a
+// policy.
+func bsonTypes(policy TypePolicy, col oracleManager.ColumnInfo) []string {
+	types := policyTypes(policy)
+	if isLob(col) {
+		types = append(types, "object")
+	}
+
+	return types
+}
@@ -0,0 +1,353 @@
+package tableToCollection
+
+import (
+	"math"
+	"strings"
+
+	"github.com/lucasgpulcinelli/mongoQLer/keyManager"
+	"github.com/lucasgpulcinelli/mongoQLer/oracleManager"
+	"github.com/lucasgpulcinelli/mongoQLer/sqlparser"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// numberBound gets the largest absolute value of a NUMBER with a precision
+// and scale, such as 999.99 for NUMBER(5, 2), as an int64 when it is an
+// integer that fits in one.
+func numberBound(precision, scale int64) any {
+	if scale <= 0 && precision-scale <= 18 {
+		digits, unit := int64(1), int64(1)
+		for i := int64(0); i < precision-scale; i++ {
+			digits *= 10
+		}
+		for i := int64(0); i < -scale; i++ {
+			unit *= 10
+		}
+
+		return digits - unit
+	}
+
+	return math.Pow10(int(precision-scale)) - math.Pow10(int(-scale))
+}
+
+// columnSchema gets the $jsonSchema of the values of a column, with the BSON
+// types of its policy, and its maximum length or bounds when oracle defines
+// them and the policy keeps the values as strings or numbers.
+func columnSchema(
+	col oracleManager.ColumnInfo, policy TypePolicy, nullable bool,
+) bson.D {
+	types := bson.A{}
+	for _, t := range bsonTypes(policy, col) {
+		types = append(types, t)
+	}
+	if nullable {
+		types = append(types, "null")
+	}
+
+	schema := bson.D{{Key: "bsonType", Value: types}}
+
+	switch kind := policyTypes(policy)[0]; col.DataType {
+	case "CHAR", "NCHAR", "VARCHAR2", "NVARCHAR2":
+		if col.CharLength > 0 && kind == "string" {
+			schema = append(schema,
+				bson.E{Key: "maxLength", Value: col.CharLength},
+			)
+		}
+
+	case "NUMBER":
+		if col.Precision != nil && col.Scale != nil && kind == "number" {
+			bound := numberBound(*col.Precision, *col.Scale)
+
+			var min any
+			switch b := bound.(type) {
+			case int64:
+				min = -b
+			case float64:
+				min = -b
+			}
+
+			schema = append(schema,
+				bson.E{Key: "minimum", Value: min},
+				bson.E{Key: "maximum", Value: bound},
+			)
+		}
+	}
+
+	return schema
+}
+
+// linkSchema gets the $jsonSchema of the field of a link, with the columns of
+// the referencer table: an object with the _id or the DBRef of the referenced
+// document, which is null if any foreign key column is, or the array with the
+// _id of the children.
+func linkSchema(
+	link Link, columns map[string]oracleManager.ColumnInfo,
+) bson.D {
+	if link.Mode == LinkChildIds {
+		return bson.D{{Key: "bsonType", Value: "array"}}
+	}
+
+	types := bson.A{"object"}
+	for _, col := range link.ColumnReferencer {
+		if columns[col].Nullable {
+			types = append(types, "null")
+			break
+		}
+	}
+
+	fields := bson.A{"_id"}
+	if link.Mode == LinkDBRef {
+		fields = bson.A{"$ref", "$id"}
+	}
+
+	return bson.D{
+		{Key: "bsonType", Value: types},
+		{Key: "required", Value: fields},
+	}
+}
+
+// conjuncts gets the expressions joined by AND in a boolean expression,
+// including in nested ones.
+func conjuncts(be sqlparser.BooleanExpression) []sqlparser.BooleanExpression {
+	bc, ok := be.(*sqlparser.BooleanComposite)
+	if !ok || strings.ToUpper(bc.BoolOp) != "AND" {
+		return []sqlparser.BooleanExpression{be}
+	}
+
+	result := []sqlparser.BooleanExpression{}
+	for _, se := range bc.SubExpr {
+		result = append(result, conjuncts(se)...)
+	}
+
+	return result
+}
+
+// struct CheckFailure represents a CHECK constraint that could not be
+// converted to a validator, with the reason why.
+type CheckFailure struct {
+	oracleManager.CheckEntry
+	Err error
+}
+
+// tableChecks gets the conditions of all CHECK constraints of a table, already
+// analyzed against the catalog, and the constraints that could not be
+// converted.
+func tableChecks(
+	catalog oracleManager.Catalog, table string,
+) ([]sqlparser.BooleanExpression, []CheckFailure, error) {
+	checks, err := catalog.GetChecks()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result := []sqlparser.BooleanExpression{}
+	failures := []CheckFailure{}
+	for _, check := range checks {
+		if check.Table != table {
+			continue
+		}
+
+		be, err := sqlparser.ParseBoolExpr(check.Check + ";")
+		if err == nil {
+			err = sqlparser.AnalyzeBoolExpr(catalog, be, check.Table)
+		}
+		if err == nil {
+			_, err = be.GetBson(catalog, check.Table, "")
+		}
+
+		if err != nil {
+			failures = append(failures, CheckFailure{check, err})
+			continue
+		}
+
+		result = append(result, conjuncts(be)...)
+	}
+
+	return result, failures, nil
+}
+
+// Validator gets the validator for the collection of a table, as converted by
+// GetCollection with the references in embedsTo embedded as objects, and the
+// links and type policies of opts. It is a $jsonSchema with the BSON types,
+// lengths and bounds of the columns, the required (NOT NULL and primary key)
+// columns, with the primary key in the _id sub document, and enums for IN
+// checks. The other checks are converted to query operators and combined with
+// the $jsonSchema in an $and. CHECK constraints that cannot be converted are
+// left out of the validator and returned as CheckFailures.
+func Validator(
+	catalog oracleManager.Catalog, table string,
+	embedsTo []oracleManager.Reference, opts Options,
+) (bson.D, []CheckFailure, error) {
+	checks, failures, err := tableChecks(catalog, table)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// columns embedded as objects are replaced by the embedded document, unless
+	// they are part of the primary key
+	embedded := map[string]bool{}
+	embeddedNames := []string{}
+	for _, embed := range embedsTo {
+		if embed.TableReferencer != table {
+			continue
+		}
+
+		for _, col := range embed.ColumnReferencer {
+			embedded[col] = !keyManager.IsPk(catalog, table, col)
+		}
+		embeddedNames = append(embeddedNames, embed.ConstraintName)
+	}
+
+	// the same goes for the links by _id or DBRef, while the links to the
+	// children add an array to the referenced table
+	links := []Link{}
+	for _, link := range opts.Links {
+		switch {
+		case link.Mode == LinkChildIds && link.TableReferenced == table:
+		case (link.Mode == LinkId || link.Mode == LinkDBRef) &&
+			link.TableReferencer == table:
+			for _, col := range link.ColumnReferencer {
+				embedded[col] = !keyManager.IsPk(catalog, table, col)
+			}
+		default:
+			continue
+		}
+
+		links = append(links, link)
+	}
+
+	columns := map[string]oracleManager.ColumnInfo{}
+	for _, col := range catalog.GetColumns(table) {
+		columns[col.Name] = col
+	}
+
+	// IN checks on a column become an enum for it, as long as there is only one
+	// for the column
+	enums := map[string]bson.A{}
+	others := []sqlparser.BooleanExpression{}
+	for _, check := range checks {
+		switch c := check.(type) {
+		case *sqlparser.InComparision:
+			col, ok := columns[strings.ToUpper(c.Id)]
+			if ok && !c.Not && !embedded[col.Name] && enums[col.Name] == nil {
+				enums[col.Name] = bson.A{}
+				for _, v := range c.Values {
+					enums[col.Name] = append(enums[col.Name], v)
+				}
+
+				// a NULL value does not fail a check in oracle
+				if col.Nullable {
+					enums[col.Name] = append(enums[col.Name], nil)
+				}
+				continue
+			}
+
+		case *sqlparser.Comparision:
+			// IS NOT NULL checks are in required, except for embedded columns,
+			// which are not in the document
+			col, ok := columns[strings.ToUpper(c.Id)]
+			if ok && c.Op == "<>" && c.Value == nil {
+				col.Nullable = false
+				columns[col.Name] = col
+				continue
+			}
+		}
+
+		others = append(others, check)
+	}
+
+	required := bson.A{}
+	properties := bson.D{}
+	idRequired := bson.A{}
+	idProperties := bson.D{}
+
+	for _, col := range catalog.GetColumns(table) {
+		if embedded[col.Name] {
+			continue
+		}
+
+		isPk := keyManager.IsPk(catalog, table, col.Name)
+		nullable := columns[col.Name].Nullable && !isPk
+
+		policy, _ := opts.Types.Policy(catalog, table, col.Name)
+		schema := columnSchema(col, policy, nullable)
+		if enums[col.Name] != nil {
+			schema = append(schema,
+				bson.E{Key: "enum", Value: enums[col.Name]},
+			)
+		}
+
+		// the time zone offset goes in a field of its own, next to the column
+		fields := bson.D{{Key: col.Name, Value: schema}}
+		if policy == PolicyDateOffset {
+			offset := bson.A{"string"}
+			if nullable {
+				offset = append(offset, "null")
+			}
+
+			fields = append(fields, bson.E{
+				Key:   col.Name + OffsetSuffix,
+				Value: bson.D{{Key: "bsonType", Value: offset}},
+			})
+		}
+
+		if isPk {
+			for _, field := range fields {
+				idRequired = append(idRequired, field.Key)
+			}
+			idProperties = append(idProperties, fields...)
+			continue
+		}
+
+		if !nullable {
+			for _, field := range fields {
+				required = append(required, field.Key)
+			}
+		}
+		properties = append(properties, fields...)
+	}
+
+	if len(idProperties) != 0 {
+		required = append(bson.A{"_id"}, required...)
+		properties = append(properties, bson.E{Key: "_id", Value: bson.D{
+			{Key: "bsonType", Value: "object"},
+			{Key: "required", Value: idRequired},
+			{Key: "properties", Value: idProperties},
+		}})
+	}
+
+	for _, name := range embeddedNames {
+		properties = append(properties, bson.E{Key: name, Value: bson.D{
+			{Key: "bsonType", Value: bson.A{"object", "null"}},
+		}})
+	}
+
+	for _, link := range links {
+		required = append(required, link.ConstraintName)
+		properties = append(properties, bson.E{
+			Key: link.ConstraintName, Value: linkSchema(link, columns),
+		})
+	}
+
+	schema := bson.D{{Key: "bsonType", Value: "object"}}
+	if len(required) != 0 {
+		schema = append(schema, bson.E{Key: "required", Value: required})
+	}
+	schema = append(schema, bson.E{Key: "properties", Value: properties})
+
+	validator := bson.D{{Key: "$jsonSchema", Value: schema}}
+	if len(others) == 0 {
+		return validator, failures, nil
+	}
+
+	conditions := bson.A{validator}
+	for _, check := range others {
+		bs, err := check.GetBson(catalog, table, "")
+		if err != nil {
+			return nil, nil, err
+		}
+
+		conditions = append(conditions, bs)
+	}
+
+	return bson.D{{Key: "$and", Value: conditions}}, failures, nil
+}
@@ -0,0 +1,250 @@
+package tableToCollection
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lucasgpulcinelli/mongoQLer/oracleManager"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// idSchema is the schema of the _id of the tables of testDDL.
+const idSchema = `"_id":{"bsonType":"object","required":["ID"],` +
+	`"properties":{"ID":{"bsonType":["number"],` +
+	`"minimum":-99999,"maximum":99999}}}`
+
+// nameSchema is the schema of the NAME column of EMP.
+const nameSchema = `"NAME":{"bsonType":["string"],"maxLength":30}`
+
+func TestValidator(t *testing.T) {
+	empDept := ref("EMP_DEPT", "EMP", "DEPT")
+	empDept.ColumnReferencer = []string{"DEPT_ID"}
+
+	tests := []struct {
+		name     string
+		table    string
+		embedsTo []oracleManager.Reference
+		opts     Options
+		want     string
+	}{
+		{
+			name:  "nullable column and primary key",
+			table: "DEPT",
+			want: `{"$jsonSchema":{"bsonType":"object","required":["_id"],` +
+				`"properties":{"NAME":{"bsonType":["string","null"],` +
+				`"maxLength":30},` + idSchema + `}}}`,
+		},
+		{
+			name:  "foreign key column",
+			table: "EMP",
+			want: `{"$jsonSchema":{"bsonType":"object",` +
+				`"required":["_id","NAME"],"properties":{` + nameSchema + `,` +
+				`"DEPT_ID":{"bsonType":["number","null"],` +
+				`"minimum":-99999,"maximum":99999},` + idSchema + `}}}`,
+		},
+		{
+			name:     "embedded object",
+			table:    "EMP",
+			embedsTo: []oracleManager.Reference{empDept},
+			want: `{"$jsonSchema":{"bsonType":"object",` +
+				`"required":["_id","NAME"],"properties":{` + nameSchema + `,` +
+				idSchema + `,"EMP_DEPT":{"bsonType":["object","null"]}}}}`,
+		},
+		{
+			name:  "link by _id",
+			table: "EMP",
+			opts:  Options{Links: []Link{{empDept, LinkId}}},
+			want: `{"$jsonSchema":{"bsonType":"object",` +
+				`"required":["_id","NAME","EMP_DEPT"],"properties":{` +
+				nameSchema + `,` + idSchema + `,"EMP_DEPT":{` +
+				`"bsonType":["object","null"],"required":["_id"]}}}}`,
+		},
+		{
+			name:  "link by DBRef",
+			table: "EMP",
+			opts:  Options{Links: []Link{{empDept, LinkDBRef}}},
+			want: `{"$jsonSchema":{"bsonType":"object",` +
+				`"required":["_id","NAME","EMP_DEPT"],"properties":{` +
+				nameSchema + `,` + idSchema + `,"EMP_DEPT":{` +
+				`"bsonType":["object","null"],"required":["$ref","$id"]}}}}`,
+		},
+		{
+			name:  "link to the children",
+			table: "DEPT",
+			opts:  Options{Links: []Link{{empDept, LinkChildIds}}},
+			want: `{"$jsonSchema":{"bsonType":"object",` +
+				`"required":["_id","EMP_DEPT"],"properties":{` +
+				`"NAME":{"bsonType":["string","null"],"maxLength":30},` +
+				idSchema + `,"EMP_DEPT":{"bsonType":"array"}}}}`,
+		},
+		{
+			name:  "link of another table",
+			table: "DEPT",
+			opts:  Options{Links: []Link{{empDept, LinkId}}},
+			want: `{"$jsonSchema":{"bsonType":"object","required":["_id"],` +
+				`"properties":{"NAME":{"bsonType":["string","null"],` +
+				`"maxLength":30},` + idSchema + `}}}`,
+		},
+	}
+
+	c := testCatalog(t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			validator, failures, err := Validator(c, test.table, test.embedsTo,
+				test.opts,
+			)
+			if err != nil || len(failures) != 0 {
+				t.Fatalf("Validator failed: %v %v", err, failures)
+			}
+
+			if got := extJSON(t, validator); got != test.want {
+				t.Errorf("Validator got\n%s\nwant\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+// property gets the schema of a property of a validator in extended JSON, or
+// an empty string if it is not in the validator.
+func property(t *testing.T, validator bson.D, name string) string {
+	t.Helper()
+
+	if validator[0].Key == "$and" {
+		validator = validator[0].Value.(bson.A)[0].(bson.D)
+	}
+
+	schema := validator[0].Value.(bson.D)
+	for _, e := range schema {
+		if e.Key != "properties" {
+			continue
+		}
+
+		for _, p := range e.Value.(bson.D) {
+			if p.Key == name {
+				return extJSON(t, p.Value.(bson.D))
+			}
+		}
+	}
+
+	return ""
+}
+
+func TestValidatorTypes(t *testing.T) {
+	types := TypeMapping{
+		"T.PRICE": PolicyString, "T.TS_OFFSET": PolicyDate,
+		"T.CODE": PolicyBinary, "T.RATE": PolicyDecimal,
+	}
+
+	tests := []struct {
+		types  TypeMapping
+		column string
+		want   string
+	}{
+		{nil, "PRICE", `{"bsonType":["number","null"],` +
+			`"minimum":-999999.99,"maximum":999999.99}`},
+		{types, "PRICE", `{"bsonType":["string","null"]}`},
+		{nil, "CODE", `{"bsonType":["string","null"],"maxLength":5}`},
+		{types, "CODE", `{"bsonType":["binData","null"]}`},
+		{nil, "TS_OFFSET", `{"bsonType":["date","null"]}`},
+		{nil, "TS_OFFSET_OFFSET", `{"bsonType":["string","null"]}`},
+		{types, "TS_OFFSET_OFFSET", ""},
+		{types, "RATE", `{"bsonType":["number","null"]}`},
+		{nil, "DOC", `{"bsonType":["string","object","null"]}`},
+		{nil, "HUGE", `{"bsonType":["number","null"],` +
+			`"minimum":-1E+30,"maximum":1E+30}`},
+	}
+
+	c := testCatalog(t)
+	for _, test := range tests {
+		validator, _, err := Validator(c, "T", nil, Options{Types: test.types})
+		if err != nil {
+			t.Fatalf("Validator failed: %v", err)
+		}
+
+		if got := property(t, validator, test.column); got != test.want {
+			t.Errorf("schema of %s got %s, want %s",
+				test.column, got, test.want,
+			)
+		}
+	}
+}
+
+func TestValidatorChecks(t *testing.T) {
+	c := testCatalog(t)
+	c.Checks = append(c.Checks,
+		oracleManager.CheckEntry{
+			Name: "CODE_IN", Table: "T", Check: "CODE IN ('A', 'B')",
+		},
+		oracleManager.CheckEntry{
+			Name: "BAD", Table: "T", Check: "UPPER(NAME) = NAME",
+		},
+	)
+
+	validator, failures, err := Validator(c, "T", nil, Options{})
+	if err != nil {
+		t.Fatalf("Validator failed: %v", err)
+	}
+
+	if len(failures) != 1 || failures[0].Name != "BAD" {
+		t.Errorf("failures got %v", failures)
+	}
+
+	want := `{"bsonType":["string","null"],"maxLength":5,` +
+		`"enum":["A","B",null]}`
+	if got := property(t, validator, "CODE"); got != want {
+		t.Errorf("schema of CODE got %s, want %s", got, want)
+	}
+
+	conditions := validator[0].Value.(bson.A)
+	if len(conditions) != 2 ||
+		extJSON(t, conditions[1].(bson.D)) != `{"PRICE":{"$gte":0}}` {
+		t.Errorf("conditions got %v", conditions)
+	}
+}
+
+func TestValidatorCheckFailures(t *testing.T) {
+	c := testCatalog(t)
+	c.Checks = append(c.Checks,
+		oracleManager.CheckEntry{
+			Name: "FUNCTION", Table: "T", Check: "UPPER(NAME) = NAME",
+		},
+		oracleManager.CheckEntry{
+			Name: "UNKNOWN", Table: "T", Check: "NOPE > 1",
+		},
+		oracleManager.CheckEntry{
+			Name: "TYPE", Table: "T", Check: "PRICE = 'abc'",
+		},
+		oracleManager.CheckEntry{
+			Name: "RATE", Table: "T", Check: "RATE < 1",
+		},
+		oracleManager.CheckEntry{
+			Name: "OTHER", Table: "DEPT", Check: "UPPER(NAME) = NAME",
+		},
+	)
+
+	validator, failures, err := Validator(c, "T", nil, Options{})
+	if err != nil {
+		t.Fatalf("Validator failed: %v", err)
+	}
+
+	names := []string{}
+	for _, failure := range failures {
+		if failure.Table != "T" || failure.Check == "" || failure.Err == nil {
+			t.Errorf("failure got %+v", failure)
+		}
+
+		names = append(names, failure.Name)
+	}
+
+	want := []string{"FUNCTION", "UNKNOWN", "TYPE"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("failures got %v, want %v", names, want)
+	}
+
+	// the checks that can be converted are still in the validator
+	conditions := validator[0].Value.(bson.A)
+	if len(conditions) != 3 ||
+		extJSON(t, conditions[2].(bson.D)) != `{"RATE":{"$lt":1}}` {
+		t.Errorf("conditions got %v", conditions)
+	}
+}
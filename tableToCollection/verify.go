@@ -0,0 +1,86 @@
+package tableToCollection
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// struct ValidatorReport represents the result of checking a validator against
+// the documents already in a collection: how many of them violate it and the
+// _id of some of them.
+type ValidatorReport struct {
+	Collection string
+	Violations int64
+	SampleIds  []any
+}
+
+// ValidationRecommendation gets the validation settings recommended for
+// applying the validator of a collection that has documents violating it.
+func ValidationRecommendation(collection string) string {
+	return fmt.Sprintf(
+		"apply the validator of %s with validationLevel: \"moderate\" to only "+
+			"validate the documents that are valid already, or validationAction: "+
+			"\"warn\" to only log the violations",
+		collection,
+	)
+}
+
+// Recommendation gets the validation settings recommended for applying the
+// validator of a report, or an empty string if no document violates it.
+func (r ValidatorReport) Recommendation() string {
+	if r.Violations == 0 {
+		return ""
+	}
+
+	return ValidationRecommendation(r.Collection)
+}
+
+// VerifyFilter gets the filter that matches the documents violating a
+// validator.
+func VerifyFilter(validator bson.D) bson.D {
+	return bson.D{{Key: "$nor", Value: bson.A{validator}}}
+}
+
+// VerifyValidator counts the documents of a collection that violate a
+// validator, getting the _id of at most samples of them.
+func VerifyValidator(
+	ctx context.Context, coll *mongo.Collection, validator bson.D,
+	samples int64,
+) (ValidatorReport, error) {
+	report := ValidatorReport{Collection: coll.Name(), SampleIds: []any{}}
+	filter := VerifyFilter(validator)
+
+	var err error
+	report.Violations, err = coll.CountDocuments(ctx, filter)
+	if err != nil || report.Violations == 0 {
+		return report, err
+	}
+
+	cursor, err := coll.Find(ctx, filter, options.Find().
+		SetProjection(bson.D{{Key: "_id", Value: 1}}).
+		SetLimit(samples),
+	)
+	if err != nil {
+		return report, err
+	}
+
+	docs := []bson.D{}
+	err = cursor.All(ctx, &docs)
+	if err != nil {
+		return report, err
+	}
+
+	for _, doc := range docs {
+		for _, e := range doc {
+			if e.Key == "_id" {
+				report.SampleIds = append(report.SampleIds, e.Value)
+			}
+		}
+	}
+
+	return report, nil
+}
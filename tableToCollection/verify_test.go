@@ -0,0 +1,56 @@
+package tableToCollection
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestVerifyFilter(t *testing.T) {
+	validator := bson.D{{Key: "A", Value: bson.D{{Key: "$gt", Value: 1}}}}
+
+	want := `{"$nor":[{"A":{"$gt":1}}]}`
+	if got := extJSON(t, VerifyFilter(validator)); got != want {
+		t.Errorf("VerifyFilter got %s, want %s", got, want)
+	}
+}
+
+func TestValidatorReportRecommendation(t *testing.T) {
+	report := ValidatorReport{Collection: "EMP"}
+	if got := report.Recommendation(); got != "" {
+		t.Errorf("Recommendation without violations got %q", got)
+	}
+
+	report.Violations = 2
+	got := report.Recommendation()
+	if !strings.Contains(got, "EMP") || !strings.Contains(got, "moderate") {
+		t.Errorf("Recommendation got %q", got)
+	}
+}
+
+func TestVerifyValidatorUnreachable(t *testing.T) {
+	// no server listens in port 1, so the count fails in server selection
+	client, err := mongo.Connect(context.Background(), options.Client().
+		ApplyURI("mongodb://127.0.0.1:1").
+		SetServerSelectionTimeout(100*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	defer client.Disconnect(context.Background())
+
+	coll := client.Database("test").Collection("EMP")
+	report, err := VerifyValidator(context.Background(), coll, bson.D{}, 5)
+	if err == nil {
+		t.Errorf("VerifyValidator did not fail")
+	}
+
+	if report.Collection != "EMP" || report.Violations != 0 {
+		t.Errorf("VerifyValidator got %+v", report)
+	}
+}
@@ -1,68 +1,208 @@
 package ui
 
 import (
+	"context"
 	"fmt"
+	"strings"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/widget"
-	"github.com/lucasgpulcinelli/mongoQLer/oracleManager"
-	"github.com/lucasgpulcinelli/mongoQLer/sqlparser"
+	"github.com/lucasgpulcinelli/mongoQLer/mongoManager"
+	"github.com/lucasgpulcinelli/mongoQLer/tableToCollection"
 	"go.mongodb.org/mongo-driver/bson"
 )
 
 var (
 	checkGeneratorButton *widget.Button
+	checkVerifyButton    *widget.Button
+	checkApplyButton     *widget.Button
 	mongoCGEntry         *widget.Entry
 )
 
-// checkGeneratorButtonFunc executes the check generation button functionality.
-func checkGeneratorButtonFunc() {
-	// get all checks from the oracle connection
-	checks, err := oracleManager.GetChecks(oracleConn)
+// sampleIds is the number of _ids of documents violating a validator shown
+// when verifying it.
+const sampleIds = 5
+
+// generateValidators generates the validator for each table, formatting it with
+// format and concatenating the results, followed by the report of the CHECK
+// constraints that could not be converted.
+func generateValidators(
+	format func(table string, validator bson.D) string,
+) (string, error) {
+	// get all tables from the catalog, views can not have validators
+	tables, err := catalogNow.GetTables()
 	if err != nil {
-		errorPopUp(err, mainWindow.Canvas())
-		return
+		return "", err
+	}
+
+	// the validators describe the documents with the embedding and links
+	// chosen in the table to collection tab, and their BSON types follow the
+	// type overrides of that tab
+	embedTo, _ := embeddingPlan()
+
+	types, err := typeMapping()
+	if err != nil {
+		return "", err
 	}
+	opts := tableToCollection.Options{Types: types, Links: linkPlan()}
 
 	s := ""
+	failures := []tableToCollection.CheckFailure{}
 
-	// for each check
-	for _, check := range checks {
-		// parse it
-		be, err := sqlparser.ParseBoolExpr(check.Check)
+	// for each table
+	for _, table := range tables {
+		// get the $jsonSchema and checks validator for it
+		bs, tableFailures, err := tableToCollection.Validator(catalogNow, table,
+			embedTo, opts,
+		)
 		if err != nil {
-			errorPopUp(err, mainWindow.Canvas())
-			return
+			return "", err
 		}
 
-		// get the bson for that check
-		bs, err := be.GetBson(check.Table, "")
-		if err != nil {
-			errorPopUp(err, mainWindow.Canvas())
-			return
-		}
+		failures = append(failures, tableFailures...)
+
+		// and concatenate it for the final text output
+		s += format(table, bs)
+	}
+
+	// finally, report the constraints left out of the validators as comments
+	if len(failures) != 0 {
+		s += "\n// CHECK constraints that could not be converted:\n"
+	}
+	for _, failure := range failures {
+		s += fmt.Sprintf("// %s on %s: %s\n//   %s\n", failure.Name,
+			failure.Table,
+			strings.ReplaceAll(failure.Err.Error(), "\n", "; "),
+			strings.ReplaceAll(failure.Check, "\n", "\n//   "),
+		)
+	}
 
+	return s, nil
+}
+
+// checkGeneratorButtonFunc executes the check generation button functionality.
+func checkGeneratorButtonFunc() {
+	s, err := generateValidators(func(table string, bs bson.D) string {
 		// generate the complete validator bson
-		bscomplete := bson.D{{Key: "collMod", Value: check.Table},
+		bscomplete := bson.D{{Key: "collMod", Value: table},
 			{Key: "validator", Value: bs}, {Key: "validationAction", Value: "error"},
 		}
 
-		// and concatenate it for the final text output
-		s += fmt.Sprintf("db.runCommand(%s)\n", bsonToString(bscomplete))
+		return fmt.Sprintf("db.runCommand(%s)\n", bsonToString(bscomplete))
+	})
+	if err != nil {
+		errorPopUp(err, mainWindow.Canvas())
+		return
+	}
+
+	mongoCGEntry.SetText(s)
+}
+
+// checkApplyButtonFunc executes the check apply button functionality,
+// running the collMod of each generated validator in mongoDB and reporting the
+// result of each.
+func checkApplyButtonFunc() {
+	if mongoDB == nil {
+		errorPopUp(errNotConnectedMongo, mainWindow.Canvas())
+		return
+	}
+
+	s, err := generateValidators(func(table string, bs bson.D) string {
+		err := mongoManager.RunCommand(context.Background(), mongoDB, bson.D{
+			{Key: "collMod", Value: table},
+			{Key: "validator", Value: bs},
+			{Key: "validationAction", Value: "error"},
+		})
+
+		return reportLine("collMod "+table, err)
+	})
+	if err != nil {
+		errorPopUp(err, mainWindow.Canvas())
+		return
+	}
+
+	mongoCGEntry.SetText(s)
+}
+
+// verifyScript gets the mongosh script that counts the documents of a
+// collection violating its validator, printing some of their _ids.
+func verifyScript(table string, bs bson.D) string {
+	return fmt.Sprintf(`// verify the validator of %[1]s against its documents
+filter = %[2]s
+violations = db.%[1]s.countDocuments(filter)
+if (violations > 0) {
+  print(violations + " documents in %[1]s violate the validator, _id samples:")
+  printjson(db.%[1]s.find(filter, {_id: 1}).limit(%[3]d).toArray())
+  print(%[4]q)
+}
+`, table, bsonToString(tableToCollection.VerifyFilter(bs)), sampleIds,
+		tableToCollection.ValidationRecommendation(table),
+	)
+}
+
+// verifyReport counts the documents of a collection in mongoDB violating its
+// validator, reporting their number, some of their _ids and the recommended
+// validation settings.
+func verifyReport(table string, bs bson.D) string {
+	report, err := tableToCollection.VerifyValidator(context.Background(),
+		mongoDB.Collection(table), bs, sampleIds,
+	)
+	if err != nil || report.Violations == 0 {
+		return reportLine("verify "+table, err)
+	}
+
+	s := fmt.Sprintf("// %d documents in %s violate the validator, "+
+		"_id samples:\n", report.Violations, table,
+	)
+	for _, id := range report.SampleIds {
+		bts, err := bson.MarshalExtJSON(bson.D{{Key: "_id", Value: id}},
+			false, false,
+		)
+		if err != nil {
+			return reportLine("verify "+table, err)
+		}
+
+		s += "//   " + string(bts) + "\n"
+	}
+
+	return s + "// " + report.Recommendation() + "\n"
+}
+
+// checkVerifyButtonFunc executes the check verification button functionality,
+// counting the documents already in each collection that violate its
+// validator. Without a mongoDB connection, it generates a mongosh script that
+// does the same instead.
+func checkVerifyButtonFunc() {
+	verify := verifyScript
+	if mongoDB != nil {
+		verify = verifyReport
+	}
+
+	s, err := generateValidators(verify)
+	if err != nil {
+		errorPopUp(err, mainWindow.Canvas())
+		return
 	}
 
 	mongoCGEntry.SetText(s)
 }
 
 // newCheckGenerator generates the main check generation UI. It generates all
-// the validators for a mongoDB database from CHECK constraints from oracle.
+// the validators for a mongoDB database from the columns and constraints of
+// the oracle tables.
 func newCheckGenerator() fyne.CanvasObject {
 
 	checkGeneratorButton = widget.NewButton("generate",
 		checkGeneratorButtonFunc,
 	)
 
+	checkVerifyButton = widget.NewButton("verify existing data",
+		checkVerifyButtonFunc,
+	)
+
+	checkApplyButton = widget.NewButton("apply", checkApplyButtonFunc)
+
 	mongoCGEntry = widget.NewMultiLineEntry()
 	mongoCGEntry.SetPlaceHolder("click generate to get the validators")
 
@@ -71,6 +211,12 @@ func newCheckGenerator() fyne.CanvasObject {
 	)
 
 	return container.NewBorder(
-		container.NewCenter(l), checkGeneratorButton, nil, nil, mongoCGEntry,
+		container.NewCenter(l),
+		container.NewGridWithColumns(3,
+			checkGeneratorButton, checkVerifyButton, checkApplyButton,
+		),
+		nil,
+		nil,
+		mongoCGEntry,
 	)
 }
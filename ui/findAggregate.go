@@ -2,17 +2,21 @@ package ui
 
 import (
 	"fmt"
+	"strings"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/widget"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
 
+	"github.com/lucasgpulcinelli/mongoQLer/mongoToSql"
 	"github.com/lucasgpulcinelli/mongoQLer/sqlparser"
 )
 
 var (
 	findAggregateButton *widget.Button
+	toSqlButton         *widget.Button
 	sqlFAEntry          *widget.Entry
 	mongoFAEntry        *widget.Entry
 )
@@ -29,9 +33,100 @@ func bsonToString(a bson.D) string {
 	return string(bts)
 }
 
+// warningsToString formats the warnings of the semantic analysis as mongosh
+// comments, to be shown before the conversion.
+func warningsToString(warnings []string) string {
+	s := ""
+	for _, w := range warnings {
+		s += "// warning: " + w + "\n"
+	}
+
+	return s
+}
+
+// aggregateToString formats an aggregation pipeline over a collection as a
+// mongosh command.
+func aggregateToString(collection string, p mongo.Pipeline) string {
+	out := "[\n"
+	for _, bs := range p {
+		out += bsonToString(bs) + ",\n"
+	}
+
+	if len(p) > 0 {
+		out = out[:len(out)-2]
+	}
+
+	return fmt.Sprint("db.", collection, ".aggregate(", out, "\n])")
+}
+
+// insertMergeToString converts an INSERT INTO ... SELECT or MERGE statement to
+// the mongosh commands that represent it.
+func insertMergeToString(sql string) (string, error) {
+	if strings.ToUpper(strings.Fields(sql)[0]) == "MERGE" {
+		merge, err := sqlparser.ParseMerge(sql)
+		if err != nil {
+			return "", err
+		}
+
+		err = merge.Analyze(catalogNow)
+		if err != nil {
+			return "", err
+		}
+
+		p, err := merge.ToMongoAggregate(catalogNow)
+		if err != nil {
+			return "", err
+		}
+
+		return warningsToString(merge.Warnings) +
+			aggregateToString(merge.SourceTable(), p), nil
+	}
+
+	ins, err := sqlparser.ParseInsert(sql)
+	if err != nil {
+		return "", err
+	}
+
+	err = ins.Analyze(catalogNow)
+	if err != nil {
+		return "", err
+	}
+
+	pMerge, err := ins.ToMongoMerge(catalogNow)
+	if err != nil {
+		return "", err
+	}
+
+	pOut, err := ins.ToMongoOut(catalogNow)
+	if err != nil {
+		return "", err
+	}
+
+	return warningsToString(ins.Warnings) +
+		aggregateToString(ins.Select.FromTable, pMerge) +
+		"\n\n// or, if the target collection is empty:\n" +
+		aggregateToString(ins.Select.FromTable, pOut), nil
+}
+
 // findAggregateButtonFunc executes the SQL to find or aggregate functionality.
 func findAggregateButtonFunc() {
 
+	// INSERT and MERGE statements are always aggregations ending in a $merge
+	// or $out
+	fields := strings.Fields(sqlFAEntry.Text)
+	if len(fields) != 0 && (strings.ToUpper(fields[0]) == "INSERT" ||
+		strings.ToUpper(fields[0]) == "MERGE") {
+
+		out, err := insertMergeToString(sqlFAEntry.Text)
+		if err != nil {
+			errorPopUp(err, mainWindow.Canvas())
+			return
+		}
+
+		mongoFAEntry.SetText(out)
+		return
+	}
+
 	// first, parse the SQL
 	stmt, err := sqlparser.Parse(sqlFAEntry.Text)
 	if err != nil {
@@ -39,32 +134,30 @@ func findAggregateButtonFunc() {
 		return
 	}
 
+	// then check all columns against the tables and convert the literals
+	err = stmt.Analyze(catalogNow)
+	if err != nil {
+		errorPopUp(err, mainWindow.Canvas())
+		return
+	}
+
 	if stmt.IsAggregate() {
 		// get the aggregation from the statement
-		mongoResult, err := stmt.ToMongoAggregate()
+		mongoResult, err := stmt.ToMongoAggregate(catalogNow)
 		if err != nil {
 			errorPopUp(err, mainWindow.Canvas())
 			return
 		}
 
 		// and format it for the final text output
-		out := "[\n"
-		for _, bs := range mongoResult {
-			out += bsonToString(bs) + ",\n"
-		}
-
-		if len(mongoResult) > 1 {
-			out = out[:len(out)-2]
-		}
-
-		mongoFAEntry.SetText(
-			fmt.Sprint("db.", stmt.FromTable, ".aggregate(", out, "\n])"),
+		mongoFAEntry.SetText(warningsToString(stmt.Warnings) +
+			aggregateToString(stmt.FromTable, mongoResult),
 		)
 	} else {
 		// if the statement is a find
 
 		// get the find and selection from the statement
-		find, selection, err := stmt.ToMongoFind()
+		find, selection, err := stmt.ToMongoFind(catalogNow)
 		if err != nil {
 			errorPopUp(err, mainWindow.Canvas())
 			return
@@ -74,17 +167,30 @@ func findAggregateButtonFunc() {
 		findJson := bsonToString(find)
 		selectionJson := bsonToString(selection)
 
-		mongoFAEntry.SetText(
+		mongoFAEntry.SetText(warningsToString(stmt.Warnings) +
 			fmt.Sprint("db.", stmt.FromTable, ".find(\n", findJson, ",\n",
 				selectionJson, "\n)"),
 		)
 	}
 }
 
+// toSqlButtonFunc executes the mongoDB find or aggregate to SQL functionality,
+// converting the mongosh command back to an SQL query.
+func toSqlButtonFunc() {
+	sql, err := mongoToSql.ShellToSQL(mongoFAEntry.Text)
+	if err != nil {
+		errorPopUp(err, mainWindow.Canvas())
+		return
+	}
+
+	sqlFAEntry.SetText(sql)
+}
+
 // newFindAggregate generates the main SQL query to find or aggregate mongoDB
 // query. It takes the SQL text and outputs the mongoDB in another text area.
 func newFindAggregate() fyne.CanvasObject {
 	findAggregateButton = widget.NewButton("convert", findAggregateButtonFunc)
+	toSqlButton = widget.NewButton("convert back to SQL", toSqlButtonFunc)
 
 	sqlFAEntry = widget.NewMultiLineEntry()
 	sqlFAEntry.SetText("SELECT * FROM DUAL;")
@@ -96,7 +202,7 @@ func newFindAggregate() fyne.CanvasObject {
 		container.NewCenter(
 			widget.NewLabel("Convert an SQL query to a mongoDB find or aggregate"),
 		),
-		findAggregateButton,
+		container.NewGridWithColumns(2, findAggregateButton, toSqlButton),
 		nil,
 		nil,
 		container.NewHSplit(sqlFAEntry, mongoFAEntry),
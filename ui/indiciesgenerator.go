@@ -1,58 +1,271 @@
 package ui
 
 import (
+	"context"
 	"fmt"
+	"regexp"
+	"strings"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/widget"
 	"github.com/lucasgpulcinelli/mongoQLer/keyManager"
+	"github.com/lucasgpulcinelli/mongoQLer/mongoManager"
 	"github.com/lucasgpulcinelli/mongoQLer/oracleManager"
+	"github.com/lucasgpulcinelli/mongoQLer/tableToCollection"
 	"go.mongodb.org/mongo-driver/bson"
 )
 
 var (
 	indiciesGeneratorButton *widget.Button
+	indiciesApplyButton     *widget.Button
 	mongoIGEntry            *widget.Entry
 )
 
-// indiciesGeneratorButton executes the indicies generator button
-// functionality.
-func indiciesGeneratorButtonFunc() {
-	// get all the unique entries from oracle
-	uniques, err := oracleManager.GetUniques(oracleConn)
+// caseExpression matches the function-based index expressions that only
+// change the case of a column, such as UPPER("NAME").
+var caseExpression = regexp.MustCompile(
+	`^(?i:UPPER|LOWER)\s*\(\s*"?([A-Za-z][A-Za-z0-9_$#]*)"?\s*\)$`,
+)
+
+// indexToMongo converts an oracle index to the keys and options of a mongoDB
+// createIndex. Expressions that only change the case of a column are
+// converted to the column itself with a case insensitive collation (which is
+// only used by queries with the same collation), and ok is false if any other
+// expression is found, as they cannot be expressed in mongoDB.
+func indexToMongo(
+	index oracleManager.IndexEntry, types tableToCollection.TypeMapping,
+) (bson.D, bson.D, bool) {
+	keys := bson.D{}
+	options := bson.D{{Key: "name", Value: index.Name}}
+	caseInsensitive := false
+	columns := []string{}
+
+	for _, col := range index.Columns {
+		column := col.Column
+		if col.Expression != "" {
+			match := caseExpression.FindStringSubmatch(col.Expression)
+			if match == nil {
+				return nil, nil, false
+			}
+
+			column = strings.ToUpper(match[1])
+			caseInsensitive = true
+		}
+
+		columns = append(columns, column)
+
+		direction := 1
+		if col.Descending {
+			direction = -1
+		}
+
+		keys = append(keys, bson.E{
+			Key:   keyManager.ToMongoId(catalogNow, index.Table, column),
+			Value: direction,
+		})
+	}
+
+	if index.Unique {
+		options = append(options,
+			uniqueOptions(index.Table, columns, types)...,
+		)
+	}
+
+	if caseInsensitive {
+		options = append(options, bson.E{Key: "collation", Value: bson.D{
+			{Key: "locale", Value: "en"},
+			{Key: "strength", Value: 2},
+		}})
+	}
+
+	return keys, options, true
+}
+
+// uniqueOptions gets the createIndex options for an unique index on some
+// columns of a table, only indexing the documents with some non-null column
+// when all columns are nullable, to keep the NULL semantics of oracle.
+func uniqueOptions(
+	table string, columns []string, types tableToCollection.TypeMapping,
+) bson.D {
+	options := bson.D{{Key: "unique", Value: true}}
+
+	filter := tableToCollection.UniquePartialFilter(catalogNow, table, columns,
+		types,
+	)
+	if filter != nil {
+		options = append(options, bson.E{
+			Key: "partialFilterExpression", Value: filter,
+		})
+	}
+
+	return options
+}
+
+// struct generatedIndex represents an index generated from the catalog, with a
+// comment explaining it. Indexes that cannot be expressed in mongoDB only have
+// the comment.
+type generatedIndex struct {
+	mongoManager.Index
+	comment string
+}
+
+// generateIndexes generates the indexes for all UNIQUE constraints and indexes
+// of the catalog, and the ones proposed for its references.
+func generateIndexes() ([]generatedIndex, error) {
+	// the partial filters follow the type overrides of the tableToCollection
+	// tab
+	types, err := typeMapping()
 	if err != nil {
-		errorPopUp(err, mainWindow.Canvas())
-		return
+		return nil, err
+	}
+
+	// get all the unique entries from the catalog
+	uniques, err := catalogNow.GetUniques()
+	if err != nil {
+		return nil, err
 	}
 
 	// for each unique, convert it to a document and add an createIndex for it
-	s := ""
+	result := []generatedIndex{}
 	for _, un := range uniques {
 		bs := bson.D{}
 		for _, col := range un.Columns {
 			bs = append(bs, bson.E{
-				Key:   keyManager.ToMongoId(un.Table, col),
+				Key:   keyManager.ToMongoId(catalogNow, un.Table, col),
 				Value: 1,
 			})
 		}
 
-		s += fmt.Sprintf("db.%s.createIndex(%s,\n{unique: true}\n)\n",
-			un.Table, bsonToString(bs),
+		result = append(result, generatedIndex{Index: mongoManager.Index{
+			Collection: un.Table,
+			Keys:       bs,
+			Options:    uniqueOptions(un.Table, un.Columns, types),
+		}})
+	}
+
+	// then, add the other indexes, with their order and name
+	indexes, err := catalogNow.GetIndexes()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, index := range indexes {
+		keys, options, ok := indexToMongo(index, types)
+		if !ok {
+			result = append(result, generatedIndex{comment: fmt.Sprintf(
+				"index %s on %s uses an expression that cannot be expressed "+
+					"in mongoDB, consider indexing a computed field instead",
+				index.Name, index.Table,
+			)})
+			continue
+		}
+
+		result = append(result, generatedIndex{Index: mongoManager.Index{
+			Collection: index.Table,
+			Keys:       keys,
+			Options:    options,
+		}})
+	}
+
+	// finally, propose indexes for the references, following the embedding
+	// chosen in the table to collection tab
+	embedTo, embedFrom := embeddingPlan()
+	proposals, err := tableToCollection.ProposeIndexes(catalogNow,
+		referencesNow, embedTo, embedFrom,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, proposal := range proposals {
+		result = append(result, generatedIndex{
+			Index: mongoManager.Index{
+				Collection: proposal.Collection,
+				Keys:       proposal.Keys,
+			},
+			comment: proposal.Reason,
+		})
+	}
+
+	return result, nil
+}
+
+// indiciesGeneratorButton executes the indicies generator button
+// functionality.
+func indiciesGeneratorButtonFunc() {
+	indexes, err := generateIndexes()
+	if err != nil {
+		errorPopUp(err, mainWindow.Canvas())
+		return
+	}
+
+	s := ""
+	for _, index := range indexes {
+		if index.comment != "" {
+			s += "// " + index.comment + "\n"
+		}
+
+		if index.Keys == nil {
+			continue
+		}
+
+		if index.Options == nil {
+			s += fmt.Sprintf("db.%s.createIndex(%s)\n",
+				index.Collection, bsonToString(index.Keys),
+			)
+		} else {
+			s += fmt.Sprintf("db.%s.createIndex(%s,\n%s\n)\n",
+				index.Collection, bsonToString(index.Keys),
+				bsonToString(index.Options),
+			)
+		}
+	}
+
+	mongoIGEntry.SetText(s)
+}
+
+// indiciesApplyButtonFunc executes the indicies apply button functionality,
+// creating the generated indexes in mongoDB and reporting the result of each.
+func indiciesApplyButtonFunc() {
+	if mongoDB == nil {
+		errorPopUp(errNotConnectedMongo, mainWindow.Canvas())
+		return
+	}
+
+	indexes, err := generateIndexes()
+	if err != nil {
+		errorPopUp(err, mainWindow.Canvas())
+		return
+	}
+
+	s := ""
+	for _, index := range indexes {
+		if index.Keys == nil {
+			s += "// skipped: " + index.comment + "\n"
+			continue
+		}
+
+		err = mongoManager.CreateIndex(context.Background(), mongoDB,
+			index.Index,
 		)
+		s += reportLine(index.Collection+" "+bsonToString(index.Keys), err)
 	}
 
 	mongoIGEntry.SetText(s)
 }
 
 // newIndiciesGenerator generates the main indicies generator UI. It generates
-// all the indicies for a mongoDB database from UNIQUE constraints from oracle.
+// all the indicies for a mongoDB database from UNIQUE constraints and indexes
+// from oracle.
 func newIndiciesGenerator() fyne.CanvasObject {
 
 	indiciesGeneratorButton = widget.NewButton("generate",
 		indiciesGeneratorButtonFunc,
 	)
 
+	indiciesApplyButton = widget.NewButton("apply", indiciesApplyButtonFunc)
+
 	mongoIGEntry = widget.NewMultiLineEntry()
 	mongoIGEntry.SetPlaceHolder("click generate to get the indicies")
 
@@ -61,6 +274,12 @@ func newIndiciesGenerator() fyne.CanvasObject {
 	)
 
 	return container.NewBorder(
-		container.NewCenter(l), indiciesGeneratorButton, nil, nil, mongoIGEntry,
+		container.NewCenter(l),
+		container.NewGridWithColumns(2,
+			indiciesGeneratorButton, indiciesApplyButton,
+		),
+		nil,
+		nil,
+		mongoIGEntry,
 	)
 }
@@ -1,25 +1,114 @@
 package ui
 
 import (
+	"context"
+	"io"
 	"os"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
 	"fyne.io/fyne/v2/widget"
-	"github.com/lucasgpulcinelli/mongoQLer/keyManager"
+	"github.com/lucasgpulcinelli/mongoQLer/mongoManager"
 	"github.com/lucasgpulcinelli/mongoQLer/oracleManager"
+	"github.com/lucasgpulcinelli/mongoQLer/sqlparser"
+	"go.mongodb.org/mongo-driver/mongo"
 
 	"database/sql"
 )
 
 var (
 	oracleConn *sql.DB
+	mongoDB    *mongo.Database       // nil if no mongoDB URI was given
+	catalogNow oracleManager.Catalog // from oracle or from a DDL file
 )
 
-// GetConnections gets the oracle database connection. Before the login window
-// closes the connection is nil.
-func GetConnections() *sql.DB {
-	return oracleConn
+// GetConnections gets the oracle database connection and the mongoDB client.
+// Before the login window closes the connections are nil, and the mongoDB
+// client is also nil if no mongoDB URI was given.
+func GetConnections() (*sql.DB, *mongo.Client) {
+	if mongoDB == nil {
+		return oracleConn, nil
+	}
+
+	return oracleConn, mongoDB.Client()
+}
+
+// loginMongo connects to the mongoDB database where the apply buttons write,
+// if an URI is given.
+func loginMongo(uri, database string) error {
+	if mongoDB != nil {
+		mongoDB.Client().Disconnect(context.Background())
+		mongoDB = nil
+	}
+
+	if uri == "" {
+		return nil
+	}
+
+	var err error
+	mongoDB, err = mongoManager.Login(uri, database)
+	return err
+}
+
+// openMainWindow opens the main window with the sources (tables, views and
+// synonyms) and references from catalogNow, closing the login window.
+func openMainWindow(a fyne.App, w fyne.Window) error {
+	sources, err := catalogNow.GetSources()
+	if err != nil {
+		return err
+	}
+
+	names := []string{}
+	for _, src := range sources {
+		names = append(names, src.Name)
+	}
+
+	referencesNow, err = catalogNow.GetReferences()
+	if err != nil {
+		return err
+	}
+
+	NewMainWindow(a)
+
+	tcSelection.SetOptions(names)
+	initReferences(referencesNow)
+
+	mainWindow.Show()
+	w.Close()
+
+	return nil
+}
+
+// readCatalogFile reads a catalog from a .sql file with DDL statements, or from
+// a .json, .yaml or .yml catalog snapshot.
+func readCatalogFile(rd fyne.URIReadCloser) (oracleManager.Catalog, error) {
+	if rd.URI().Extension() != ".sql" {
+		format, err := oracleManager.SnapshotFormat(rd.URI().Name())
+		if err != nil {
+			return nil, err
+		}
+
+		snapshot, err := oracleManager.DecodeSnapshot(rd, format)
+		if err != nil {
+			return nil, err
+		}
+
+		return snapshot.Catalog(), nil
+	}
+
+	bs, err := io.ReadAll(rd)
+	if err != nil {
+		return nil, err
+	}
+
+	schema, err := sqlparser.ParseDDL(string(bs))
+	if err != nil {
+		return nil, err
+	}
+
+	return schema.Catalog(), nil
 }
 
 // NewLoginWindow creates the login window for connecting with the database.
@@ -29,7 +118,7 @@ func GetConnections() *sql.DB {
 // rerun the application multiple times.
 func NewLoginWindow(a fyne.App) fyne.Window {
 	w := a.NewWindow("Login to Oracle")
-	w.Resize(fyne.NewSize(500, 300))
+	w.Resize(fyne.NewSize(500, 380))
 
 	oracleURL := widget.NewEntry()
 	s, ok := os.LookupEnv("ORACLE_URL")
@@ -52,6 +141,20 @@ func NewLoginWindow(a fyne.App) fyne.Window {
 	}
 	oraclePass.SetText(s)
 
+	// the schema to read, where an empty owner means the one logged in
+	oracleOwner := widget.NewEntry()
+	oracleOwner.SetPlaceHolder("same as the user")
+	oracleOwner.SetText(os.Getenv("ORACLE_OWNER"))
+
+	// the mongoDB connection is optional, only needed to apply the results
+	mongoURI := widget.NewEntry()
+	mongoURI.SetPlaceHolder("no mongoDB connection")
+	mongoURI.SetText(os.Getenv("MONGO_URI"))
+
+	mongoDatabase := widget.NewEntry()
+	mongoDatabase.SetPlaceHolder("Database")
+	mongoDatabase.SetText(os.Getenv("MONGO_DATABASE"))
+
 	// the button that will execute the login functionality
 	b := widget.NewButton("login", func() {
 		var err error
@@ -60,45 +163,82 @@ func NewLoginWindow(a fyne.App) fyne.Window {
 			oracleConn.Close()
 		}
 
-		// log in to oracle
-		oracleConn, err = oracleManager.Login(oracleURL.Text, oracleUser.Text,
-			oraclePass.Text,
-		)
+		err = loginMongo(mongoURI.Text, mongoDatabase.Text)
 		if err != nil {
 			errorPopUp(err, w.Canvas())
 			return
 		}
 
-		// initialise some oracle metadata:
-
-		// get all foreign key references
-		referencesNow, err = oracleManager.GetReferences(oracleConn)
+		// log in to oracle
+		oracleConn, err = oracleManager.Login(oracleURL.Text, oracleUser.Text,
+			oraclePass.Text,
+		)
 		if err != nil {
 			errorPopUp(err, w.Canvas())
 			return
 		}
 
-		// get all primary keys and prepare the key manager
-		err = keyManager.InitPrimaryKeys(oracleConn)
+		// read the oracle metadata
+		catalogNow, err = oracleManager.NewOracleCatalog(oracleConn,
+			oracleOwner.Text,
+		)
 		if err != nil {
 			errorPopUp(err, w.Canvas())
 			return
 		}
 
-		// get all table names
-		tables, err := oracleManager.GetTables(oracleConn)
+		err = openMainWindow(a, w)
 		if err != nil {
 			errorPopUp(err, w.Canvas())
-			return
 		}
+	})
+
+	// the button that reads the metadata from a DDL file or a catalog snapshot
+	// instead, for working without an oracle connection
+	bSchema := widget.NewButton("open .sql schema or snapshot", func() {
+		d := dialog.NewFileOpen(func(rd fyne.URIReadCloser, err error) {
+			if err != nil {
+				errorPopUp(err, w.Canvas())
+				return
+			}
+
+			// the dialog was cancelled
+			if rd == nil {
+				return
+			}
+			defer rd.Close()
+
+			catalog, err := readCatalogFile(rd)
+			if err != nil {
+				errorPopUp(err, w.Canvas())
+				return
+			}
+
+			err = loginMongo(mongoURI.Text, mongoDatabase.Text)
+			if err != nil {
+				errorPopUp(err, w.Canvas())
+				return
+			}
 
-		NewMainWindow(a)
+			if oracleConn != nil {
+				oracleConn.Close()
+				oracleConn = nil
+			}
 
-		tcSelection.SetOptions(tables)
-		initReferences(referencesNow)
+			// use the same metadata as the login does, but from the file
+			catalogNow = catalog
 
-		mainWindow.Show()
-		w.Close()
+			err = openMainWindow(a, w)
+			if err != nil {
+				errorPopUp(err, w.Canvas())
+			}
+		}, w)
+
+		d.SetFilter(storage.NewExtensionFileFilter(
+			[]string{".sql", ".json", ".yaml", ".yml"},
+		))
+		w.Resize(fyne.NewSize(800, 600))
+		d.Show()
 	})
 
 	content := container.NewVBox(
@@ -111,7 +251,17 @@ func NewLoginWindow(a fyne.App) fyne.Window {
 		container.NewBorder(
 			nil, nil, widget.NewLabel("Oracle Password"), nil, oraclePass,
 		),
+		container.NewBorder(
+			nil, nil, widget.NewLabel("Schema Owner"), nil, oracleOwner,
+		),
+		container.NewBorder(
+			nil, nil, widget.NewLabel("MongoDB URI"), nil, mongoURI,
+		),
+		container.NewBorder(
+			nil, nil, widget.NewLabel("MongoDB Database"), nil, mongoDatabase,
+		),
 		b,
+		bSchema,
 	)
 
 	w.SetContent(content)
@@ -1,7 +1,6 @@
 package ui
 
 import (
-	"fmt"
 	"strings"
 
 	"fyne.io/fyne/v2"
@@ -12,14 +11,26 @@ import (
 )
 
 var (
-	queryButton    *widget.Button
-	sqlQCEntry     *widget.Entry
-	mongoQCEntry   *widget.Entry
-	queryNameEntry *widget.Entry
+	queryButton      *widget.Button
+	queryApplyButton *widget.Button
+	querySaveButton  *widget.Button
+	sqlQCEntry       *widget.Entry
+	mongoQCEntry     *widget.Entry
+	queryNameEntry   *widget.Entry
 )
 
-// queryButtonFunc executes the query to collection button functionality
-func queryButtonFunc() {
+// convertQuery runs the query and starts converting its result to documents,
+// returning them with the name of the output collection. The LOBs in a query
+// are read whole by the driver and nothing is embedded, so there is nothing
+// to send to the sinks.
+func convertQuery(
+	_ tableToCollection.Options,
+) (string, *tableToCollection.Stream, error) {
+	// the data can only be read with a connection, not from a schema file
+	if oracleConn == nil {
+		return "", nil, errNotConnected
+	}
+
 	var collection string
 	if queryNameEntry.Text == "" {
 		collection = "query"
@@ -36,38 +47,32 @@ func queryButtonFunc() {
 
 	rows, err := oracleConn.Query(query)
 	if err != nil {
-		errorPopUp(err, mainWindow.Canvas())
-		return
+		return "", nil, err
 	}
 
-	// generate the collection from the query
-	docs, err := tableToCollection.GetCollection(oracleConn, rows, "",
+	// generate the collection from the query, closing it at the end
+	return collection, tableToCollection.StreamCollection(oracleConn,
+		catalogNow, rows, "",
 		[]oracleManager.Reference{}, []oracleManager.Reference{},
-	)
-	if err != nil {
-		errorPopUp(err, mainWindow.Canvas())
-		return
-	}
+		tableToCollection.Options{},
+	), nil
+}
 
-	err = rows.Close()
-	if err != nil {
-		errorPopUp(err, mainWindow.Canvas())
-		return
-	}
+// queryButtonFunc executes the query to collection button functionality
+func queryButtonFunc() {
+	previewStream(convertQuery, mongoQCEntry)
+}
 
-	// and write the result in the mongoQCEntry
-	s := fmt.Sprintf("db.%s.insertMany([\n", collection)
-	i := 0
-	for _, doc := range docs {
-		s += bsonToString(doc) + ",\n"
-		i++
-	}
-	if i > 0 {
-		s = s[:len(s)-2] + "\n"
-	}
-	s += "])\n"
+// queryApplyButtonFunc executes the query to collection apply button
+// functionality, inserting the documents directly in mongoDB.
+func queryApplyButtonFunc() {
+	applyStream(convertQuery, mongoQCEntry, queryApplyButton)
+}
 
-	mongoQCEntry.SetText(s)
+// querySaveButtonFunc executes the query to collection save button
+// functionality, writing the documents to a file.
+func querySaveButtonFunc() {
+	saveStream(convertQuery, mongoQCEntry)
 }
 
 // newQueryToCollection generates the main query to collection UI. It takes an
@@ -75,6 +80,8 @@ func queryButtonFunc() {
 // to be inserted based on the result of running the query in oracle.
 func newQueryToCollection() fyne.CanvasObject {
 	queryButton = widget.NewButton("generate", queryButtonFunc)
+	queryApplyButton = widget.NewButton("apply", queryApplyButtonFunc)
+	querySaveButton = widget.NewButton("save", querySaveButtonFunc)
 
 	sqlQCEntry = widget.NewMultiLineEntry()
 	sqlQCEntry.SetText("SELECT * FROM DUAL;")
@@ -89,7 +96,10 @@ func newQueryToCollection() fyne.CanvasObject {
 		container.NewCenter(
 			widget.NewLabel("Convert an SQL query to a mongoDB collection"),
 		),
-		container.NewBorder(nil, nil, nil, queryButton, queryNameEntry),
+		container.NewBorder(nil, nil, nil,
+			container.NewHBox(queryButton, queryApplyButton, querySaveButton),
+			queryNameEntry,
+		),
 		nil,
 		nil,
 		container.NewHSplit(sqlQCEntry, mongoQCEntry),
@@ -0,0 +1,184 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/lucasgpulcinelli/mongoQLer/mongoManager"
+	"github.com/lucasgpulcinelli/mongoQLer/tableToCollection"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// previewSize is the number of documents shown when converting a table or
+// query, the others are only written by apply or save.
+const previewSize = 100
+
+// startStream starts converting a table or query, returning the name of the
+// collection and the documents being converted, with the LOBs above their
+// threshold and the overflow of big documents going to the sinks in the
+// Options (or failing the conversion if they are nil).
+type startStream func(
+	sinks tableToCollection.Options,
+) (string, *tableToCollection.Stream, error)
+
+// previewLob is the sink of the previews, which do not read the LOBs above
+// their threshold, leaving the file id null.
+func previewLob(_ tableToCollection.LobFile) (any, error) {
+	return nil, nil
+}
+
+// previewOverflow is the overflow sink of the previews, which do not write
+// the bucket documents anywhere.
+func previewOverflow(_ string, _ bson.D) error {
+	return nil
+}
+
+// insertOverflow is the overflow sink when applying to mongoDB, which inserts
+// the bucket documents in their side collection.
+func insertOverflow(collection string, doc bson.D) error {
+	return mongoManager.InsertDocument(context.Background(), mongoDB,
+		collection, doc,
+	)
+}
+
+// gridFSLob is the sink when applying to mongoDB, which streams the LOBs to
+// their GridFS bucket.
+func gridFSLob(file tableToCollection.LobFile) (any, error) {
+	return mongoManager.UploadFile(mongoDB, file.Bucket, file.Name,
+		file.Reader, bson.D{
+			{Key: "table", Value: file.Table},
+			{Key: "column", Value: file.Column},
+			{Key: "contentType", Value: file.ContentType},
+			{Key: "length", Value: file.Length},
+		},
+	)
+}
+
+// previewStream shows the first documents of a conversion as a mongosh
+// insertMany in an entry, stopping the conversion after them.
+func previewStream(start startStream, entry *widget.Entry) {
+	collection, stream, err := start(tableToCollection.Options{
+		LobSink: previewLob, OverflowSink: previewOverflow,
+	})
+	if err != nil {
+		errorPopUp(err, mainWindow.Canvas())
+		return
+	}
+
+	// read one more document to know if there are more than previewSize
+	docs := tableToCollection.Preview(stream.Next, previewSize+1)
+	stream.Close()
+
+	if err = stream.Err(); err != nil {
+		errorPopUp(err, mainWindow.Canvas())
+		return
+	}
+
+	s := ""
+	if len(docs) > previewSize {
+		docs = docs[:previewSize]
+		s += fmt.Sprintf("// preview of the first %d documents, use apply or "+
+			"save to get all of them\n", previewSize,
+		)
+	}
+
+	// format and print every new document to the mongo output text entry
+	s += fmt.Sprintf("db.%s.insertMany([\n", collection)
+	for i, doc := range docs {
+		s += bsonToString(doc)
+		if i != len(docs)-1 {
+			s += ","
+		}
+		s += "\n"
+	}
+	s += "])\n"
+
+	entry.SetText(s)
+}
+
+// applyStream inserts all documents of a conversion in mongoDB in the
+// background, showing the result of each batch in an entry as it is written.
+// The button is disabled until the conversion ends.
+func applyStream(start startStream, entry *widget.Entry, b *widget.Button) {
+	if mongoDB == nil {
+		errorPopUp(errNotConnectedMongo, mainWindow.Canvas())
+		return
+	}
+
+	collection, stream, err := start(tableToCollection.Options{
+		LobSink: gridFSLob, OverflowSink: insertOverflow,
+	})
+	if err != nil {
+		errorPopUp(err, mainWindow.Canvas())
+		return
+	}
+
+	b.Disable()
+	s := fmt.Sprintf("// inserting documents in %s\n", collection)
+	entry.SetText(s)
+
+	go func() {
+		defer b.Enable()
+
+		mongoManager.InsertDocuments(context.Background(), mongoDB, collection,
+			stream.Next, func(result mongoManager.BatchResult) {
+				s += "// " + result.String() + "\n"
+				entry.SetText(s)
+			},
+		)
+
+		s += reportLine("conversion of "+collection, stream.Err())
+		entry.SetText(s)
+	}()
+}
+
+// saveStream writes all documents of a conversion to a file chosen by the
+// user as JSON lines (as read by mongoimport) in the background, showing the
+// result in an entry.
+func saveStream(start startStream, entry *widget.Entry) {
+	d := dialog.NewFileSave(func(wr fyne.URIWriteCloser, err error) {
+		if err != nil {
+			errorPopUp(err, mainWindow.Canvas())
+			return
+		}
+
+		// the dialog was cancelled
+		if wr == nil {
+			return
+		}
+
+		collection, stream, err := start(tableToCollection.Options{})
+		if err != nil {
+			wr.Close()
+			errorPopUp(err, mainWindow.Canvas())
+			return
+		}
+
+		entry.SetText(fmt.Sprintf("// saving %s to %s\n", collection, wr.URI()))
+
+		go func() {
+			n, err := tableToCollection.WriteJSONLines(wr, stream.Next)
+			stream.Close()
+
+			if err == nil {
+				err = stream.Err()
+			}
+			if errClose := wr.Close(); err == nil {
+				err = errClose
+			}
+
+			entry.SetText(reportLine(
+				fmt.Sprintf("saved %d documents of %s to %s", n, collection,
+					wr.URI(),
+				),
+				err,
+			))
+		}()
+	}, mainWindow)
+
+	d.SetFileName("collection.json")
+	d.Show()
+}
@@ -2,23 +2,49 @@ package ui
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/widget"
 
 	"github.com/lucasgpulcinelli/mongoQLer/oracleManager"
+	"github.com/lucasgpulcinelli/mongoQLer/sqlparser"
 	"github.com/lucasgpulcinelli/mongoQLer/tableToCollection"
 )
 
 var (
 	tableCollectionButton *widget.Button
+	createViewButton      *widget.Button
+	tableApplyButton      *widget.Button
+	tableSaveButton       *widget.Button
+	idMapSaveButton       *widget.Button
+	idMapLoadButton       *widget.Button
 	mongoTCEntry          *widget.Entry
+	maxDepthEntry         *widget.Entry
+	typesEntry            *widget.Entry
+	lobsEntry             *widget.Entry
+	sizeSelection         *widget.Select
 	tcSelection           *widget.Select
 	embedSelections       *fyne.Container
 	referencesNow         []oracleManager.Reference
+	idMap                 *tableToCollection.IdMap
 )
 
+// linkModeNames are the options of the link selection of each reference, in
+// the order they are shown.
+var linkModeNames = []string{"columns", "_id", "DBRef", "child _ids"}
+
+// linkModes maps each option of the link selection to its mode.
+var linkModes = map[string]tableToCollection.LinkMode{
+	linkModeNames[0]: tableToCollection.LinkColumns,
+	linkModeNames[1]: tableToCollection.LinkId,
+	linkModeNames[2]: tableToCollection.LinkDBRef,
+	linkModeNames[3]: tableToCollection.LinkChildIds,
+}
+
 // initReferences initialises the checkboxes in the tableToCollection tab with
 // the references contained in refs.
 func initReferences(refs []oracleManager.Reference) {
@@ -38,7 +64,10 @@ func initReferences(refs []oracleManager.Reference) {
 
 		// add two checkboxes, one to embed the reference as an array in the
 		// referenced collection, and other to embed the reference as an object in
-		// the referencer collection
+		// the referencer collection, and a selection of how it is linked when it
+		// is not embedded
+		links := widget.NewSelect(linkModeNames, func(_ string) {})
+		links.SetSelectedIndex(0)
 
 		// the lambdas used are to make sure both are not checked at the same time,
 		// because doing so would create an infinite recursion.
@@ -61,20 +90,17 @@ func initReferences(refs []oracleManager.Reference) {
 				ck := cont.Objects[0].(*widget.Check)
 				ck.SetChecked(false)
 			}),
+			links,
 		))
 		i++
 	}
 
 }
 
-// tableCollectionButtonFunc executes the table to collection button
-// functionality.
-func tableCollectionButtonFunc() {
-	if referencesNow == nil || tcSelection.Selected == "" {
-		return
-	}
-
-	// first, see wich references should be embedded based on the check boxes
+// embeddingPlan gets the references that should be embedded as objects in the
+// referencer (embedTo) and as arrays in the referenced (embedFrom) collection,
+// based on the check boxes of the tableToCollection tab.
+func embeddingPlan() ([]oracleManager.Reference, []oracleManager.Reference) {
 	embedToRefs := []oracleManager.Reference{}
 	embedFromRefs := []oracleManager.Reference{}
 	for i, ref := range referencesNow {
@@ -90,38 +116,273 @@ func tableCollectionButtonFunc() {
 		}
 	}
 
-	// execute the main query to pass to the GetCollection function
-	rows, err := oracleConn.Query("SELECT * FROM " + tcSelection.Selected)
+	return embedToRefs, embedFromRefs
+}
+
+// sizeStrategyNames are the options of the size selection, in the order they
+// are shown.
+var sizeStrategyNames = []string{
+	"fail on documents over 16MB",
+	"cap big arrays, keeping the rest as _ids",
+	"move the rest of big arrays to side collections",
+}
+
+// sizeStrategies maps each option of the size selection to its strategy.
+var sizeStrategies = map[string]tableToCollection.SizeStrategy{
+	sizeStrategyNames[0]: tableToCollection.SizeFail,
+	sizeStrategyNames[1]: tableToCollection.SizeCap,
+	sizeStrategyNames[2]: tableToCollection.SizeBucket,
+}
+
+// maxDepth gets the maximum embedding depth typed in the tableToCollection
+// tab, where 0 (or an empty entry) means no limit.
+func maxDepth() (int, error) {
+	text := strings.TrimSpace(maxDepthEntry.Text)
+	if text == "" {
+		return 0, nil
+	}
+
+	depth, err := strconv.Atoi(text)
+	if err != nil || depth < 0 {
+		return 0, fmt.Errorf("invalid maximum embedding depth %q", text)
+	}
+
+	return depth, nil
+}
+
+// typeMapping gets the type policy overrides written in the tableToCollection
+// tab.
+func typeMapping() (tableToCollection.TypeMapping, error) {
+	return tableToCollection.ParseTypeMapping(typesEntry.Text)
+}
+
+// linkPlan gets the references that are not embedded and are kept as links
+// other than their columns, based on the link selections of the
+// tableToCollection tab.
+func linkPlan() []tableToCollection.Link {
+	links := []tableToCollection.Link{}
+	for i, ref := range referencesNow {
+		cont := embedSelections.Objects[i].(*fyne.Container)
+
+		ckFrom := cont.Objects[0].(*widget.Check)
+		ckTo := cont.Objects[1].(*widget.Check)
+		mode := linkModes[cont.Objects[2].(*widget.Select).Selected]
+
+		if !ckFrom.Checked && !ckTo.Checked &&
+			mode != tableToCollection.LinkColumns {
+			links = append(links, tableToCollection.Link{
+				Reference: ref, Mode: mode,
+			})
+		}
+	}
+
+	return links
+}
+
+// convertSelected starts converting the selected table to documents, with
+// the embedding chosen in the check boxes, sending the LOBs above their
+// threshold and the overflow of big documents to the sinks.
+func convertSelected(
+	sinks tableToCollection.Options,
+) (string, *tableToCollection.Stream, error) {
+	// the data can only be read with a connection, not from a schema file
+	if oracleConn == nil {
+		return "", nil, errNotConnected
+	}
+
+	// first, see wich references should be embedded based on the check boxes,
+	// and check that they do not loop back before querying anything
+	embedToRefs, embedFromRefs := embeddingPlan()
+	err := tableToCollection.ValidatePlan(embedToRefs, embedFromRefs)
+	if err != nil {
+		return "", nil, err
+	}
+
+	depth, err := maxDepth()
+	if err != nil {
+		return "", nil, err
+	}
+
+	opts := sinks
+	opts.MaxDepth = depth
+	opts.Size = sizeStrategies[sizeSelection.Selected]
+	opts.Links = linkPlan()
+	opts.Ids = idMap
+
+	opts.Types, err = typeMapping()
+	if err != nil {
+		return "", nil, err
+	}
+
+	opts.Lobs, err = tableToCollection.ParseLobMapping(lobsEntry.Text)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// execute the main query to pass to the StreamCollection function, which
+	// leaves the big LOBs out to be streamed later
+	table := tcSelection.Selected
+	query, err := tableToCollection.SelectTable(catalogNow, table, opts.Lobs)
+	if err != nil {
+		return "", nil, err
+	}
+
+	rows, err := oracleConn.Query(query)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// the stream converts the documents to be added to the new collection as
+	// they are read, closing the query at the end
+	return table, tableToCollection.StreamCollection(oracleConn, catalogNow,
+		rows, table, embedToRefs, embedFromRefs, opts,
+	), nil
+}
+
+// tableCollectionButtonFunc executes the table to collection button
+// functionality.
+func tableCollectionButtonFunc() {
+	if referencesNow == nil || tcSelection.Selected == "" {
+		return
+	}
+
+	previewStream(convertSelected, mongoTCEntry)
+}
+
+// tableApplyButtonFunc executes the table to collection apply button
+// functionality, inserting the documents directly in mongoDB.
+func tableApplyButtonFunc() {
+	if referencesNow == nil || tcSelection.Selected == "" {
+		return
+	}
+
+	applyStream(convertSelected, mongoTCEntry, tableApplyButton)
+}
+
+// tableSaveButtonFunc executes the table to collection save button
+// functionality, writing the documents to a file.
+func tableSaveButtonFunc() {
+	if referencesNow == nil || tcSelection.Selected == "" {
+		return
+	}
+
+	saveStream(convertSelected, mongoTCEntry)
+}
+
+// idMapSaveButtonFunc executes the save _id map button functionality, writing
+// the ObjectIds given to the tuples without a primary key to a file chosen by
+// the user, so that later conversions can link to the same documents.
+func idMapSaveButtonFunc() {
+	d := dialog.NewFileSave(func(wr fyne.URIWriteCloser, err error) {
+		if err != nil {
+			errorPopUp(err, mainWindow.Canvas())
+			return
+		}
+
+		// the dialog was cancelled
+		if wr == nil {
+			return
+		}
+
+		n, err := idMap.Save(wr)
+		if errClose := wr.Close(); err == nil {
+			err = errClose
+		}
+
+		mongoTCEntry.SetText(reportLine(
+			fmt.Sprintf("saved %d _ids to %s", n, wr.URI()), err,
+		))
+	}, mainWindow)
+
+	d.SetFileName("ids.json")
+	d.Show()
+}
+
+// idMapLoadButtonFunc executes the load _id map button functionality, reading
+// back the ObjectIds saved by idMapSaveButtonFunc.
+func idMapLoadButtonFunc() {
+	d := dialog.NewFileOpen(func(rd fyne.URIReadCloser, err error) {
+		if err != nil {
+			errorPopUp(err, mainWindow.Canvas())
+			return
+		}
+
+		// the dialog was cancelled
+		if rd == nil {
+			return
+		}
+		defer rd.Close()
+
+		n, err := idMap.Load(rd)
+		if err != nil {
+			errorPopUp(err, mainWindow.Canvas())
+			return
+		}
+
+		mongoTCEntry.SetText(reportLine(
+			fmt.Sprintf("loaded %d _ids from %s", n, rd.URI()), nil,
+		))
+	}, mainWindow)
+
+	d.Show()
+}
+
+// createViewButtonFunc executes the create view button functionality,
+// translating the query of the selected oracle view to a mongoDB view.
+func createViewButtonFunc() {
+	if tcSelection.Selected == "" {
+		return
+	}
+
+	sources, err := catalogNow.GetSources()
 	if err != nil {
 		errorPopUp(err, mainWindow.Canvas())
 		return
 	}
 
-	// get all documents to be added to the new collection
-	docs, err := tableToCollection.GetCollection(oracleConn, rows,
-		tcSelection.Selected, embedToRefs, embedFromRefs,
-	)
+	// search for the selected view
+	var view *oracleManager.Source
+	for i, src := range sources {
+		if src.Name == tcSelection.Selected {
+			view = &sources[i]
+		}
+	}
+
+	if view == nil || view.Kind != oracleManager.SourceView {
+		errorPopUp(
+			fmt.Errorf("%s is not a view", tcSelection.Selected),
+			mainWindow.Canvas(),
+		)
+		return
+	}
+
+	// translate the view query like in the find or aggregate tab
+	stmt, err := sqlparser.ParseView(view.Text)
 	if err != nil {
 		errorPopUp(err, mainWindow.Canvas())
 		return
 	}
 
-	// close the query, we are done by now
-	err = rows.Close()
+	err = stmt.Analyze(catalogNow)
 	if err != nil {
 		errorPopUp(err, mainWindow.Canvas())
 		return
 	}
 
-	// format and print every new document to the mongo output text entry
-	s := fmt.Sprintf("db.%s.insertMany([\n", tcSelection.Selected)
-	i := 0
-	for _, doc := range docs {
-		s += bsonToString(doc) + ",\n"
-		i++
+	p, err := stmt.ToMongoPipeline(catalogNow)
+	if err != nil {
+		errorPopUp(err, mainWindow.Canvas())
+		return
 	}
-	if i > 0 {
-		s = s[:len(s)-2] + "\n"
+
+	s := warningsToString(stmt.Warnings) +
+		fmt.Sprintf("db.createView(%q, %q, [\n", view.Name, stmt.FromTable)
+	for i, bs := range p {
+		s += bsonToString(bs)
+		if i != len(p)-1 {
+			s += ","
+		}
+		s += "\n"
 	}
 	s += "])\n"
 
@@ -135,12 +396,20 @@ func tableCollectionButtonFunc() {
 // oracle.
 func newTableToCollection() fyne.CanvasObject {
 	l := widget.NewLabel("Convert an oracle table to a mongoDB collection")
-	l2 := widget.NewLabel("references to embed as arrays / objects")
+	l2 := widget.NewLabel(
+		"references to embed as arrays / objects, or how to link them",
+	)
 
 	tableCollectionButton = widget.NewButton("convert",
 		tableCollectionButtonFunc,
 	)
 
+	createViewButton = widget.NewButton("create view", createViewButtonFunc)
+
+	tableApplyButton = widget.NewButton("apply", tableApplyButtonFunc)
+
+	tableSaveButton = widget.NewButton("save", tableSaveButtonFunc)
+
 	mongoTCEntry = widget.NewMultiLineEntry()
 	mongoTCEntry.SetPlaceHolder("click convert to get your collection")
 
@@ -153,13 +422,40 @@ func newTableToCollection() fyne.CanvasObject {
 
 	embedSelections = container.NewVBox()
 
+	// the ObjectIds of the tuples without a primary key are kept for all
+	// conversions, so that the links to them match
+	idMap = tableToCollection.NewIdMap()
+	idMapSaveButton = widget.NewButton("save _id map", idMapSaveButtonFunc)
+	idMapLoadButton = widget.NewButton("load _id map", idMapLoadButtonFunc)
+
+	maxDepthEntry = widget.NewEntry()
+	maxDepthEntry.SetPlaceHolder("max embedding depth (empty for no limit)")
+
+	typesEntry = widget.NewEntry()
+	typesEntry.SetPlaceHolder("type overrides, such as T.PRICE=double")
+
+	lobsEntry = widget.NewEntry()
+	lobsEntry.SetPlaceHolder("LOB overrides, such as T.PHOTO=1048576:photos")
+
+	sizeSelection = widget.NewSelect(sizeStrategyNames, func(_ string) {})
+	sizeSelection.SetSelectedIndex(0)
+
+	// how the documents are converted, below the embedding check boxes
+	conversionOptions := container.NewVBox(maxDepthEntry, typesEntry, lobsEntry,
+		sizeSelection,
+		container.NewGridWithColumns(2, idMapSaveButton, idMapLoadButton),
+	)
+
 	return container.NewBorder(
 		container.NewCenter(l),
-		tableCollectionButton,
+		container.NewGridWithColumns(4,
+			tableCollectionButton, createViewButton, tableApplyButton,
+			tableSaveButton,
+		),
 		nil,
 		nil,
 		container.NewHSplit(
-			container.NewBorder(tcSelection, nil, nil, nil,
+			container.NewBorder(tcSelection, conversionOptions, nil, nil,
 				container.NewBorder(container.NewCenter(l2), nil, nil, nil,
 					container.NewVScroll(container.NewCenter(embedSelections)),
 				),
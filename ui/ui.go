@@ -1,18 +1,47 @@
 package ui
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/widget"
+	"github.com/lucasgpulcinelli/mongoQLer/oracleManager"
 )
 
 var (
 	mainWindow fyne.Window // the main ui window
 )
 
+// errNotConnected is shown when a tab needs data from oracle but the metadata
+// was read from a schema file instead.
+var errNotConnected = errors.New(
+	"not connected to oracle: this tab needs to read the table data",
+)
+
+// errNotConnectedMongo is shown when applying changes to mongoDB without a
+// mongoDB connection.
+var errNotConnectedMongo = errors.New(
+	"not connected to mongoDB: give a mongoDB URI and database at login",
+)
+
+// reportLine gets a line reporting the result of an operation applied to
+// mongoDB, as a comment.
+func reportLine(operation string, err error) string {
+	operation = strings.ReplaceAll(operation, "\n", "")
+	if err != nil {
+		return fmt.Sprintf("// failed: %s: %s\n", operation,
+			strings.ReplaceAll(err.Error(), "\n", "; "),
+		)
+	}
+
+	return fmt.Sprintf("// ok: %s\n", operation)
+}
+
 var helpTC string = `
 In this tab you can transform oracle tables to mongoDB collections.
 
@@ -34,6 +63,59 @@ program uses recursion into each document to embed everything as specified.
 
 You can use each reference in one of the two forms described, but not both,
 since it would create an infinite recursion.
+
+References that together loop back to a table (such as A embedding B and B
+embedding A) are also rejected before converting, showing the path of the
+cycle. The maximum embedding depth limits how many levels of documents are
+embedded: past it, references are kept as plain key fields.
+
+The values are converted to BSON types from the column metadata: NUMBER(p, 0)
+to int or long, NUMBER(p, s) to decimal, other numbers to double, DATE and
+TIMESTAMP to date (with a COLUMN_OFFSET field for WITH TIME ZONE), RAW(16) to
+UUID, other RAWs and BLOBs to binary, and CHAR to a string without padding.
+Type overrides change that for some columns, as TABLE.COLUMN=policy separated
+by commas, where the policy is one of auto, driver, int, long, decimal, double,
+string, trimmed, date, dateOffset, uuid or binary.
+
+CLOBs and BLOBs of tables up to 4 MiB (in characters for CLOBs) stay in the
+documents, while bigger ones are streamed from oracle to GridFS when applying,
+with the document keeping the file id, bucket, length and content type. The
+preview leaves their file id null, and saving to a file fails on them. LOB
+overrides change the threshold and bucket of some columns, as
+TABLE.COLUMN=threshold or TABLE.COLUMN=threshold:bucket separated by commas.
+
+Documents over the 16MB limit of mongoDB because of their embedded arrays
+either fail the conversion (reporting the size of each array), have the
+arrays capped with the _id of the remaining documents in a CONSTRAINT_OVERFLOW
+field, or have the remaining documents moved to buckets in a TABLE_CONSTRAINT
+side collection, with the CONSTRAINT_OVERFLOW field telling how many there
+are. Side collections are only written when applying.
+
+References that are not embedded keep their foreign key columns by default,
+but can instead be linked by the _id of the referenced document (as an object
+{ _id: ... } named after the constraint), by a DBRef, or with an array of the
+_id of the referencing documents in the referenced one ("child _ids"). Tables
+without a primary key get an ObjectId for each tuple, which is kept for the
+whole session so that the links to them stay consistent. The "save _id map"
+button writes these ObjectIds to a file (which mongoimport can also load as a
+mapping collection), and "load _id map" reads them back in a later session.
+
+If a schema owner was given at login, the tables are read from that schema
+(as OWNER.TABLE), which must be granted to the user logged in.
+
+Views, materialized views and synonyms (of tables or views, possibly in other
+schemas) can be selected and converted as well. For a view, the "create view"
+button translates its query with the same parser as the find or aggregate tab
+to a mongoDB createView command, so simple views can be kept as read-only
+views in mongoDB instead of copies of their data.
+
+The documents are converted as they are read from oracle, so tables of any size
+can be converted: the "convert" button only shows the first 100 documents, and
+the "save" button writes all of them to a file as JSON lines, which can be
+imported with mongoimport. If a mongoDB URI and database were given at login,
+the "apply" button inserts all documents directly in the collection with the
+name of the table, in batches of 1000, showing the result of each batch as it
+is written.
 `
 
 var helpQC string = `
@@ -45,6 +127,12 @@ them. The _id of all tables created this way is a simple mongoDB ObjectID.
 
 You can specify the output collection name in the box near the bottom of the
 window.
+
+The "generate" button only shows the first 100 documents, and the "save" button
+writes all of them to a file as JSON lines, which can be imported with
+mongoimport. If a mongoDB URI and database were given at login, the "apply"
+button inserts all documents directly in the output collection, in batches of
+1000, showing the result of each batch as it is written.
 `
 
 var helpIG string = `
@@ -52,25 +140,77 @@ In this tab you can generate all the indicies for mongoDB collections based on
 your oracle tables.
 
 This tab generates a new index for each UNIQUE contraint in oracle (not primary
-keys, since the _id already has an index in mongoDB).
+keys, since the _id already has an index in mongoDB), and for each other B-tree
+index, keeping its name, column order and descending columns. Function-based
+indexes on UPPER(column) or LOWER(column) become indexes on the column with a
+case insensitive collation, which is only used by queries with the same
+collation. Other function-based indexes cannot be expressed in mongoDB and are
+listed as comments, consider indexing a computed field for them.
+
+Like in oracle, unique indexes accept any number of documents where all their
+columns are null: when all columns are nullable, the index has a
+partialFilterExpression with the non-null types of the columns, so only the
+documents with some non-null column are indexed (for more than one column the
+filter uses $or, which needs a recent mongoDB version).
+
+Indexes are also proposed for the foreign key references, following the
+embedding chosen with the check boxes in the Table to Collection tab: references
+kept as links get an index on the referencer columns (for $lookup), references
+embedded as objects get an index on the key of the embedded document (such as
+FK_NAME._id.COL), and references embedded as arrays get a multikey index on the
+key of the array elements. Proposals already covered by an unique constraint or
+index are not repeated.
+
+If a .sql schema file was opened instead of logging in, the UNIQUE constraints
+and indexes are read from the CREATE TABLE, ALTER TABLE ... ADD and CREATE
+INDEX statements in it. If a catalog snapshot (saved with file > save catalog
+snapshot) was opened, they are read from it.
+
+If a mongoDB URI and database were given at login, the "apply" button creates
+the indexes directly in mongoDB, showing the result of each one.
 `
 
 var helpVG string = `
 In this tab you can generate all the validtaors for mongoDB collections based
 on your oracle tables.
 
-This tab generates a new validator for each table / collection, considering
-primary key constraints as well as check constraints (the first considering
-that the primary key cannot be null, and including in the check constraints
-all NOT NULLs as well as custom CHECKS).
+This tab generates a new validator for each table / collection, with a
+$jsonSchema describing its documents: the bsonType of each field comes from the
+oracle data type, maxLength from the length of character columns, minimum and
+maximum from the precision and scale of NUMBER columns, and required from NOT
+NULL and primary key columns (the primary key is described as the _id sub
+document). CHECK constraints of the form COLUMN IN (...) become an enum for the
+field, and all other CHECKs are converted to query operators, combined with the
+$jsonSchema in an $and.
+
+References embedded as objects with the check boxes in the Table to Collection
+tab are described as the embedded document instead of their columns.
+
+CHECK constraints that cannot be parsed or converted are left out of the
+validators, and listed as comments at the end of the output with their name,
+text and the reason.
+
+Before applying the validators with validationAction: "error", click verify
+existing data to count, for each collection in the mongoDB connection, the
+documents that violate its validator (with a $nor of the validator), along with
+some of their _ids. Without a mongoDB connection, a mongosh script that does
+the same is generated instead. If there are violations, apply the validator
+with validationLevel: "moderate" or validationAction: "warn" instead.
 
 The check parsing is done using the same SQL parser as in the last
 functionality WHERE statement, so the same syntax restrictions for there apply
 as well for all checks.
 
-Note that, because the check parser has no context outside of the text itself,
-it does differentiate between 1 and '1', for instance, even if the type for the
-original table is a CHAR (which would make both the same in oracle).
+Literals in checks are converted to the type of the column they are compared
+to, so 1 and '1' are the same for a CHAR column, like in oracle.
+
+If a .sql schema file was opened instead of logging in, the constraints are
+read from the CREATE TABLE and ALTER TABLE ... ADD statements in it. If a
+catalog snapshot (saved with file > save catalog snapshot) was opened, they are
+read from it.
+
+If a mongoDB URI and database were given at login, the "apply" button runs the
+collMod of each validator directly in mongoDB, showing the result of each one.
 `
 
 var helpQFA string = `
@@ -98,6 +238,36 @@ comparisions, AND and OR can be used, but parenthesis must be used to mix them
 "(A = B AND B = C) OR C = D").
 
 There is no support for GROUP BY or ORDER BY.
+
+Before the conversion, every column is checked against the tables in the
+query: unknown columns are reported as errors, and columns in both the FROM and
+JOIN tables are taken from the JOIN table with a warning. Literals are
+converted to the type of their column, so for a CHAR column "A = 1" is the same
+as "A = '1'", as in oracle.
+
+Aggregations are optimized before being shown: conditions that only use the
+FROM table are checked before the $lookup, and a $project before it removes
+the fields that are not needed after the join.
+
+INSERT INTO TABLE (A, B, ...) SELECT ... statements are converted to an
+aggregation of the query ending in a $merge into the target collection (or in
+an $out, that replaces the whole collection). The column list is optional, and
+if omitted the selected column names are used.
+
+MERGE INTO TABLE T USING SOURCE S ON (T.A = S.B AND ...) statements, where
+SOURCE can also be a query in parenthesis, are converted to an aggregation of
+the source ending in a $merge. WHEN MATCHED THEN UPDATE SET T.C = S.D, ... and
+WHEN NOT MATCHED THEN INSERT (T.C, ...) VALUES (S.D, ...) clauses are supported,
+with values being source columns or literals. When both clauses are used, the
+values in UPDATE SET must also appear in the ON or INSERT clauses. The ON
+columns must either contain the whole primary key of the target table or none
+of it, and in the last case mongoDB requires an unique index on them.
+
+The "convert back to SQL" button does the opposite: it reads a mongosh
+db.COLLECTION.find(FILTER, PROJECTION) or db.COLLECTION.aggregate([...])
+command, with arguments in extended JSON, and writes the equivalent oracle
+SELECT. Pipelines must map to a single SELECT, using $match, one $lookup
+followed by its $unwind, one $group, $project, $sort, $count, $skip and $limit.
 `
 
 // errorPopUp shows an error to a fyne canvas as a popup.
@@ -116,6 +286,44 @@ func errorPopUp(err error, c fyne.Canvas) {
 	pop.Show()
 }
 
+// saveSnapshot saves a snapshot of the current catalog in a .json, .yaml or
+// .yml file chosen by the user, so that it can be opened later instead of
+// logging in.
+func saveSnapshot() {
+	d := dialog.NewFileSave(func(wr fyne.URIWriteCloser, err error) {
+		if err != nil {
+			errorPopUp(err, mainWindow.Canvas())
+			return
+		}
+
+		// the dialog was cancelled
+		if wr == nil {
+			return
+		}
+		defer wr.Close()
+
+		format, err := oracleManager.SnapshotFormat(wr.URI().Name())
+		if err != nil {
+			errorPopUp(err, mainWindow.Canvas())
+			return
+		}
+
+		snapshot, err := oracleManager.NewSnapshot(catalogNow)
+		if err != nil {
+			errorPopUp(err, mainWindow.Canvas())
+			return
+		}
+
+		err = oracleManager.EncodeSnapshot(wr, snapshot, format)
+		if err != nil {
+			errorPopUp(err, mainWindow.Canvas())
+		}
+	}, mainWindow)
+
+	d.SetFileName("catalog.yaml")
+	d.Show()
+}
+
 // NewMainWindow creates the main application window.
 func NewMainWindow(a fyne.App) {
 	mainWindow = a.NewWindow("Oracle to Mongo Translator")
@@ -130,7 +338,9 @@ func NewMainWindow(a fyne.App) {
 		container.NewTabItem("Query to Find or Aggregate", newFindAggregate()),
 	)
 
-	mainWindow.SetMainMenu(fyne.NewMainMenu(fyne.NewMenu("help",
+	mainWindow.SetMainMenu(fyne.NewMainMenu(fyne.NewMenu("file",
+		fyne.NewMenuItem("save catalog snapshot", saveSnapshot),
+	), fyne.NewMenu("help",
 		fyne.NewMenuItem("about this tab", func() {
 			var help string
 			switch tabs.SelectedIndex() {